@@ -0,0 +1,24 @@
+package v1alpha1
+
+// +kubebuilder:validation:Enum=ConfigMap;Secret;Directory;S3
+type StorageType string
+
+var (
+	StorageConfigMap StorageType = "ConfigMap"
+	StorageSecret    StorageType = "Secret"
+	// StorageDirectory stores files in the directory configured in the
+	// manager, usually backed by a PersistentVolumeClaim
+	StorageDirectory StorageType = "Directory"
+	// StorageS3 stores files in the bucket of the S3 compatible endpoint
+	// configured in the manager
+	StorageS3 StorageType = "S3"
+)
+
+// StorageReference points to a set of files persisted by Thatchd
+type StorageReference struct {
+	Type StorageType `json:"type"`
+	// Location is the name of the ConfigMap or Secret in the namespace, or
+	// the path relative to the storage directory, or the bucket and prefix of
+	// the S3 objects
+	Location string `json:"location"`
+}
@@ -22,7 +22,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// +kubebuilder:validation:Enum=Created;Canceled;Dispatched;Running;Finished;Failed
+// +kubebuilder:validation:Enum=Created;Canceled;Dispatched;Running;Finished;Failed;Skipped;XFailed;XPassed
 type TestCaseCurrentStatus string
 
 var (
@@ -32,6 +32,34 @@ var (
 	TestCaseRunning    TestCaseCurrentStatus = "Running"
 	TestCaseFinished   TestCaseCurrentStatus = "Finished"
 	TestCaseFailed     TestCaseCurrentStatus = "Failed"
+	TestCaseSkipped    TestCaseCurrentStatus = "Skipped"
+	// TestCaseXFailed is the status of a test case that failed as expected
+	TestCaseXFailed TestCaseCurrentStatus = "XFailed"
+	// TestCaseXPassed is the status of a test case that was expected to fail
+	// but passed
+	TestCaseXPassed TestCaseCurrentStatus = "XPassed"
+)
+
+// +kubebuilder:validation:Enum=Eventually;Consistently
+type PollMode string
+
+var (
+	// PollEventually passes as soon as a run of the TestCase passes, and
+	// fails if none did once the duration elapses
+	PollEventually PollMode = "Eventually"
+	// PollConsistently fails as soon as a run of the TestCase fails, and
+	// passes if all of them did once the duration elapses
+	PollConsistently PollMode = "Consistently"
+)
+
+// +kubebuilder:validation:Enum=Passed;Failed;Warning;Skipped
+type AssertionOutcome string
+
+var (
+	AssertionPassed  AssertionOutcome = "Passed"
+	AssertionFailed  AssertionOutcome = "Failed"
+	AssertionWarning AssertionOutcome = "Warning"
+	AssertionSkipped AssertionOutcome = "Skipped"
 )
 
 const (
@@ -46,8 +74,91 @@ type TestCaseSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	Timeout  *string  `json:"timeout,omitempty"`
-	Strategy Strategy `json:"strategy"`
+	Timeout       *string          `json:"timeout,omitempty"`
+	Strategy      Strategy         `json:"strategy"`
+	ExpectFailure *ExpectedFailure `json:"expectFailure,omitempty"`
+	Diagnostics   *DiagnosticsSpec `json:"diagnostics,omitempty"`
+	Output        *OutputSpec      `json:"output,omitempty"`
+	Artifacts     *ArtifactsSpec   `json:"artifacts,omitempty"`
+	PollPolicy    *PollPolicy      `json:"pollPolicy,omitempty"`
+	// Thresholds are the bounds of the measurements reported by the
+	// TestCase. A measurement outside of its bounds, or missing, fails it
+	Thresholds []Threshold `json:"thresholds,omitempty"`
+}
+
+// Threshold bounds a named measurement reported by a TestCase. The bounds are
+// inclusive, in the unit of the measurement
+type Threshold struct {
+	Measurement string `json:"measurement"`
+	// Unit is the unit of the bounds, such as s. The threshold fails when the
+	// measurement is reported in another unit. Not checked if not set
+	Unit string `json:"unit,omitempty"`
+	// +kubebuilder:validation:Pattern=`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`
+	Min *string `json:"min,omitempty"`
+	// +kubebuilder:validation:Pattern=`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`
+	Max *string `json:"max,omitempty"`
+}
+
+// PollPolicy runs a TestCase repeatedly instead of once, so it doesn't race
+// with the state it checks. The result is the one of the last run
+type PollPolicy struct {
+	Mode PollMode `json:"mode"`
+	// Duration is how long the TestCase is polled for, such as 2m
+	Duration string `json:"duration"`
+	// Interval is the time waited between two runs. Defaults to 5s
+	Interval *string `json:"interval,omitempty"`
+}
+
+// ArtifactsSpec configures how the artifacts attached by a TestCase are stored
+type ArtifactsSpec struct {
+	// Storage is where the artifacts are stored. Defaults to a ConfigMap
+	Storage StorageType `json:"storage,omitempty"`
+}
+
+// OutputSpec configures how the lines logged by a TestCase are captured
+type OutputSpec struct {
+	// MaxLines is the number of lines kept, discarding the oldest ones.
+	// Defaults to 1000
+	MaxLines int `json:"maxLines,omitempty"`
+	// Storage is where the output is stored. Defaults to a ConfigMap
+	Storage StorageType `json:"storage,omitempty"`
+}
+
+// ExpectedFailure marks a TestCase as known to be broken. A failure results in
+// the XFailed status, while a success results in XPassed, which fails the
+// suite verdict as the test case is no longer broken
+type ExpectedFailure struct {
+	Reason string `json:"reason"`
+	// Quarantine excludes the TestCase from the suite verdict, whatever its
+	// result is
+	Quarantine bool `json:"quarantine,omitempty"`
+}
+
+// DiagnosticsSpec configures the diagnostics bundle collected when a TestCase
+// fails or times out
+type DiagnosticsSpec struct {
+	// Selector limits the collected resources, events and logs to the ones
+	// of the objects matching the labels. Everything in the namespace is
+	// collected if not set
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Resources are the kinds of resources collected as YAML. Defaults to
+	// Pods, Services, PersistentVolumeClaims, Deployments, StatefulSets and
+	// DaemonSets. Secrets are only collected when the bundle is stored in a
+	// Secret
+	Resources []ResourceKind `json:"resources,omitempty"`
+	// Events collects the Events in the namespace
+	Events bool `json:"events,omitempty"`
+	// LogTailLines is the number of log lines collected from each container.
+	// Logs aren't collected if not set
+	LogTailLines *int64 `json:"logTailLines,omitempty"`
+	// Storage is where the bundle is stored. Defaults to a ConfigMap
+	Storage StorageType `json:"storage,omitempty"`
+}
+
+// ResourceKind identifies a kind of Kubernetes resource
+type ResourceKind struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
 }
 
 // TestCaseStatus defines the observed state of TestCase
@@ -59,6 +170,49 @@ type TestCaseStatus struct {
 	FinishedAt     *string               `json:"finishedAt,omitempty"`
 	FailureMessage *string               `json:"failureMessage,omitempty"`
 	Status         TestCaseCurrentStatus `json:"status,omitempty"`
+	Assertions     []AssertionResult     `json:"assertions,omitempty"`
+	Diagnostics    *StorageReference     `json:"diagnostics,omitempty"`
+	Output         *StorageReference     `json:"output,omitempty"`
+	Artifacts      []ArtifactReference   `json:"artifacts,omitempty"`
+	// Attempts is the number of times a polled TestCase ran
+	Attempts int `json:"attempts,omitempty"`
+	// LastAttemptFailure is the failure of the last run of a polled TestCase
+	// that failed, even if a later run passed
+	LastAttemptFailure *string `json:"lastAttemptFailure,omitempty"`
+	// Measurements are the named numeric values reported by the TestCase,
+	// such as the time a resource took to be ready
+	Measurements []Measurement `json:"measurements,omitempty"`
+	// StorageErrors are the failures to store the diagnostics, output or
+	// artifacts of the TestCase
+	StorageErrors []string `json:"storageErrors,omitempty"`
+}
+
+// Measurement is a named numeric value reported by a TestCase. The value is
+// a decimal string, as floating point numbers aren't portable across the
+// API clients
+type Measurement struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// ArtifactReference points to a file attached by a TestCase while it ran
+type ArtifactReference struct {
+	Name string `json:"name"`
+	// Size is the size of the artifact in bytes
+	Size    int              `json:"size"`
+	Storage StorageReference `json:"storage"`
+}
+
+// AssertionResult is the outcome of a single named check performed by a
+// TestCase. A TestCase fails if any of its assertions failed, while warnings
+// are reported without affecting the result
+type AssertionResult struct {
+	Name     string           `json:"name"`
+	Outcome  AssertionOutcome `json:"outcome"`
+	Expected string           `json:"expected,omitempty"`
+	Actual   string           `json:"actual,omitempty"`
+	Message  string           `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
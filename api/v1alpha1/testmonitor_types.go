@@ -0,0 +1,98 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=Pending;Healthy;Violated
+type TestMonitorCurrentStatus string
+
+var (
+	// TestMonitorPending is the status of a monitor that wasn't checked yet
+	TestMonitorPending TestMonitorCurrentStatus = "Pending"
+	// TestMonitorHealthy is the status of a monitor whose checks all passed
+	TestMonitorHealthy TestMonitorCurrentStatus = "Healthy"
+	// TestMonitorViolated is the status of a monitor with a failed check,
+	// which fails the suite verdict
+	TestMonitorViolated TestMonitorCurrentStatus = "Violated"
+)
+
+// TestMonitorSpec defines the desired state of TestMonitor
+type TestMonitorSpec struct {
+	// Strategy is a TestCase strategy, checked repeatedly while the monitor
+	// is active
+	Strategy Strategy `json:"strategy"`
+	// Interval is the time between two checks, such as 30s. Defaults to 10s
+	Interval *string `json:"interval,omitempty"`
+}
+
+// TestMonitorStatus defines the observed state of TestMonitor
+type TestMonitorStatus struct {
+	// Active is set by the TestSuite while the dispatch condition of the
+	// strategy holds and the suite has TestCases in progress
+	Active bool                     `json:"active,omitempty"`
+	Status TestMonitorCurrentStatus `json:"status,omitempty"`
+	// Checks is the number of times the monitor was checked
+	Checks        int          `json:"checks,omitempty"`
+	LastCheckedAt *metav1.Time `json:"lastCheckedAt,omitempty"`
+	// ViolationCount is the number of failed checks
+	ViolationCount int `json:"violationCount,omitempty"`
+	// Violations are the most recent failed checks
+	Violations []MonitorViolation `json:"violations,omitempty"`
+	// Error is set when the monitor can't be checked, such as when its
+	// interval is invalid
+	Error string `json:"error,omitempty"`
+}
+
+// MonitorViolation is a failed check of a TestMonitor
+type MonitorViolation struct {
+	Time    metav1.Time `json:"time"`
+	Message string      `json:"message"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// TestMonitor is the Schema for the testmonitors API
+type TestMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TestMonitorSpec   `json:"spec,omitempty"`
+	Status TestMonitorStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TestMonitorList contains a list of TestMonitor
+type TestMonitorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TestMonitor `json:"items"`
+}
+
+var _ StrategyBacked = &TestMonitor{}
+
+func (tm *TestMonitor) GetStrategy() Strategy {
+	return tm.Spec.Strategy
+}
+
+func init() {
+	SchemeBuilder.Register(&TestMonitor{}, &TestMonitorList{})
+}
@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thatchd/thatchd/api/v1alpha2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var _ conversion.Convertible = &TestSuite{}
+
+// ConvertTo converts this TestSuite to the hub version, parsing the string
+// state into a structured one
+func (src *TestSuite) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha2.TestSuite)
+
+	initialState, err := rawState(src.Spec.InitialState)
+	if err != nil {
+		return fmt.Errorf("invalid initialContext: %w", err)
+	}
+	currentState, err := rawState(src.Status.CurrentState)
+	if err != nil {
+		return fmt.Errorf("invalid currentState: %w", err)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1alpha2.TestSuiteSpec{
+		InitialState:      initialState,
+		StateStrategy:     v1alpha2.Strategy{Strategy: src.Spec.StateStrategy.Strategy},
+		StateHistoryLimit: src.Spec.StateHistoryLimit,
+	}
+	if src.Spec.StateStorage != nil {
+		dst.Spec.StateStorage = &v1alpha2.StateStorageSpec{
+			Type:      v1alpha2.StateStorageType(src.Spec.StateStorage.Type),
+			ChunkSize: src.Spec.StateStorage.ChunkSize,
+		}
+	}
+
+	dst.Status = v1alpha2.TestSuiteStatus{
+		CurrentState: currentState,
+		StateVersion: src.Status.StateVersion,
+		Error:        src.Status.Error,
+		Verdict:      v1alpha2.TestSuiteVerdict(src.Status.Verdict),
+	}
+	if src.Status.StateReference != nil {
+		dst.Status.StateReference = &v1alpha2.StateReference{
+			ConfigMaps: src.Status.StateReference.ConfigMaps,
+			Hash:       src.Status.StateReference.Hash,
+			Size:       src.Status.StateReference.Size,
+		}
+	}
+	if src.Status.Summary != nil {
+		summary := v1alpha2.TestSuiteSummary(*src.Status.Summary)
+		dst.Status.Summary = &summary
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the hub version to this TestSuite, serializing the
+// structured state into a string
+func (dst *TestSuite) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha2.TestSuite)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = TestSuiteSpec{
+		InitialState:      stringState(src.Spec.InitialState),
+		StateStrategy:     Strategy{Strategy: src.Spec.StateStrategy.Strategy},
+		StateHistoryLimit: src.Spec.StateHistoryLimit,
+	}
+	if src.Spec.StateStorage != nil {
+		dst.Spec.StateStorage = &StateStorageSpec{
+			Type:      StateStorageType(src.Spec.StateStorage.Type),
+			ChunkSize: src.Spec.StateStorage.ChunkSize,
+		}
+	}
+
+	dst.Status = TestSuiteStatus{
+		CurrentState: stringState(src.Status.CurrentState),
+		StateVersion: src.Status.StateVersion,
+		Error:        src.Status.Error,
+		Verdict:      TestSuiteVerdict(src.Status.Verdict),
+	}
+	if src.Status.StateReference != nil {
+		dst.Status.StateReference = &StateReference{
+			ConfigMaps: src.Status.StateReference.ConfigMaps,
+			Hash:       src.Status.StateReference.Hash,
+			Size:       src.Status.StateReference.Size,
+		}
+	}
+	if src.Status.Summary != nil {
+		summary := TestSuiteSummary(*src.Status.Summary)
+		dst.Status.Summary = &summary
+	}
+
+	return nil
+}
+
+func rawState(state string) (*apiextensionsv1.JSON, error) {
+	if state == "" {
+		return nil, nil
+	}
+
+	if !json.Valid([]byte(state)) {
+		return nil, fmt.Errorf("state is not valid JSON")
+	}
+
+	return &apiextensionsv1.JSON{Raw: []byte(state)}, nil
+}
+
+func stringState(state *apiextensionsv1.JSON) string {
+	if state == nil {
+		return ""
+	}
+
+	return string(state.Raw)
+}
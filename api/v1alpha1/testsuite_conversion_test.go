@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thatchd/thatchd/api/v1alpha2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTestSuiteConversion(t *testing.T) {
+	src := &TestSuite{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		},
+		Spec: TestSuiteSpec{
+			InitialState: `{"ready":false}`,
+		},
+		Status: TestSuiteStatus{
+			// States aren't necessarily objects
+			CurrentState: `["pod-a","pod-b"]`,
+			StateVersion: 2,
+			Verdict:      TestSuitePassed,
+			Summary:      &TestSuiteSummary{Total: 1, Passed: 1},
+		},
+	}
+
+	hub := &v1alpha2.TestSuite{}
+	if err := src.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(hub.Spec.InitialState.Raw) != `{"ready":false}` || string(hub.Status.CurrentState.Raw) != `["pod-a","pod-b"]` {
+		t.Errorf("expected the state to be converted, got %s and %s", hub.Spec.InitialState.Raw, hub.Status.CurrentState.Raw)
+	}
+
+	dst := &TestSuite{}
+	if err := dst.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(src, dst) {
+		t.Errorf("expected the TestSuite to be preserved, got %v", dst)
+	}
+
+	src.Spec.InitialState = "not json"
+	if err := src.ConvertTo(&v1alpha2.TestSuite{}); err == nil {
+		t.Errorf("expected an invalid initialContext to fail the conversion")
+	}
+}
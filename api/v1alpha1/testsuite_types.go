@@ -28,16 +28,82 @@ type TestSuiteSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	InitialState  string   `json:"initialContext,omitempty"`
-	StateStrategy Strategy `json:"stateStrategy"`
+	InitialState  string            `json:"initialContext,omitempty"`
+	StateStrategy Strategy          `json:"stateStrategy"`
+	StateStorage  *StateStorageSpec `json:"stateStorage,omitempty"`
+	// StateHistoryLimit is the number of state transitions recorded in the
+	// <name>-state-history ConfigMap. Defaults to 100, 0 disables the history
+	StateHistoryLimit *int `json:"stateHistoryLimit,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=Status;ConfigMap
+type StateStorageType string
+
+var (
+	// StateStorageStatus stores the state in the currentState field of the
+	// TestSuite status
+	StateStorageStatus StateStorageType = "Status"
+	// StateStorageConfigMap offloads the state to ConfigMaps owned by the
+	// TestSuite, keeping only a reference in its status
+	StateStorageConfigMap StateStorageType = "ConfigMap"
+)
+
+// StateStorageSpec configures where the state of the TestSuite is persisted
+type StateStorageSpec struct {
+	// Type defaults to Status
+	Type StateStorageType `json:"type,omitempty"`
+	// ChunkSize is the maximum size in bytes of the state stored in each
+	// ConfigMap. Larger states are split across several ConfigMaps. Defaults
+	// to 512KiB, and can't exceed 1000KiB so each chunk fits in a ConfigMap
+	// +kubebuilder:validation:Maximum=1024000
+	ChunkSize int `json:"chunkSize,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Pending;Running;Passed;Failed
+type TestSuiteVerdict string
+
+var (
+	TestSuitePending TestSuiteVerdict = "Pending"
+	TestSuiteRunning TestSuiteVerdict = "Running"
+	TestSuitePassed  TestSuiteVerdict = "Passed"
+	TestSuiteFailed  TestSuiteVerdict = "Failed"
+)
+
 // TestSuiteStatus defines the observed state of TestSuite
 type TestSuiteStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	CurrentState string `json:"currentState,omitempty"`
-	Error        string `json:"error,omitempty"`
+	CurrentState   string            `json:"currentState,omitempty"`
+	StateReference *StateReference   `json:"stateReference,omitempty"`
+	StateVersion   int               `json:"stateVersion,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Verdict        TestSuiteVerdict  `json:"verdict,omitempty"`
+	Summary        *TestSuiteSummary `json:"summary,omitempty"`
+}
+
+// StateReference points to the state of the TestSuite when it's offloaded
+// to ConfigMaps
+type StateReference struct {
+	// ConfigMaps holding the chunks of the state, in order
+	ConfigMaps []string `json:"configMaps"`
+	// Hash is the SHA-256 of the state, used to detect changes and verify
+	// the chunks
+	Hash string `json:"hash"`
+	// Size is the size of the state in bytes
+	Size int `json:"size"`
+}
+
+// TestSuiteSummary counts the TestCases of the suite by their result
+type TestSuiteSummary struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	XFailed int `json:"xfailed"`
+	XPassed int `json:"xpassed"`
+	// ViolatedMonitors is the number of TestMonitors with violations
+	ViolatedMonitors int `json:"violatedMonitors,omitempty"`
 }
 
 // +kubebuilder:object:root=true
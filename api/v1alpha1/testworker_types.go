@@ -26,6 +26,59 @@ import (
 // TestWorkerSpec defines the desired state of TestWorker
 type TestWorkerSpec struct {
 	Strategy Strategy `json:"strategy"`
+	// StatePatch is applied to the state of the TestSuite once the worker
+	// runs successfully, after the mutation of the strategy, if any
+	StatePatch *StatePatch `json:"statePatch,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=JSONPatch;MergePatch
+type StatePatchType string
+
+var (
+	// StateJSONPatch is a RFC 6902 JSON Patch
+	StateJSONPatch StatePatchType = "JSONPatch"
+	// StateMergePatch is a RFC 7386 JSON Merge Patch
+	StateMergePatch StatePatchType = "MergePatch"
+)
+
+// StatePatch is a patch to the state of the TestSuite
+type StatePatch struct {
+	Type StatePatchType `json:"type"`
+	// Patch is the JSON encoded patch
+	Patch string `json:"patch"`
+}
+
+// +kubebuilder:validation:Enum=Delete;MergePatch
+type RevertType string
+
+var (
+	// RevertDelete reverts an action by deleting the resource it created
+	RevertDelete RevertType = "Delete"
+	// RevertMergePatch reverts an action by patching the resource it changed
+	RevertMergePatch RevertType = "MergePatch"
+)
+
+// ActionRevert describes how to revert an action
+type ActionRevert struct {
+	Type RevertType `json:"type"`
+	// Patch is the JSON Merge Patch that reverts the action, for MergePatch
+	Patch string `json:"patch,omitempty"`
+}
+
+// WorkerAction is a change made by a worker to a resource of the cluster.
+// Actions are recorded as they're made, so the ones that can be reverted are
+// reverted if the worker is canceled
+type WorkerAction struct {
+	// Type is what the worker did, such as PodKilled or NodeCordoned
+	Type       string `json:"type"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Revert is how the action is reverted, if it can be
+	Revert      *ActionRevert `json:"revert,omitempty"`
+	PerformedAt string        `json:"performedAt"`
+	RevertedAt  *string       `json:"revertedAt,omitempty"`
 }
 
 // TestWorkerStatus defines the observed state of TestWorker
@@ -34,6 +87,16 @@ type TestWorkerStatus struct {
 	StartedAt      *string `json:"startedAt,omitempty"`
 	FinishedAt     *string `json:"finishedAt,omitempty"`
 	FailureMessage *string `json:"failureMessage,omitempty"`
+	// StatePatches are the patches applied to the state of the TestSuite by
+	// the worker. They're recorded before being applied, so they're replayed
+	// if the manager stops before the worker finishes
+	StatePatches []StatePatch `json:"statePatches,omitempty"`
+	// Actions are the changes made to the cluster by the worker, recorded by
+	// workers that revert them when canceled
+	Actions []WorkerAction `json:"actions,omitempty"`
+	// RevertAt is when the actions of a worker that keeps its changes for a
+	// while are reverted, before it finishes
+	RevertAt *metav1.Time `json:"revertAt,omitempty"`
 }
 
 // +kubebuilder:object:root=true
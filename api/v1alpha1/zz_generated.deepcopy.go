@@ -21,9 +21,262 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionRevert) DeepCopyInto(out *ActionRevert) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionRevert.
+func (in *ActionRevert) DeepCopy() *ActionRevert {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionRevert)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactReference) DeepCopyInto(out *ArtifactReference) {
+	*out = *in
+	out.Storage = in.Storage
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactReference.
+func (in *ArtifactReference) DeepCopy() *ArtifactReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactsSpec) DeepCopyInto(out *ArtifactsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactsSpec.
+func (in *ArtifactsSpec) DeepCopy() *ArtifactsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AssertionResult) DeepCopyInto(out *AssertionResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AssertionResult.
+func (in *AssertionResult) DeepCopy() *AssertionResult {
+	if in == nil {
+		return nil
+	}
+	out := new(AssertionResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosticsSpec) DeepCopyInto(out *DiagnosticsSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogTailLines != nil {
+		in, out := &in.LogTailLines, &out.LogTailLines
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosticsSpec.
+func (in *DiagnosticsSpec) DeepCopy() *DiagnosticsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosticsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExpectedFailure) DeepCopyInto(out *ExpectedFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpectedFailure.
+func (in *ExpectedFailure) DeepCopy() *ExpectedFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(ExpectedFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Measurement) DeepCopyInto(out *Measurement) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Measurement.
+func (in *Measurement) DeepCopy() *Measurement {
+	if in == nil {
+		return nil
+	}
+	out := new(Measurement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitorViolation) DeepCopyInto(out *MonitorViolation) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitorViolation.
+func (in *MonitorViolation) DeepCopy() *MonitorViolation {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitorViolation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputSpec) DeepCopyInto(out *OutputSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputSpec.
+func (in *OutputSpec) DeepCopy() *OutputSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PollPolicy) DeepCopyInto(out *PollPolicy) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PollPolicy.
+func (in *PollPolicy) DeepCopy() *PollPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PollPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceKind) DeepCopyInto(out *ResourceKind) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceKind.
+func (in *ResourceKind) DeepCopy() *ResourceKind {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatePatch) DeepCopyInto(out *StatePatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatePatch.
+func (in *StatePatch) DeepCopy() *StatePatch {
+	if in == nil {
+		return nil
+	}
+	out := new(StatePatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateReference) DeepCopyInto(out *StateReference) {
+	*out = *in
+	if in.ConfigMaps != nil {
+		in, out := &in.ConfigMaps, &out.ConfigMaps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateReference.
+func (in *StateReference) DeepCopy() *StateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(StateReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateStorageSpec) DeepCopyInto(out *StateStorageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateStorageSpec.
+func (in *StateStorageSpec) DeepCopy() *StateStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StateStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageReference) DeepCopyInto(out *StorageReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageReference.
+func (in *StorageReference) DeepCopy() *StorageReference {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Strategy) DeepCopyInto(out *Strategy) {
 	*out = *in
@@ -108,6 +361,38 @@ func (in *TestCaseSpec) DeepCopyInto(out *TestCaseSpec) {
 		**out = **in
 	}
 	in.Strategy.DeepCopyInto(&out.Strategy)
+	if in.ExpectFailure != nil {
+		in, out := &in.ExpectFailure, &out.ExpectFailure
+		*out = new(ExpectedFailure)
+		**out = **in
+	}
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = new(DiagnosticsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(OutputSpec)
+		**out = **in
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = new(ArtifactsSpec)
+		**out = **in
+	}
+	if in.PollPolicy != nil {
+		in, out := &in.PollPolicy, &out.PollPolicy
+		*out = new(PollPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Thresholds != nil {
+		in, out := &in.Thresholds, &out.Thresholds
+		*out = make([]Threshold, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestCaseSpec.
@@ -143,6 +428,41 @@ func (in *TestCaseStatus) DeepCopyInto(out *TestCaseStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Assertions != nil {
+		in, out := &in.Assertions, &out.Assertions
+		*out = make([]AssertionResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = new(StorageReference)
+		**out = **in
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(StorageReference)
+		**out = **in
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]ArtifactReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAttemptFailure != nil {
+		in, out := &in.LastAttemptFailure, &out.LastAttemptFailure
+		*out = new(string)
+		**out = **in
+	}
+	if in.Measurements != nil {
+		in, out := &in.Measurements, &out.Measurements
+		*out = make([]Measurement, len(*in))
+		copy(*out, *in)
+	}
+	if in.StorageErrors != nil {
+		in, out := &in.StorageErrors, &out.StorageErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestCaseStatus.
@@ -155,13 +475,119 @@ func (in *TestCaseStatus) DeepCopy() *TestCaseStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestMonitor) DeepCopyInto(out *TestMonitor) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestMonitor.
+func (in *TestMonitor) DeepCopy() *TestMonitor {
+	if in == nil {
+		return nil
+	}
+	out := new(TestMonitor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TestMonitor) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestMonitorList) DeepCopyInto(out *TestMonitorList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TestMonitor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestMonitorList.
+func (in *TestMonitorList) DeepCopy() *TestMonitorList {
+	if in == nil {
+		return nil
+	}
+	out := new(TestMonitorList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TestMonitorList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestMonitorSpec) DeepCopyInto(out *TestMonitorSpec) {
+	*out = *in
+	in.Strategy.DeepCopyInto(&out.Strategy)
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestMonitorSpec.
+func (in *TestMonitorSpec) DeepCopy() *TestMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TestMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestMonitorStatus) DeepCopyInto(out *TestMonitorStatus) {
+	*out = *in
+	if in.LastCheckedAt != nil {
+		in, out := &in.LastCheckedAt, &out.LastCheckedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]MonitorViolation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestMonitorStatus.
+func (in *TestMonitorStatus) DeepCopy() *TestMonitorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TestMonitorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TestSuite) DeepCopyInto(out *TestSuite) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestSuite.
@@ -218,6 +644,16 @@ func (in *TestSuiteList) DeepCopyObject() runtime.Object {
 func (in *TestSuiteSpec) DeepCopyInto(out *TestSuiteSpec) {
 	*out = *in
 	in.StateStrategy.DeepCopyInto(&out.StateStrategy)
+	if in.StateStorage != nil {
+		in, out := &in.StateStorage, &out.StateStorage
+		*out = new(StateStorageSpec)
+		**out = **in
+	}
+	if in.StateHistoryLimit != nil {
+		in, out := &in.StateHistoryLimit, &out.StateHistoryLimit
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestSuiteSpec.
@@ -233,6 +669,16 @@ func (in *TestSuiteSpec) DeepCopy() *TestSuiteSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TestSuiteStatus) DeepCopyInto(out *TestSuiteStatus) {
 	*out = *in
+	if in.StateReference != nil {
+		in, out := &in.StateReference, &out.StateReference
+		*out = new(StateReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(TestSuiteSummary)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestSuiteStatus.
@@ -245,6 +691,21 @@ func (in *TestSuiteStatus) DeepCopy() *TestSuiteStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestSuiteSummary) DeepCopyInto(out *TestSuiteSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestSuiteSummary.
+func (in *TestSuiteSummary) DeepCopy() *TestSuiteSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(TestSuiteSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TestWorker) DeepCopyInto(out *TestWorker) {
 	*out = *in
@@ -308,6 +769,11 @@ func (in *TestWorkerList) DeepCopyObject() runtime.Object {
 func (in *TestWorkerSpec) DeepCopyInto(out *TestWorkerSpec) {
 	*out = *in
 	in.Strategy.DeepCopyInto(&out.Strategy)
+	if in.StatePatch != nil {
+		in, out := &in.StatePatch, &out.StatePatch
+		*out = new(StatePatch)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestWorkerSpec.
@@ -343,6 +809,22 @@ func (in *TestWorkerStatus) DeepCopyInto(out *TestWorkerStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.StatePatches != nil {
+		in, out := &in.StatePatches, &out.StatePatches
+		*out = make([]StatePatch, len(*in))
+		copy(*out, *in)
+	}
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]WorkerAction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RevertAt != nil {
+		in, out := &in.RevertAt, &out.RevertAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestWorkerStatus.
@@ -354,3 +836,53 @@ func (in *TestWorkerStatus) DeepCopy() *TestWorkerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Threshold) DeepCopyInto(out *Threshold) {
+	*out = *in
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		*out = new(string)
+		**out = **in
+	}
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Threshold.
+func (in *Threshold) DeepCopy() *Threshold {
+	if in == nil {
+		return nil
+	}
+	out := new(Threshold)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerAction) DeepCopyInto(out *WorkerAction) {
+	*out = *in
+	if in.Revert != nil {
+		in, out := &in.Revert, &out.Revert
+		*out = new(ActionRevert)
+		**out = **in
+	}
+	if in.RevertedAt != nil {
+		in, out := &in.RevertedAt, &out.RevertedAt
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerAction.
+func (in *WorkerAction) DeepCopy() *WorkerAction {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerAction)
+	in.DeepCopyInto(out)
+	return out
+}
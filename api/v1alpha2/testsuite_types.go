@@ -0,0 +1,143 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Strategy selects the strategy provider and its configuration
+type Strategy struct {
+	strategy.Strategy `json:",inline"`
+}
+
+// TestSuiteSpec defines the desired state of TestSuite
+type TestSuiteSpec struct {
+	// InitialState is the state of the suite before its first
+	// reconciliation, any JSON value. Defaults to an empty object
+	// +optional
+	InitialState  *apiextensionsv1.JSON `json:"initialState,omitempty"`
+	StateStrategy Strategy              `json:"stateStrategy"`
+	StateStorage  *StateStorageSpec     `json:"stateStorage,omitempty"`
+	// StateHistoryLimit is the number of state transitions recorded in the
+	// <name>-state-history ConfigMap. Defaults to 100, 0 disables the history
+	StateHistoryLimit *int `json:"stateHistoryLimit,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Status;ConfigMap
+type StateStorageType string
+
+var (
+	// StateStorageStatus stores the state in the currentState field of the
+	// TestSuite status
+	StateStorageStatus StateStorageType = "Status"
+	// StateStorageConfigMap offloads the state to ConfigMaps owned by the
+	// TestSuite, keeping only a reference in its status
+	StateStorageConfigMap StateStorageType = "ConfigMap"
+)
+
+// StateStorageSpec configures where the state of the TestSuite is persisted
+type StateStorageSpec struct {
+	// Type defaults to Status
+	Type StateStorageType `json:"type,omitempty"`
+	// ChunkSize is the maximum size in bytes of the state stored in each
+	// ConfigMap. Larger states are split across several ConfigMaps. Defaults
+	// to 512KiB, and can't exceed 1000KiB so each chunk fits in a ConfigMap
+	// +kubebuilder:validation:Maximum=1024000
+	ChunkSize int `json:"chunkSize,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Pending;Running;Passed;Failed
+type TestSuiteVerdict string
+
+var (
+	TestSuitePending TestSuiteVerdict = "Pending"
+	TestSuiteRunning TestSuiteVerdict = "Running"
+	TestSuitePassed  TestSuiteVerdict = "Passed"
+	TestSuiteFailed  TestSuiteVerdict = "Failed"
+)
+
+// TestSuiteStatus defines the observed state of TestSuite
+type TestSuiteStatus struct {
+	// CurrentState is the state reconciled by the suite strategy, unless it's
+	// offloaded to the ConfigMaps in StateReference
+	// +optional
+	CurrentState   *apiextensionsv1.JSON `json:"currentState,omitempty"`
+	StateReference *StateReference       `json:"stateReference,omitempty"`
+	// StateVersion is the version of the strategy the state was stored
+	// with, used to migrate it when the strategy changes
+	StateVersion int               `json:"stateVersion,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Verdict      TestSuiteVerdict  `json:"verdict,omitempty"`
+	Summary      *TestSuiteSummary `json:"summary,omitempty"`
+}
+
+// StateReference points to the state of the TestSuite when it's offloaded
+// to ConfigMaps
+type StateReference struct {
+	// ConfigMaps holding the chunks of the state, in order
+	ConfigMaps []string `json:"configMaps"`
+	// Hash is the SHA-256 of the state, used to detect changes and verify
+	// the chunks
+	Hash string `json:"hash"`
+	// Size is the size of the state in bytes
+	Size int `json:"size"`
+}
+
+// TestSuiteSummary counts the TestCases of the suite by their result
+type TestSuiteSummary struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	XFailed int `json:"xfailed"`
+	XPassed int `json:"xpassed"`
+	// ViolatedMonitors is the number of TestMonitors with violations
+	ViolatedMonitors int `json:"violatedMonitors,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+
+// TestSuite is the Schema for the testsuites API
+type TestSuite struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TestSuiteSpec   `json:"spec,omitempty"`
+	Status TestSuiteStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TestSuiteList contains a list of TestSuite
+type TestSuiteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TestSuite `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TestSuite{}, &TestSuiteList{})
+}
+
+// Hub marks this version as the conversion hub
+func (*TestSuite) Hub() {}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/state"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const usage = `thatchctl inspects the Thatchd test suites of a cluster
+
+Usage:
+  thatchctl history [flags] <test suite>
+
+Commands:
+  history    Print the state transitions of a test suite
+
+Flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	var namespace, source, output string
+	flag.StringVar(&namespace, "namespace", "default", "Namespace of the test suite.")
+	flag.StringVar(&source, "source", "", "Only print the transitions of this source, such as suite or worker/<name>.")
+	flag.StringVar(&output, "output", "text", "Output format, text or json.")
+
+	if len(os.Args) < 2 || os.Args[1] != "history" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := flag.CommandLine.Parse(os.Args[2:]); err != nil {
+		os.Exit(2)
+	}
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := history(namespace, flag.Arg(0), source, output, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func history(namespace, name, source, output string, out io.Writer) error {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := thatchdv1alpha2.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	suite := &thatchdv1alpha2.TestSuite{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, suite); err != nil {
+		return err
+	}
+
+	transitions, err := (&state.History{Client: c, Scheme: scheme}).Load(context.TODO(), suite)
+	if err != nil {
+		return err
+	}
+
+	filtered := make([]state.Transition, 0, len(transitions))
+	for _, transition := range transitions {
+		if source == "" || transition.Source == source {
+			filtered = append(filtered, transition)
+		}
+	}
+
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(filtered)
+	case "text":
+		return printTransitions(filtered, out)
+	}
+
+	return fmt.Errorf("unsupported output format %s", output)
+}
+
+func printTransitions(transitions []state.Transition, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSOURCE\tOP\tPATH\tVALUE")
+
+	for _, transition := range transitions {
+		if transition.Truncated {
+			fmt.Fprintf(w, "%s\t%s\t\t\t(diff too large to be recorded)\n", transition.Time, transition.Source)
+		}
+
+		for _, operation := range transition.Diff {
+			value := ""
+			if operation.Value != nil {
+				content, err := json.Marshal(operation.Value)
+				if err != nil {
+					return err
+				}
+				value = string(content)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				transition.Time,
+				transition.Source,
+				operation.Operation,
+				operation.Path,
+				strings.TrimSpace(value),
+			)
+		}
+	}
+
+	return w.Flush()
+}
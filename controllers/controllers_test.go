@@ -5,13 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
 	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/diagnostics"
+	"github.com/thatchd/thatchd/pkg/thatchd/storage"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -25,7 +32,7 @@ type testScenario struct {
 	Name              string
 	StrategyProviders map[string]strategy.StrategyProvider
 	TestCaseCRs       []*thatchdv1alpha1.TestCase
-	TestSuiteCR       *thatchdv1alpha1.TestSuite
+	TestSuiteCR       *thatchdv1alpha2.TestSuite
 	Assert            func(client client.Client, programReconcileResult reconcile.Result, programReconcileError error, testCaseResults map[string]*testCaseRun) error
 }
 
@@ -47,14 +54,14 @@ type componentStatus struct {
 
 var scenario1 testScenario = testScenario{
 	Name: "Tests for component 1 ready are dispatched",
-	TestSuiteCR: &thatchdv1alpha1.TestSuite{
+	TestSuiteCR: &thatchdv1alpha2.TestSuite{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      "test-suite",
 			Namespace: "thatchd",
 		},
-		Spec: thatchdv1alpha1.TestSuiteSpec{
-			InitialState: "{}",
-			StateStrategy: thatchdv1alpha1.Strategy{
+		Spec: thatchdv1alpha2.TestSuiteSpec{
+			InitialState: &apiextensionsv1.JSON{Raw: []byte("{}")},
+			StateStrategy: thatchdv1alpha2.Strategy{
 				Strategy: strategy.Strategy{
 					Provider:      "testSuiteStrategyProvider",
 					Configuration: map[string]string{},
@@ -69,6 +76,11 @@ var scenario1 testScenario = testScenario{
 				Namespace: "thatchd",
 			},
 			Spec: thatchdv1alpha1.TestCaseSpec{
+				Diagnostics: &thatchdv1alpha1.DiagnosticsSpec{
+					Resources: []thatchdv1alpha1.ResourceKind{
+						{APIVersion: "testing.thatchd.io/v1alpha2", Kind: "TestSuite"},
+					},
+				},
 				Strategy: thatchdv1alpha1.Strategy{
 					Strategy: strategy.Strategy{
 						Provider: "testCaseStrategyProvider",
@@ -112,6 +124,139 @@ var scenario1 testScenario = testScenario{
 				},
 			},
 		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-D",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "D",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-E",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "E",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-F",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				ExpectFailure: &thatchdv1alpha1.ExpectedFailure{
+					Reason: "component A is not healthy yet",
+				},
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "A",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-G",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				PollPolicy: &thatchdv1alpha1.PollPolicy{
+					Mode:     thatchdv1alpha1.PollEventually,
+					Duration: "10s",
+					Interval: addr("10ms"),
+				},
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "G",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-H",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				PollPolicy: &thatchdv1alpha1.PollPolicy{
+					Mode:     thatchdv1alpha1.PollConsistently,
+					Duration: "10s",
+					Interval: addr("10ms"),
+				},
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "H",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-I",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				Thresholds: []thatchdv1alpha1.Threshold{
+					{Measurement: "latency", Max: addr("0.5")},
+					{Measurement: "replicas", Min: addr("3"), Max: addr("3")},
+				},
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "I",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-case-J",
+				Namespace: "thatchd",
+			},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				Thresholds: []thatchdv1alpha1.Threshold{
+					{Measurement: "latency", Max: addr("0.1")},
+					{Measurement: "throughput", Min: addr("1")},
+				},
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{
+						Provider: "testCaseStrategyProvider",
+						Configuration: map[string]string{
+							"Name": "I",
+						},
+					},
+				},
+			},
+		},
 	},
 
 	StrategyProviders: map[string]strategy.StrategyProvider{
@@ -140,6 +285,20 @@ var scenario1 testScenario = testScenario{
 		if testCaseACR.Status.Status != thatchdv1alpha1.TestCaseFailed {
 			return fmt.Errorf("expected test case A to be in failed status, but was %s", testCaseACR.Status.Status)
 		}
+		if testCaseACR.Status.Diagnostics == nil || testCaseACR.Status.Diagnostics.Location != "test-case-A-diagnostics" {
+			return fmt.Errorf("expected test case A diagnostics to be stored in test-case-A-diagnostics, but got %v", testCaseACR.Status.Diagnostics)
+		}
+
+		diagnosticsConfigMap := &corev1.ConfigMap{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-A-diagnostics",
+			Namespace: "thatchd",
+		}, diagnosticsConfigMap); err != nil {
+			return fmt.Errorf("failed to retrieve diagnostics: %v", err)
+		}
+		if !strings.Contains(diagnosticsConfigMap.Data["testsuite.testing.thatchd.io.yaml"], "name: test-suite") {
+			return fmt.Errorf("expected diagnostics to contain the test suite, but got %v", diagnosticsConfigMap.Data)
+		}
 
 		testCaseBCR := &thatchdv1alpha1.TestCase{}
 		if err := client.Get(context.TODO(), types.NamespacedName{
@@ -168,6 +327,167 @@ var scenario1 testScenario = testScenario{
 			return fmt.Errorf("expected test case C to be marked as canceled, but was %s", testCaseCCR.Status.Status)
 		}
 
+		testCaseDCR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-D",
+			Namespace: "thatchd",
+		}, testCaseDCR); err != nil {
+			return fmt.Errorf("failed to retrieve test case D: %v", err)
+		}
+
+		if testCaseDCR.Status.Status != thatchdv1alpha1.TestCaseFailed {
+			return fmt.Errorf("expected test case D to be in failed status, but was %s", testCaseDCR.Status.Status)
+		}
+		if len(testCaseDCR.Status.Assertions) != 3 {
+			return fmt.Errorf("expected test case D to report 3 assertions, but got %d", len(testCaseDCR.Status.Assertions))
+		}
+		expectedOutcomes := []thatchdv1alpha1.AssertionOutcome{
+			thatchdv1alpha1.AssertionPassed,
+			thatchdv1alpha1.AssertionFailed,
+			thatchdv1alpha1.AssertionWarning,
+		}
+		for i, assertion := range testCaseDCR.Status.Assertions {
+			if assertion.Outcome != expectedOutcomes[i] {
+				return fmt.Errorf("expected assertion %s to be %s, but was %s", assertion.Name, expectedOutcomes[i], assertion.Outcome)
+			}
+		}
+		if *testCaseDCR.Status.FailureMessage != "1 assertion(s) failed: ready: expected true, got false" {
+			return fmt.Errorf("unexpected failure message. Got %s", *testCaseDCR.Status.FailureMessage)
+		}
+		if len(testCaseDCR.Status.Artifacts) != 1 || testCaseDCR.Status.Artifacts[0].Name != "components.json" {
+			return fmt.Errorf("expected test case D to reference its artifact, got %v", testCaseDCR.Status.Artifacts)
+		}
+
+		artifactsConfigMap := &corev1.ConfigMap{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      testCaseDCR.Status.Artifacts[0].Storage.Location,
+			Namespace: "thatchd",
+		}, artifactsConfigMap); err != nil {
+			return fmt.Errorf("failed to retrieve test case D artifacts: %v", err)
+		}
+		if artifactsConfigMap.Data["components.json"] != `{"replicas":3}` {
+			return fmt.Errorf("unexpected test case D artifact %s", artifactsConfigMap.Data["components.json"])
+		}
+
+		if testCaseDCR.Status.Output == nil {
+			return fmt.Errorf("expected test case D to reference its output")
+		}
+
+		outputConfigMap := &corev1.ConfigMap{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      testCaseDCR.Status.Output.Location,
+			Namespace: "thatchd",
+		}, outputConfigMap); err != nil {
+			return fmt.Errorf("failed to retrieve test case D output: %v", err)
+		}
+		if output := outputConfigMap.Data["output.log"]; !strings.Contains(output, "INFO checking components replicas=3") {
+			return fmt.Errorf("expected test case D output to contain the logged line, got %s", output)
+		}
+
+		testCaseECR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-E",
+			Namespace: "thatchd",
+		}, testCaseECR); err != nil {
+			return fmt.Errorf("failed to retrieve test case E: %v", err)
+		}
+
+		if testCaseECR.Status.Status != thatchdv1alpha1.TestCaseSkipped {
+			return fmt.Errorf("expected test case E to be skipped, but was %s", testCaseECR.Status.Status)
+		}
+
+		testCaseFCR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-F",
+			Namespace: "thatchd",
+		}, testCaseFCR); err != nil {
+			return fmt.Errorf("failed to retrieve test case F: %v", err)
+		}
+
+		if testCaseFCR.Status.Status != thatchdv1alpha1.TestCaseXFailed {
+			return fmt.Errorf("expected test case F to be XFailed, but was %s", testCaseFCR.Status.Status)
+		}
+
+		testCaseGCR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-G",
+			Namespace: "thatchd",
+		}, testCaseGCR); err != nil {
+			return fmt.Errorf("failed to retrieve test case G: %v", err)
+		}
+
+		if testCaseGCR.Status.Status != thatchdv1alpha1.TestCaseFinished {
+			return fmt.Errorf("expected test case G to eventually pass, but was %s", testCaseGCR.Status.Status)
+		}
+		if testCaseGCR.Status.Attempts != 3 {
+			return fmt.Errorf("expected test case G to run 3 times, but ran %d", testCaseGCR.Status.Attempts)
+		}
+		if testCaseGCR.Status.LastAttemptFailure == nil || *testCaseGCR.Status.LastAttemptFailure != "1 assertion(s) failed: attempt: expected 3, got 2" {
+			return fmt.Errorf("unexpected last attempt failure of test case G: %v", testCaseGCR.Status.LastAttemptFailure)
+		}
+		if len(testCaseGCR.Status.Assertions) != 1 || testCaseGCR.Status.Assertions[0].Outcome != thatchdv1alpha1.AssertionPassed {
+			return fmt.Errorf("expected test case G to report the assertion of its last run, got %v", testCaseGCR.Status.Assertions)
+		}
+
+		testCaseHCR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-H",
+			Namespace: "thatchd",
+		}, testCaseHCR); err != nil {
+			return fmt.Errorf("failed to retrieve test case H: %v", err)
+		}
+
+		if testCaseHCR.Status.Status != thatchdv1alpha1.TestCaseFailed {
+			return fmt.Errorf("expected test case H to fail, but was %s", testCaseHCR.Status.Status)
+		}
+		if testCaseHCR.Status.Attempts != 2 {
+			return fmt.Errorf("expected test case H to run 2 times, but ran %d", testCaseHCR.Status.Attempts)
+		}
+		if !strings.HasPrefix(*testCaseHCR.Status.FailureMessage, "failed on attempt 2 after") {
+			return fmt.Errorf("unexpected failure message of test case H. Got %s", *testCaseHCR.Status.FailureMessage)
+		}
+
+		testCaseICR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-I",
+			Namespace: "thatchd",
+		}, testCaseICR); err != nil {
+			return fmt.Errorf("failed to retrieve test case I: %v", err)
+		}
+
+		if testCaseICR.Status.Status != thatchdv1alpha1.TestCaseFinished {
+			return fmt.Errorf("expected test case I to pass its thresholds, but was %s", testCaseICR.Status.Status)
+		}
+		if len(testCaseICR.Status.Measurements) != 2 || testCaseICR.Status.Measurements[0] != (thatchdv1alpha1.Measurement{Name: "latency", Value: "0.25", Unit: "s"}) {
+			return fmt.Errorf("unexpected measurements of test case I: %v", testCaseICR.Status.Measurements)
+		}
+		if len(testCaseICR.Status.Assertions) != 2 || testCaseICR.Status.Assertions[0].Name != "threshold latency" || testCaseICR.Status.Assertions[0].Actual != "0.25 s" {
+			return fmt.Errorf("expected test case I to assert its thresholds, got %v", testCaseICR.Status.Assertions)
+		}
+
+		testCaseJCR := &thatchdv1alpha1.TestCase{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-case-J",
+			Namespace: "thatchd",
+		}, testCaseJCR); err != nil {
+			return fmt.Errorf("failed to retrieve test case J: %v", err)
+		}
+
+		if testCaseJCR.Status.Status != thatchdv1alpha1.TestCaseFailed {
+			return fmt.Errorf("expected test case J to fail its thresholds, but was %s", testCaseJCR.Status.Status)
+		}
+		if *testCaseJCR.Status.FailureMessage != "2 assertion(s) failed: threshold latency: 0.25 is above the maximum 0.1; threshold throughput: measurement not reported" {
+			return fmt.Errorf("unexpected failure message of test case J. Got %s", *testCaseJCR.Status.FailureMessage)
+		}
+
+		reportConfigMap := &corev1.ConfigMap{}
+		if err := client.Get(context.TODO(), types.NamespacedName{
+			Name:      "test-suite-report",
+			Namespace: "thatchd",
+		}, reportConfigMap); err != nil {
+			return fmt.Errorf("failed to retrieve report: %v", err)
+		}
+
 		return nil
 	},
 }
@@ -181,7 +501,12 @@ func TestThatchdControllers(t *testing.T) {
 		t.Run(scenario.Name, func(t *testing.T) {
 			// Create scheme and client
 			scheme := buildScheme(t)
-			client := fake.NewFakeClientWithScheme(scheme, scenario.TestSuiteCR)
+			client := fake.NewFakeClientWithScheme(scheme)
+			// Create the TestSuite CR through the client so it's assigned a
+			// resource version
+			if err := client.Create(context.TODO(), scenario.TestSuiteCR); err != nil {
+				t.Fatalf("error pre-populating TestSuite CR: %v", err)
+			}
 			// Pre populate client with TestCase CRs
 			for _, testCaseCR := range scenario.TestCaseCRs {
 				if err := client.Create(context.TODO(), testCaseCR); err != nil {
@@ -202,6 +527,8 @@ func TestThatchdControllers(t *testing.T) {
 				Scheme:            scheme,
 				StrategyProviders: scenario.StrategyProviders,
 				Log:               ctrl.Log.Logger,
+				Diagnostics:       &diagnostics.Collector{Client: client},
+				Stores:            storage.NewStores(client, scheme, ""),
 			}
 
 			// Create test case run data
@@ -239,6 +566,12 @@ func buildScheme(t *testing.T) *runtime.Scheme {
 	if err := thatchdv1alpha1.SchemeBuilder.AddToScheme(scheme); err != nil {
 		t.Fatalf("error building scheme: %v", err)
 	}
+	if err := thatchdv1alpha2.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("error building scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error building scheme: %v", err)
+	}
 
 	return scheme
 }
@@ -250,7 +583,7 @@ type testProgramReconcilerMock struct {
 
 var _ testsuite.Reconciler = &testProgramReconcilerMock{}
 
-func (m *testProgramReconcilerMock) Reconcile(client client.Client, _ string, currentState interface{}) (interface{}, error) {
+func (m *testProgramReconcilerMock) Reconcile(client client.Client, _ string, currentState interface{}, _ logr.Logger) (interface{}, error) {
 	return m.reconcile(client, currentState)
 }
 
@@ -260,17 +593,17 @@ func (m *testProgramReconcilerMock) ParseState(state string) (interface{}, error
 
 type testCaseInterfaceMock struct {
 	shouldRun func(testContext interface{}) bool
-	run       func(client client.Client) error
+	run       func(client client.Client, result *testcase.Result, logger logr.Logger) error
 }
 
 var _ testcase.Interface = &testCaseInterfaceMock{}
 
-func (m *testCaseInterfaceMock) ShouldRun(testContext interface{}) bool {
+func (m *testCaseInterfaceMock) ShouldRun(testContext interface{}, _ logr.Logger) bool {
 	return m.shouldRun(testContext)
 }
 
-func (m *testCaseInterfaceMock) Run(client client.Client, namespace string) error {
-	return m.run(client)
+func (m *testCaseInterfaceMock) Run(client client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	return m.run(client, result, logger)
 }
 
 type testSuiteStrategyProvider struct{}
@@ -309,7 +642,7 @@ func (p *testCaseStrategyProvider) New(configuration map[string]string) interfac
 			shouldRun: func(testContext interface{}) bool {
 				return testContext.(testProgramState).ComponentA.Ready
 			},
-			run: func(client client.Client) error {
+			run: func(client client.Client, _ *testcase.Result, _ logr.Logger) error {
 				return errors.New("This test failed")
 			},
 		}
@@ -318,7 +651,7 @@ func (p *testCaseStrategyProvider) New(configuration map[string]string) interfac
 			shouldRun: func(testContext interface{}) bool {
 				return testContext.(testProgramState).ComponentB.Ready
 			},
-			run: func(client client.Client) error {
+			run: func(client client.Client, _ *testcase.Result, _ logr.Logger) error {
 				return errors.New("This test failed")
 			},
 		}
@@ -327,11 +660,73 @@ func (p *testCaseStrategyProvider) New(configuration map[string]string) interfac
 			shouldRun: func(testContext interface{}) bool {
 				return true
 			},
-			run: func(client client.Client) error {
+			run: func(client client.Client, _ *testcase.Result, _ logr.Logger) error {
 				time.Sleep(time.Second * 5)
 				return nil
 			},
 		}
+	case "D":
+		return &testCaseInterfaceMock{
+			shouldRun: func(testContext interface{}) bool {
+				return true
+			},
+			run: func(client client.Client, result *testcase.Result, logger logr.Logger) error {
+				logger.Info("checking components", "replicas", 3)
+				if err := result.Attach("components.json", []byte(`{"replicas":3}`)); err != nil {
+					return err
+				}
+				result.Equal("replicas", 3, 3)
+				result.Equal("ready", true, false)
+				result.Warn("latency", false, "latency above 100ms")
+				return nil
+			},
+		}
+	case "E":
+		return &testCaseInterfaceMock{
+			shouldRun: func(testContext interface{}) bool {
+				return true
+			},
+			run: func(client client.Client, _ *testcase.Result, _ logr.Logger) error {
+				return testcase.Skip("component B is not ready")
+			},
+		}
+	case "G":
+		attempts := 0
+		return &testCaseInterfaceMock{
+			shouldRun: func(testContext interface{}) bool {
+				return true
+			},
+			run: func(client client.Client, result *testcase.Result, _ logr.Logger) error {
+				attempts++
+				result.Equal("attempt", 3, attempts)
+				return nil
+			},
+		}
+	case "H":
+		attempts := 0
+		return &testCaseInterfaceMock{
+			shouldRun: func(testContext interface{}) bool {
+				return true
+			},
+			run: func(client client.Client, _ *testcase.Result, _ logr.Logger) error {
+				attempts++
+				if attempts == 2 {
+					return errors.New("component A is not ready")
+				}
+				return nil
+			},
+		}
+	case "I":
+		return &testCaseInterfaceMock{
+			shouldRun: func(testContext interface{}) bool {
+				return true
+			},
+			run: func(client client.Client, result *testcase.Result, _ logr.Logger) error {
+				result.MeasureDuration("latency", 250*time.Millisecond)
+				result.Measure("replicas", 3, "")
+				return nil
+			},
+		}
 	}
 
 	return nil
@@ -18,7 +18,10 @@ package controllers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -28,24 +31,47 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/assertion"
+	"github.com/thatchd/thatchd/pkg/thatchd/diagnostics"
+	"github.com/thatchd/thatchd/pkg/thatchd/metrics"
+	"github.com/thatchd/thatchd/pkg/thatchd/output"
+	"github.com/thatchd/thatchd/pkg/thatchd/storage"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
 )
 
+// defaultPollInterval is the time waited between two runs of a polled test
+// case
+const defaultPollInterval = 5 * time.Second
+
 // TestCaseReconciler reconciles a TestCase object
 type TestCaseReconciler struct {
 	client.Client
 	Log               logr.Logger
 	Scheme            *runtime.Scheme
 	StrategyProviders map[string]strategy.StrategyProvider
+	Diagnostics       *diagnostics.Collector
+	Stores            storage.Stores
 }
 
 // +kubebuilder:rbac:groups=testing.thatchd.io,resources=testcases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=testing.thatchd.io,resources=testcases/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods;services;endpoints;events;persistentvolumeclaims;namespaces;nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups="",resources=services/proxy;pods/proxy,verbs=get;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods;services,verbs=create;update
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=create;update
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=create;update
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 func (r *TestCaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
-	_ = r.Log.WithValues("testcase", req.NamespacedName)
+	log := r.Log.WithValues("testcase", req.NamespacedName)
 
 	// Fetch the TestCase instance
 	instance := &thatchdv1alpha1.TestCase{}
@@ -54,7 +80,9 @@ func (r *TestCaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// The measurements of a deleted test case aren't exported anymore.
 			// Return and don't requeue
+			metrics.DeleteMeasurements(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -71,13 +99,16 @@ func (r *TestCaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	// Update the status to mark it as running
+	// Update the status to mark it as running. The optimistic lock prevents
+	// the test from being started twice
+	original := instance.DeepCopy()
 	instance.Status.StartedAt = thatchdv1alpha1.TimeString(time.Now())
 	instance.Status.Status = thatchdv1alpha1.TestCaseRunning
 
-	if err := r.Status().Update(ctx, instance); err != nil {
+	if err := patchStatus(ctx, r, instance, original, client.MergeFromWithOptimisticLock{}); err != nil {
 		return ctrl.Result{}, err
 	}
+	original = instance.DeepCopy()
 
 	// Get the timeout channel, or a channel that never closes if timeout hasn't
 	// been specified
@@ -97,11 +128,52 @@ func (r *TestCaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, fmt.Errorf("error obtaining strategy for test case %s: %v", instance.Name, err)
 	}
 
-	// Run the test in a goroutine and create a channel that closes when it's done
-	done := make(chan error)
+	// Capture the lines logged by the test
+	testLog := log
+	if testSuite, err := getNamespaceTestSuite(ctx, r, req.Namespace); err == nil {
+		testLog = testLog.WithValues("testsuite", testSuite.Name)
+	}
+	maxLines := 0
+	if instance.Spec.Output != nil {
+		maxLines = instance.Spec.Output.MaxLines
+	}
+	outputBuffer := output.NewBuffer(maxLines)
+
+	// Polled test cases run until their poll policy is met, or the test times
+	// out
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	poller, pollErr := newPoller(runCtx, instance.Spec.PollPolicy)
+
+	// Each run records into its own result, and the status is built from
+	// the result of the last run that completed. A run that's still going
+	// when the test times out doesn't change it
+	var resultLock sync.Mutex
+	lastResult := testcase.NewResult()
+	testLogger := output.NewLogger(testLog, outputBuffer)
+	run := func() error {
+		result := testcase.NewResultWithContext(runCtx)
+		err := testCaseInterface.Run(r, req.Namespace, result, testLogger)
+		if err == nil {
+			result.CheckThresholds(instance.Spec.Thresholds)
+			err = result.Err()
+		}
+
+		resultLock.Lock()
+		lastResult = result
+		resultLock.Unlock()
+		return err
+	}
+
+	// Run the test in a goroutine and create a channel that receives its
+	// error. It's buffered so the goroutine can finish after a timeout
+	done := make(chan error, 1)
 	go func() {
-		err := testCaseInterface.Run(r, req.Namespace)
-		done <- err
+		if pollErr != nil {
+			done <- pollErr
+			return
+		}
+		done <- poll(poller, instance.Spec.PollPolicy, run)
 	}()
 
 	var testError error
@@ -111,25 +183,80 @@ func (r *TestCaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	// on which channel, set the new values for the status
 	select {
 	case <-timeoutCh:
-		testError = fmt.Errorf("test timed out after %v", instance.Spec.Timeout)
+		// Stop polling, and cancel the requests of the run in progress
+		cancel()
+		testError = fmt.Errorf("test timed out after %v", *instance.Spec.Timeout)
 		testCaseStatus = thatchdv1alpha1.TestCaseCanceled
 	case err := <-done:
 		testError = err
-		if err != nil {
+		if goerrors.Is(testError, testcase.ErrSkip) {
+			testCaseStatus = thatchdv1alpha1.TestCaseSkipped
+		} else if testError != nil {
 			testCaseStatus = thatchdv1alpha1.TestCaseFailed
 		}
 	}
 
+	resultLock.Lock()
+	result := lastResult
+	resultLock.Unlock()
+
+	if poller != nil {
+		instance.Status.Attempts = poller.Attempts()
+		if lastFailure := poller.LastFailure(); lastFailure != nil {
+			lastFailureMessage := lastFailure.Error()
+			instance.Status.LastAttemptFailure = &lastFailureMessage
+		}
+	}
+
+	// Failing to store the diagnostics, output or artifacts doesn't change
+	// the result of the test case, but is reported in its status
+	var storageErrors []string
+
+	// Collect the diagnostics bundle while the evidence of the failure is
+	// still around
+	if testCaseStatus == thatchdv1alpha1.TestCaseFailed || testCaseStatus == thatchdv1alpha1.TestCaseCanceled {
+		diagnostics, err := r.collectDiagnostics(ctx, instance)
+		if err != nil {
+			log.Error(err, "failed to collect diagnostics")
+			storageErrors = append(storageErrors, fmt.Sprintf("diagnostics: %v", err))
+		}
+		instance.Status.Diagnostics = diagnostics
+	}
+
+	outputReference, err := r.storeOutput(ctx, instance, outputBuffer)
+	if err != nil {
+		log.Error(err, "failed to store output")
+		storageErrors = append(storageErrors, fmt.Sprintf("output: %v", err))
+	}
+	instance.Status.Output = outputReference
+
+	artifacts, err := r.storeArtifacts(ctx, instance, result)
+	if err != nil {
+		log.Error(err, "failed to store artifacts")
+		storageErrors = append(storageErrors, fmt.Sprintf("artifacts: %v", err))
+	}
+	instance.Status.Artifacts = artifacts
+	instance.Status.StorageErrors = storageErrors
+
+	// Test cases that are expected to fail report whether they did
+	if instance.Spec.ExpectFailure != nil {
+		testCaseStatus = expectedFailureStatus(testCaseStatus)
+	}
+
 	// If an error occurred set the failure message
 	if testError != nil {
 		failureMessage := testError.Error()
 		instance.Status.FailureMessage = &failureMessage
 	}
+	instance.Status.Assertions = result.Assertions()
+	instance.Status.Measurements = result.Measurements()
 	instance.Status.Status = testCaseStatus
 	instance.Status.FinishedAt = thatchdv1alpha1.TimeString(time.Now())
 
+	metrics.RecordMeasurements(instance)
+
 	// Update the CR status
-	err = r.Status().Update(context.TODO(), instance)
+	err = patchStatus(ctx, r, instance, original)
 	return ctrl.Result{}, err
 }
 
@@ -138,3 +265,130 @@ func (r *TestCaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&thatchdv1alpha1.TestCase{}).
 		Complete(r)
 }
+
+// collectDiagnostics collects the diagnostics bundle of a failed test case
+// and stores it. Returns nil if the test case doesn't configure diagnostics
+func (r *TestCaseReconciler) collectDiagnostics(ctx context.Context, instance *thatchdv1alpha1.TestCase) (*thatchdv1alpha1.StorageReference, error) {
+	if instance.Spec.Diagnostics == nil || r.Diagnostics == nil {
+		return nil, nil
+	}
+
+	store, err := r.Stores.Get(instance.Spec.Diagnostics.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := r.Diagnostics.Collect(ctx, instance.Namespace, instance.Spec.Diagnostics)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Save(ctx, instance, fmt.Sprintf("%s-diagnostics", instance.Name), files)
+}
+
+// storeOutput stores the lines captured from the test case logger, if any
+func (r *TestCaseReconciler) storeOutput(ctx context.Context, instance *thatchdv1alpha1.TestCase, buffer *output.Buffer) (*thatchdv1alpha1.StorageReference, error) {
+	if buffer.Len() == 0 {
+		return nil, nil
+	}
+
+	var storageType thatchdv1alpha1.StorageType
+	if instance.Spec.Output != nil {
+		storageType = instance.Spec.Output.Storage
+	}
+
+	store, err := r.Stores.Get(storageType)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Save(ctx, instance, fmt.Sprintf("%s-output", instance.Name), map[string][]byte{
+		"output.log": buffer.Bytes(),
+	})
+}
+
+// storeArtifacts stores the artifacts attached to the result, if any, and
+// returns a reference to each of them
+func (r *TestCaseReconciler) storeArtifacts(ctx context.Context, instance *thatchdv1alpha1.TestCase, result *testcase.Result) ([]thatchdv1alpha1.ArtifactReference, error) {
+	artifacts := result.Artifacts()
+	if len(artifacts) == 0 {
+		return nil, nil
+	}
+
+	var storageType thatchdv1alpha1.StorageType
+	if instance.Spec.Artifacts != nil {
+		storageType = instance.Spec.Artifacts.Storage
+	}
+
+	store, err := r.Stores.Get(storageType)
+	if err != nil {
+		return nil, err
+	}
+
+	reference, err := store.Save(ctx, instance, fmt.Sprintf("%s-artifacts", instance.Name), artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make([]thatchdv1alpha1.ArtifactReference, 0, len(artifacts))
+	for name, content := range artifacts {
+		references = append(references, thatchdv1alpha1.ArtifactReference{
+			Name:    name,
+			Size:    len(content),
+			Storage: *reference,
+		})
+	}
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].Name < references[j].Name
+	})
+
+	return references, nil
+}
+
+// newPoller creates the poller of a test case with a poll policy, or returns
+// nil if it runs once
+func newPoller(ctx context.Context, policy *thatchdv1alpha1.PollPolicy) (*assertion.Poller, error) {
+	if policy == nil {
+		return nil, nil
+	}
+
+	duration, err := time.ParseDuration(policy.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poll policy duration: %w", err)
+	}
+
+	interval := defaultPollInterval
+	if policy.Interval != nil {
+		if interval, err = time.ParseDuration(*policy.Interval); err != nil {
+			return nil, fmt.Errorf("invalid poll policy interval: %w", err)
+		}
+	}
+
+	return &assertion.Poller{Duration: duration, Interval: interval, Context: ctx}, nil
+}
+
+// poll runs the test case according to its poll policy, or once if it
+// doesn't have one
+func poll(poller *assertion.Poller, policy *thatchdv1alpha1.PollPolicy, run func() error) error {
+	switch {
+	case poller == nil:
+		return run()
+	case policy.Mode == thatchdv1alpha1.PollConsistently:
+		return poller.Consistently(run)
+	}
+
+	return poller.Eventually(run)
+}
+
+// expectedFailureStatus maps the status of a finished test case into the
+// status of a test case that's expected to fail
+func expectedFailureStatus(status thatchdv1alpha1.TestCaseCurrentStatus) thatchdv1alpha1.TestCaseCurrentStatus {
+	switch status {
+	case thatchdv1alpha1.TestCaseFailed, thatchdv1alpha1.TestCaseCanceled:
+		return thatchdv1alpha1.TestCaseXFailed
+	case thatchdv1alpha1.TestCaseFinished:
+		return thatchdv1alpha1.TestCaseXPassed
+	}
+
+	return status
+}
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/metrics"
+	"github.com/thatchd/thatchd/pkg/thatchd/storage"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTestCaseTimeout(t *testing.T) {
+	ctx := context.TODO()
+	scheme := buildScheme(t)
+	c := fake.NewFakeClientWithScheme(scheme)
+
+	// The first attempt fails quickly, and the second one is still running
+	// when the test case times out
+	var lock sync.Mutex
+	attempts := 0
+	provider := strategy.NewProviderFunction(func(map[string]string) interface{} {
+		return &testCaseInterfaceMock{
+			shouldRun: func(interface{}) bool { return true },
+			run: func(_ client.Client, result *testcase.Result, _ logr.Logger) error {
+				lock.Lock()
+				attempts++
+				attempt := attempts
+				lock.Unlock()
+
+				if attempt == 1 {
+					result.Equal("ready", true, false)
+					return nil
+				}
+
+				result.Equal("partial", true, true)
+				time.Sleep(300 * time.Millisecond)
+				return errors.New("component A is not ready")
+			},
+		}
+	})
+
+	if err := c.Create(ctx, &thatchdv1alpha1.TestCase{
+		ObjectMeta: v1.ObjectMeta{Name: "test-case", Namespace: "thatchd"},
+		Spec: thatchdv1alpha1.TestCaseSpec{
+			Timeout: addr("100ms"),
+			PollPolicy: &thatchdv1alpha1.PollPolicy{
+				Mode:     thatchdv1alpha1.PollEventually,
+				Duration: "10s",
+				Interval: addr("10ms"),
+			},
+			Strategy: thatchdv1alpha1.Strategy{
+				Strategy: strategy.Strategy{Provider: "timeout"},
+			},
+		},
+		Status: thatchdv1alpha1.TestCaseStatus{
+			Status:       thatchdv1alpha1.TestCaseDispatched,
+			DispatchedAt: thatchdv1alpha1.TimeString(time.Now()),
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := &TestCaseReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		StrategyProviders: map[string]strategy.StrategyProvider{"timeout": provider},
+		Log:               ctrl.Log.Logger,
+	}
+	if _, err := reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+		Name:      "test-case",
+		Namespace: "thatchd",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCase := &thatchdv1alpha1.TestCase{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "test-case", Namespace: "thatchd"}, testCase); err != nil {
+		t.Fatal(err)
+	}
+
+	if testCase.Status.Status != thatchdv1alpha1.TestCaseCanceled {
+		t.Errorf("expected test case to be canceled, but was %s", testCase.Status.Status)
+	}
+	if testCase.Status.FailureMessage == nil || *testCase.Status.FailureMessage != "test timed out after 100ms" {
+		t.Errorf("unexpected failure message %v", testCase.Status.FailureMessage)
+	}
+
+	// The status reports the last attempt that completed
+	if assertions := testCase.Status.Assertions; len(assertions) != 1 || assertions[0].Name != "ready" {
+		t.Errorf("expected the assertions of the first attempt, got %v", assertions)
+	}
+
+	// The polling stops after the attempt in progress
+	time.Sleep(500 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	if attempts != 2 {
+		t.Errorf("expected the polling to stop after 2 attempts, got %d", attempts)
+	}
+}
+
+func TestTestCaseMeasurementsDeleted(t *testing.T) {
+	ctx := context.TODO()
+	scheme := buildScheme(t)
+	c := fake.NewFakeClientWithScheme(scheme)
+
+	provider := strategy.NewProviderFunction(func(map[string]string) interface{} {
+		return &testCaseInterfaceMock{
+			shouldRun: func(interface{}) bool { return true },
+			run: func(_ client.Client, result *testcase.Result, _ logr.Logger) error {
+				result.Measure("replicas", 3, "")
+				return nil
+			},
+		}
+	})
+
+	testCase := &thatchdv1alpha1.TestCase{
+		ObjectMeta: v1.ObjectMeta{Name: "measured", Namespace: "thatchd"},
+		Spec: thatchdv1alpha1.TestCaseSpec{
+			Strategy: thatchdv1alpha1.Strategy{
+				Strategy: strategy.Strategy{Provider: "measured"},
+			},
+		},
+		Status: thatchdv1alpha1.TestCaseStatus{
+			Status:       thatchdv1alpha1.TestCaseDispatched,
+			DispatchedAt: thatchdv1alpha1.TimeString(time.Now()),
+		},
+	}
+	if err := c.Create(ctx, testCase); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := &TestCaseReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		StrategyProviders: map[string]strategy.StrategyProvider{"measured": provider},
+		Log:               ctrl.Log.Logger,
+	}
+	reconcileTestCase := func() {
+		if _, err := reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      "measured",
+			Namespace: "thatchd",
+		}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reconcileTestCase()
+	if value := testutil.ToFloat64(metrics.Measurements.WithLabelValues("thatchd", "measured", "replicas", "")); value != 3 {
+		t.Errorf("expected the measurement to be exported, got %v", value)
+	}
+
+	if err := c.Delete(ctx, testCase); err != nil {
+		t.Fatal(err)
+	}
+	reconcileTestCase()
+
+	if metrics.Measurements.DeleteLabelValues("thatchd", "measured", "replicas", "") {
+		t.Errorf("expected the measurement to be deleted with the test case")
+	}
+}
+
+func TestTestCaseStorageErrors(t *testing.T) {
+	ctx := context.TODO()
+	scheme := buildScheme(t)
+	c := fake.NewFakeClientWithScheme(scheme)
+
+	provider := strategy.NewProviderFunction(func(map[string]string) interface{} {
+		return &testCaseInterfaceMock{
+			shouldRun: func(interface{}) bool { return true },
+			run: func(_ client.Client, result *testcase.Result, _ logr.Logger) error {
+				return result.Attach("response.json", []byte("{}"))
+			},
+		}
+	})
+
+	if err := c.Create(ctx, &thatchdv1alpha1.TestCase{
+		ObjectMeta: v1.ObjectMeta{Name: "attaching", Namespace: "thatchd"},
+		Spec: thatchdv1alpha1.TestCaseSpec{
+			Artifacts: &thatchdv1alpha1.ArtifactsSpec{Storage: thatchdv1alpha1.StorageS3},
+			Strategy: thatchdv1alpha1.Strategy{
+				Strategy: strategy.Strategy{Provider: "attaching"},
+			},
+		},
+		Status: thatchdv1alpha1.TestCaseStatus{
+			Status:       thatchdv1alpha1.TestCaseDispatched,
+			DispatchedAt: thatchdv1alpha1.TimeString(time.Now()),
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := &TestCaseReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		StrategyProviders: map[string]strategy.StrategyProvider{"attaching": provider},
+		Stores:            storage.NewStores(c, scheme, ""),
+		Log:               ctrl.Log.Logger,
+	}
+	if _, err := reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+		Name:      "attaching",
+		Namespace: "thatchd",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCase := &thatchdv1alpha1.TestCase{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "attaching", Namespace: "thatchd"}, testCase); err != nil {
+		t.Fatal(err)
+	}
+
+	if testCase.Status.Status != thatchdv1alpha1.TestCaseFinished {
+		t.Errorf("expected the test case to finish, but was %s", testCase.Status.Status)
+	}
+	if errs := testCase.Status.StorageErrors; len(errs) != 1 || errs[0] != "artifacts: storage S3 is not available" {
+		t.Errorf("expected the artifacts storage failure to be reported, got %v", errs)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+)
+
+const (
+	// monitorCheckTimeout is the time a check of a monitor can take before
+	// it's a violation
+	monitorCheckTimeout = 30 * time.Second
+	// monitorCheckPeriod is the time between checks of a monitor that
+	// doesn't have an interval
+	monitorCheckPeriod = 10 * time.Second
+	// monitorViolationsLimit is the number of violations kept in the
+	// TestMonitor status
+	monitorViolationsLimit = 10
+	// maxConcurrentMonitors is the number of TestMonitors checked at the
+	// same time, so a slow check doesn't hold back the others
+	maxConcurrentMonitors = 4
+)
+
+// TestMonitorReconciler checks the active TestMonitors every interval. The
+// TestSuiteReconciler activates and deactivates the monitors
+type TestMonitorReconciler struct {
+	client.Client
+	Log               logr.Logger
+	Scheme            *runtime.Scheme
+	StrategyProviders map[string]strategy.StrategyProvider
+}
+
+// +kubebuilder:rbac:groups=testing.thatchd.io,resources=testmonitors,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=testing.thatchd.io,resources=testmonitors/status,verbs=get;update;patch
+
+func (r *TestMonitorReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("testmonitor", req.NamespacedName)
+
+	instance := &thatchdv1alpha1.TestMonitor{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	if !instance.Status.Active {
+		return ctrl.Result{}, nil
+	}
+
+	// The monitor isn't checked until its interval is fixed, which triggers
+	// a reconciliation
+	interval, err := monitorInterval(instance)
+	if err != nil {
+		log.Error(err, "invalid monitor")
+		original := instance.DeepCopy()
+		instance.Status.Error = err.Error()
+		return ctrl.Result{}, patchStatus(ctx, r, instance, original)
+	}
+
+	// Updating the status after a check triggers a reconciliation, so wait
+	// for the next check to be due
+	if lastCheckedAt := instance.Status.LastCheckedAt; lastCheckedAt != nil {
+		if wait := time.Until(lastCheckedAt.Add(interval)); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	if err := checkMonitor(ctx, r, r.StrategyProviders, instance, log); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+func (r *TestMonitorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&thatchdv1alpha1.TestMonitor{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentMonitors}).
+		Complete(r)
+}
+
+// monitorInterval returns the time between two checks of the monitor
+func monitorInterval(monitor *thatchdv1alpha1.TestMonitor) (time.Duration, error) {
+	if monitor.Spec.Interval == nil {
+		return monitorCheckPeriod, nil
+	}
+
+	interval, err := time.ParseDuration(*monitor.Spec.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval: %w", err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid interval: %s is not positive", *monitor.Spec.Interval)
+	}
+
+	return interval, nil
+}
+
+// checkMonitor runs the strategy of a monitor once and records the check in
+// its status. A failed check is a violation, which fails the suite verdict.
+// Skipped checks aren't violations
+func checkMonitor(ctx context.Context, c client.Client, providers map[string]strategy.StrategyProvider, monitor *thatchdv1alpha1.TestMonitor, log logr.Logger) error {
+	str := strategy.Strategy(monitor.Spec.Strategy.Strategy)
+
+	var checkErr error
+	if testCaseInterface, err := testcase.FromStrategy(&str, providers); err != nil {
+		checkErr = fmt.Errorf("error obtaining strategy: %w", err)
+	} else {
+		checkErr = runMonitorCheck(ctx, testCaseInterface, c, monitor.Namespace, log)
+	}
+
+	original := monitor.DeepCopy()
+	now := metav1.Now()
+	monitor.Status.Error = ""
+	monitor.Status.Checks++
+	monitor.Status.LastCheckedAt = &now
+
+	if checkErr == nil || goerrors.Is(checkErr, testcase.ErrSkip) {
+		if monitor.Status.Status != thatchdv1alpha1.TestMonitorViolated {
+			monitor.Status.Status = thatchdv1alpha1.TestMonitorHealthy
+		}
+	} else {
+		log.Info("monitor violated", "violation", checkErr.Error())
+		monitor.Status.Status = thatchdv1alpha1.TestMonitorViolated
+		monitor.Status.ViolationCount++
+		monitor.Status.Violations = append(monitor.Status.Violations, thatchdv1alpha1.MonitorViolation{
+			Time:    now,
+			Message: checkErr.Error(),
+		})
+		if excess := len(monitor.Status.Violations) - monitorViolationsLimit; excess > 0 {
+			monitor.Status.Violations = monitor.Status.Violations[excess:]
+		}
+	}
+
+	if err := patchStatus(ctx, c, monitor, original); err != nil {
+		return fmt.Errorf("error recording check of TestMonitor %s: %w", monitor.Name, err)
+	}
+
+	return nil
+}
+
+// runMonitorCheck runs the strategy of a monitor, failing if it doesn't
+// finish in time. The context of the result is canceled on timeout, so the
+// check stops once its requests are
+func runMonitorCheck(ctx context.Context, testCaseInterface testcase.Interface, c client.Client, namespace string, log logr.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, monitorCheckTimeout)
+	defer cancel()
+
+	result := testcase.NewResultWithContext(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- testCaseInterface.Run(c, namespace, result, log)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		return result.Err()
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %v", monitorCheckTimeout)
+	}
+}
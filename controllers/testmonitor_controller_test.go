@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// monitorStrategyProvider creates monitors that check the components of the
+// test program state
+type monitorStrategyProvider struct{}
+
+var _ strategy.StrategyProvider = &monitorStrategyProvider{}
+
+func (p *monitorStrategyProvider) New(configuration map[string]string) interface{} {
+	return &testCaseInterfaceMock{
+		shouldRun: func(testContext interface{}) bool {
+			if configuration["Component"] == "B" {
+				return testContext.(testProgramState).ComponentB.Ready
+			}
+			return testContext.(testProgramState).ComponentA.Ready
+		},
+		run: func(client client.Client, result *testcase.Result, _ logr.Logger) error {
+			if configuration["Violated"] == "true" {
+				return errors.New("component A is not healthy")
+			}
+			result.True("healthy", true, "")
+			return nil
+		},
+	}
+}
+
+func TestTestMonitors(t *testing.T) {
+	ctx := context.TODO()
+	scheme := buildScheme(t)
+	c := fake.NewFakeClientWithScheme(scheme)
+
+	providers := map[string]strategy.StrategyProvider{
+		"testSuiteStrategyProvider": &testSuiteStrategyProvider{},
+		"monitor":                   &monitorStrategyProvider{},
+	}
+
+	monitor := func(name string, configuration map[string]string, interval *string) *thatchdv1alpha1.TestMonitor {
+		return &thatchdv1alpha1.TestMonitor{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "thatchd"},
+			Spec: thatchdv1alpha1.TestMonitorSpec{
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{Provider: "monitor", Configuration: configuration},
+				},
+				Interval: interval,
+			},
+		}
+	}
+
+	for _, object := range []runtime.Object{
+		&thatchdv1alpha2.TestSuite{
+			ObjectMeta: v1.ObjectMeta{Name: "test-suite", Namespace: "thatchd"},
+			Spec: thatchdv1alpha2.TestSuiteSpec{
+				StateStrategy: thatchdv1alpha2.Strategy{
+					Strategy: strategy.Strategy{Provider: "testSuiteStrategyProvider"},
+				},
+			},
+		},
+		&thatchdv1alpha1.TestCase{
+			ObjectMeta: v1.ObjectMeta{Name: "test-case", Namespace: "thatchd"},
+			Spec: thatchdv1alpha1.TestCaseSpec{
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{Provider: "monitor", Configuration: map[string]string{"Component": "B"}},
+				},
+			},
+		},
+		monitor("healthy", map[string]string{}, nil),
+		monitor("violated", map[string]string{"Violated": "true"}, nil),
+		monitor("inactive", map[string]string{"Component": "B"}, nil),
+		monitor("interval", map[string]string{}, addr("1h")),
+		monitor("invalid", map[string]string{}, addr("soon")),
+		&thatchdv1alpha1.TestMonitor{
+			ObjectMeta: v1.ObjectMeta{Name: "unknown", Namespace: "thatchd"},
+			Spec: thatchdv1alpha1.TestMonitorSpec{
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{Provider: "unknown"},
+				},
+			},
+		},
+	} {
+		if err := c.Create(ctx, object); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	suiteReconciler := &TestSuiteReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		StrategyProviders: providers,
+		Log:               ctrl.Log.Logger,
+	}
+	monitorReconciler := &TestMonitorReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		StrategyProviders: providers,
+		Log:               ctrl.Log.Logger,
+	}
+
+	reconcileSuite := func() {
+		if _, err := suiteReconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reconcileMonitor := func(name string) reconcile.Result {
+		result, err := monitorReconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: "thatchd",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	getMonitor := func(name string) *thatchdv1alpha1.TestMonitor {
+		monitor := &thatchdv1alpha1.TestMonitor{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "thatchd"}, monitor); err != nil {
+			t.Fatal(err)
+		}
+		return monitor
+	}
+	assertMonitor := func(name string, active bool, status thatchdv1alpha1.TestMonitorCurrentStatus, checks, violations int) {
+		monitor := getMonitor(name)
+		if monitor.Status.Active != active {
+			t.Errorf("expected monitor %s to be active: %v", name, active)
+		}
+		if monitor.Status.Status != status {
+			t.Errorf("expected monitor %s to be %s, but was %s", name, status, monitor.Status.Status)
+		}
+		if monitor.Status.Checks != checks {
+			t.Errorf("expected monitor %s to be checked %d times, but was %d", name, checks, monitor.Status.Checks)
+		}
+		if monitor.Status.ViolationCount != violations || len(monitor.Status.Violations) != violations {
+			t.Errorf("expected monitor %s to record %d violations, got %v", name, violations, monitor.Status.Violations)
+		}
+	}
+
+	// The suite activates the monitors, which are checked by their own
+	// controller
+	reconcileSuite()
+
+	assertMonitor("healthy", true, thatchdv1alpha1.TestMonitorPending, 0, 0)
+	assertMonitor("inactive", false, thatchdv1alpha1.TestMonitorPending, 0, 0)
+
+	// Monitors without an interval are checked every monitorCheckPeriod
+	for _, name := range []string{"healthy", "violated", "inactive", "unknown"} {
+		reconcileMonitor(name)
+	}
+	if result := reconcileMonitor("healthy"); result.RequeueAfter <= 0 || result.RequeueAfter > monitorCheckPeriod {
+		t.Errorf("expected the next check to be due within %v, got %v", monitorCheckPeriod, result.RequeueAfter)
+	}
+
+	assertMonitor("healthy", true, thatchdv1alpha1.TestMonitorHealthy, 1, 0)
+	assertMonitor("violated", true, thatchdv1alpha1.TestMonitorViolated, 1, 1)
+	assertMonitor("inactive", false, thatchdv1alpha1.TestMonitorPending, 0, 0)
+	assertMonitor("unknown", true, thatchdv1alpha1.TestMonitorViolated, 1, 1)
+
+	for _, name := range []string{"healthy", "violated", "unknown"} {
+		monitor := getMonitor(name)
+		lastCheckedAt := v1.NewTime(monitor.Status.LastCheckedAt.Add(-monitorCheckPeriod))
+		monitor.Status.LastCheckedAt = &lastCheckedAt
+		if err := c.Status().Update(ctx, monitor); err != nil {
+			t.Fatal(err)
+		}
+
+		if result := reconcileMonitor(name); result.RequeueAfter != monitorCheckPeriod {
+			t.Errorf("expected the next check of %s in %v, got %v", name, monitorCheckPeriod, result.RequeueAfter)
+		}
+	}
+
+	assertMonitor("healthy", true, thatchdv1alpha1.TestMonitorHealthy, 2, 0)
+	assertMonitor("violated", true, thatchdv1alpha1.TestMonitorViolated, 2, 2)
+	assertMonitor("unknown", true, thatchdv1alpha1.TestMonitorViolated, 2, 2)
+
+	if violation := getMonitor("violated").Status.Violations[0]; violation.Message != "component A is not healthy" || violation.Time.IsZero() {
+		t.Errorf("unexpected violation %v", violation)
+	}
+
+	reconcileSuite()
+
+	suite := &thatchdv1alpha2.TestSuite{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "test-suite", Namespace: "thatchd"}, suite); err != nil {
+		t.Fatal(err)
+	}
+	if suite.Status.Verdict != thatchdv1alpha2.TestSuiteFailed {
+		t.Errorf("expected the violated monitor to fail the suite, but the verdict is %s", suite.Status.Verdict)
+	}
+	if suite.Status.Summary.ViolatedMonitors != 2 {
+		t.Errorf("expected 2 violated monitors in the summary, got %d", suite.Status.Summary.ViolatedMonitors)
+	}
+
+	// Monitors with an interval are checked once it's due
+	if result := reconcileMonitor("interval"); result.RequeueAfter.Hours() != 1 {
+		t.Errorf("expected the next check in 1h, got %v", result.RequeueAfter)
+	}
+	if result := reconcileMonitor("interval"); result.RequeueAfter <= 0 || result.RequeueAfter.Hours() > 1 {
+		t.Errorf("expected the next check to be due within 1h, got %v", result.RequeueAfter)
+	}
+	assertMonitor("interval", true, thatchdv1alpha1.TestMonitorHealthy, 1, 0)
+
+	// Monitors with an invalid interval aren't checked, and report it
+	if result := reconcileMonitor("invalid"); result.RequeueAfter != 0 {
+		t.Errorf("expected the invalid monitor not to be requeued, got %v", result.RequeueAfter)
+	}
+	assertMonitor("invalid", true, thatchdv1alpha1.TestMonitorPending, 0, 0)
+	if err := getMonitor("invalid").Status.Error; err != `invalid interval: time: invalid duration "soon"` {
+		t.Errorf("expected the invalid interval to be reported, got %q", err)
+	}
+
+	// Monitors stop once the test cases of the suite finish
+	testCase := &thatchdv1alpha1.TestCase{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "test-case", Namespace: "thatchd"}, testCase); err != nil {
+		t.Fatal(err)
+	}
+	testCase.Status.Status = thatchdv1alpha1.TestCaseFinished
+	if err := c.Status().Update(ctx, testCase); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileSuite()
+
+	assertMonitor("healthy", false, thatchdv1alpha1.TestMonitorHealthy, 2, 0)
+	assertMonitor("interval", false, thatchdv1alpha1.TestMonitorHealthy, 1, 0)
+}
+
+func TestRunMonitorCheckCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	// The check only returns once the test is done, so it can't finish
+	// before timing out
+	stopped, release := make(chan struct{}), make(chan struct{})
+	testCaseInterface := &testCaseInterfaceMock{
+		run: func(_ client.Client, result *testcase.Result, _ logr.Logger) error {
+			<-result.Context().Done()
+			close(stopped)
+			<-release
+			return result.Context().Err()
+		},
+	}
+
+	err := runMonitorCheck(ctx, testCaseInterface, fake.NewFakeClient(), "thatchd", ctrl.Log.Logger)
+	close(release)
+	if err == nil || err.Error() != "check timed out after 30s" {
+		t.Errorf("expected the check to time out, got %v", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Errorf("expected the check to be canceled")
+	}
+}
@@ -29,6 +29,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/report"
+	"github.com/thatchd/thatchd/pkg/thatchd/state"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
@@ -45,12 +48,15 @@ type TestSuiteReconciler struct {
 
 // +kubebuilder:rbac:groups=testing.thatchd.io,resources=testsuites,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=testing.thatchd.io,resources=testsuites/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=testing.thatchd.io,resources=testmonitors,verbs=get;list;watch
+// +kubebuilder:rbac:groups=testing.thatchd.io,resources=testmonitors/status,verbs=get;update;patch
 
 func (r *TestSuiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
-	_ = r.Log.WithValues("testsuite", req.NamespacedName)
+	log := r.Log.WithValues("testsuite", req.NamespacedName)
 
-	instance := &thatchdv1alpha1.TestSuite{}
+	instance := &thatchdv1alpha2.TestSuite{}
 	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{}, nil
@@ -59,13 +65,12 @@ func (r *TestSuiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	var currentState string
-	if instance.Status.CurrentState != "" {
-		currentState = instance.Status.CurrentState
-	} else if instance.Spec.InitialState != "" {
-		currentState = instance.Spec.InitialState
-	} else {
-		currentState = "{}"
+	original := instance.DeepCopy()
+	stateStore := &state.Store{Client: r.Client, Scheme: r.Scheme}
+
+	storedState, err := stateStore.Load(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error loading state: %w", err)
 	}
 
 	str := strategy.Strategy(instance.Spec.StateStrategy.Strategy)
@@ -75,35 +80,82 @@ func (r *TestSuiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return r.withErrorStatus(ctx, instance, fmt.Errorf("error obtaining program reconciler: %v", err))
 	}
 
+	currentState, err := currentSuiteState(instance, programReconciler, storedState)
+	if err != nil {
+		return r.withErrorStatus(ctx, instance, err)
+	}
+
+	if err := testsuite.ValidateState(programReconciler, currentState); err != nil {
+		return r.withErrorStatus(ctx, instance, fmt.Errorf("invalid current state: %w", err))
+	}
+
 	parsedState, err := programReconciler.ParseState(currentState)
 	if err != nil {
 		return r.withErrorStatus(ctx, instance, fmt.Errorf("failed to parse current state: %w", err))
 	}
 
 	// Reconcile the program state
-	updatedState, err := programReconciler.Reconcile(r.Client, req.Namespace, parsedState)
+	updatedState, err := programReconciler.Reconcile(r.Client, req.Namespace, parsedState, log)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("error reconciling program state: %v", err)
 	}
 
-	marshalledState, err := json.MarshalIndent(updatedState, "", "  ")
+	marshalledState, err := json.Marshal(updatedState)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("error marshalling state: %v", err)
 	}
 
-	instance.Status.CurrentState = string(marshalledState)
-	if err := r.Status().Update(context.TODO(), instance); err != nil {
+	// Reject states that couldn't be read on the next reconciliation, keeping
+	// the current one
+	if err := testsuite.ValidateState(programReconciler, string(marshalledState)); err != nil {
+		return r.withErrorStatus(ctx, instance, fmt.Errorf("invalid reconciled state: %w", err))
+	}
+
+	testCases := &thatchdv1alpha1.TestCaseList{}
+	if err := r.List(ctx, testCases, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error listing test cases: %w", err)
+	}
+
+	if err := stateStore.Save(ctx, instance, string(marshalledState)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error storing state: %w", err)
+	}
+	instance.Status.StateVersion = testsuite.StateVersion(programReconciler)
+
+	monitors, err := r.reconcileMonitors(ctx, req.Namespace, updatedState, suiteActive(testCases.Items), log)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling test monitors: %w", err)
+	}
+
+	instance.Status.Error = ""
+	instance.Status.Verdict, instance.Status.Summary = report.Summarize(testCases.Items, monitors)
+	if err := patchStatus(ctx, r, instance, original, client.MergeFromWithOptimisticLock{}); err != nil {
 		return ctrl.Result{}, fmt.Errorf("error updating state: %v", err)
 	}
 
-	if err := r.dispatchTestCases(ctx, req.Namespace, updatedState); err != nil {
+	// The state ConfigMaps replaced by the update are only removed once
+	// nothing references them
+	if err := stateStore.Prune(ctx, instance); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error pruning state: %w", err)
+	}
+
+	// Only transitions that were persisted are recorded
+	history := &state.History{Client: r.Client, Scheme: r.Scheme}
+	if err := history.Record(ctx, instance, state.SuiteSource, previousSuiteState(storedState, currentState), string(marshalledState)); err != nil {
+		log.Error(err, "failed to record state transition")
+	}
+
+	if err := r.dispatchTestCases(ctx, req.Namespace, updatedState, log); err != nil {
 		return ctrl.Result{}, fmt.Errorf("error dispatching test cases: %v", err)
 	}
 
-	if err := r.dispatchTestWorkers(ctx, req.Namespace, updatedState); err != nil {
+	if err := r.dispatchTestWorkers(ctx, req.Namespace, updatedState, log); err != nil {
 		return ctrl.Result{}, fmt.Errorf("error dispatching test workers: %w", err)
 	}
 
+	if err := report.Publish(ctx, r.Client, r.Scheme, instance, report.New(instance, testCases.Items, monitors)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error publishing report: %w", err)
+	}
+
 	return ctrl.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second,
@@ -112,11 +164,11 @@ func (r *TestSuiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 func (r *TestSuiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&thatchdv1alpha1.TestSuite{}).
+		For(&thatchdv1alpha2.TestSuite{}).
 		Complete(r)
 }
 
-func (r *TestSuiteReconciler) dispatchTestCases(ctx context.Context, namespace string, currentState interface{}) error {
+func (r *TestSuiteReconciler) dispatchTestCases(ctx context.Context, namespace string, currentState interface{}, log logr.Logger) error {
 	testCases := &thatchdv1alpha1.TestCaseList{}
 	if err := r.List(ctx, testCases); err != nil {
 		return err
@@ -131,7 +183,7 @@ func (r *TestSuiteReconciler) dispatchTestCases(ctx context.Context, namespace s
 		}
 
 		// Skip tests that aren't meant to be run yet
-		if !testCaseInterface.ShouldRun(currentState) {
+		if !testCaseInterface.ShouldRun(currentState, log.WithValues("testcase", testCase.Name)) {
 			continue
 		}
 
@@ -141,9 +193,10 @@ func (r *TestSuiteReconciler) dispatchTestCases(ctx context.Context, namespace s
 		}
 
 		// Dispatch by setting the DispatchedAt field to the current time
+		original := testCase.DeepCopy()
 		testCase.Status.DispatchedAt = thatchdv1alpha1.TimeString(time.Now())
 		testCase.Status.Status = thatchdv1alpha1.TestCaseDispatched
-		if err := r.Status().Update(ctx, &testCase); err != nil {
+		if err := patchStatus(ctx, r, &testCase, original); err != nil {
 			return fmt.Errorf("error dispatching TestCase %s", testCase.Name)
 		}
 	}
@@ -151,7 +204,7 @@ func (r *TestSuiteReconciler) dispatchTestCases(ctx context.Context, namespace s
 	return nil
 }
 
-func (r *TestSuiteReconciler) dispatchTestWorkers(ctx context.Context, namespace string, currentState interface{}) error {
+func (r *TestSuiteReconciler) dispatchTestWorkers(ctx context.Context, namespace string, currentState interface{}, log logr.Logger) error {
 	testWorkers := &thatchdv1alpha1.TestWorkerList{}
 	if err := r.List(ctx, testWorkers); err != nil {
 		return err
@@ -165,7 +218,7 @@ func (r *TestSuiteReconciler) dispatchTestWorkers(ctx context.Context, namespace
 			return err
 		}
 
-		if !testWorkerInterface.ShouldRun(currentState) {
+		if !testWorkerInterface.ShouldRun(currentState, log.WithValues("testworker", testWorker.Name)) {
 			continue
 		}
 
@@ -173,8 +226,9 @@ func (r *TestSuiteReconciler) dispatchTestWorkers(ctx context.Context, namespace
 			continue
 		}
 
+		original := testWorker.DeepCopy()
 		testWorker.Status.DispatchedAt = thatchdv1alpha1.TimeString(time.Now())
-		if err := r.Status().Update(ctx, &testWorker); err != nil {
+		if err := patchStatus(ctx, r, &testWorker, original); err != nil {
 			return fmt.Errorf("error dispatching TestWorker %s: %v", testWorker.Name, err)
 		}
 	}
@@ -182,9 +236,64 @@ func (r *TestSuiteReconciler) dispatchTestWorkers(ctx context.Context, namespace
 	return nil
 }
 
-func (r *TestSuiteReconciler) withErrorStatus(ctx context.Context, instance *thatchdv1alpha1.TestSuite, errorStatus error) (ctrl.Result, error) {
+// reconcileMonitors activates the monitors whose dispatch condition holds
+// while the suite is active, for the TestMonitorReconciler to check them.
+// Returns the monitors of the namespace
+func (r *TestSuiteReconciler) reconcileMonitors(ctx context.Context, namespace string, currentState interface{}, active bool, log logr.Logger) ([]thatchdv1alpha1.TestMonitor, error) {
+	testMonitors := &thatchdv1alpha1.TestMonitorList{}
+	if err := r.List(ctx, testMonitors, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	for i := range testMonitors.Items {
+		testMonitor := &testMonitors.Items[i]
+		monitorLog := log.WithValues("testmonitor", testMonitor.Name)
+		str := testMonitor.Spec.Strategy.Strategy
+
+		// A monitor with an invalid strategy stays active, so its checks
+		// record the error as a violation
+		shouldRun := true
+		if testCaseInterface, err := testcase.FromStrategy(&str, r.StrategyProviders); err != nil {
+			monitorLog.Error(err, "invalid monitor strategy")
+		} else {
+			shouldRun = testCaseInterface.ShouldRun(currentState, monitorLog)
+		}
+
+		original := testMonitor.DeepCopy()
+		testMonitor.Status.Active = active && shouldRun
+		if testMonitor.Status.Status == "" {
+			testMonitor.Status.Status = thatchdv1alpha1.TestMonitorPending
+		}
+		if err := patchStatus(ctx, r, testMonitor, original); err != nil {
+			return nil, fmt.Errorf("error activating TestMonitor %s: %w", testMonitor.Name, err)
+		}
+	}
+
+	return testMonitors.Items, nil
+}
+
+// suiteActive returns whether the suite has test cases waiting to be
+// dispatched or in progress, or none yet. Monitors are only checked while
+// the suite is active
+func suiteActive(testCases []thatchdv1alpha1.TestCase) bool {
+	if len(testCases) == 0 {
+		return true
+	}
+
+	for _, testCase := range testCases {
+		switch testCase.Status.Status {
+		case "", thatchdv1alpha1.TestCaseCreated, thatchdv1alpha1.TestCaseDispatched, thatchdv1alpha1.TestCaseRunning:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *TestSuiteReconciler) withErrorStatus(ctx context.Context, instance *thatchdv1alpha2.TestSuite, errorStatus error) (ctrl.Result, error) {
+	original := instance.DeepCopy()
 	instance.Status.Error = errorStatus.Error()
-	if err := r.Status().Update(ctx, instance); err != nil {
+	if err := patchStatus(ctx, r, instance, original); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to update to error status \"%v\": %v", errorStatus, err)
 	}
 
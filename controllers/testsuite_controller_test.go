@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/state"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// failingStatusClient fails the status updates while failStatus is set
+type failingStatusClient struct {
+	client.Client
+	failStatus bool
+}
+
+func (c *failingStatusClient) Status() client.StatusWriter {
+	return &failingStatusWriter{c.Client.Status(), c}
+}
+
+type failingStatusWriter struct {
+	client.StatusWriter
+	client *failingStatusClient
+}
+
+func (w *failingStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if w.client.failStatus {
+		return errors.New("the object has been modified")
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func TestTestSuiteStateStorage(t *testing.T) {
+	ctx := context.TODO()
+	scheme := buildScheme(t)
+	c := &failingStatusClient{Client: fake.NewFakeClientWithScheme(scheme)}
+
+	// The state counts the reconciliations, so it changes every time
+	counter := strategy.NewProviderFunction(func(map[string]string) interface{} {
+		return &testProgramReconcilerMock{
+			parseState: func(s string) (interface{}, error) {
+				result := map[string]int{}
+				err := json.Unmarshal([]byte(s), &result)
+				return result, err
+			},
+			reconcile: func(_ client.Client, currentState interface{}) (interface{}, error) {
+				count := currentState.(map[string]int)["count"]
+				return map[string]int{"count": count + 1}, nil
+			},
+		}
+	})
+
+	if err := c.Create(ctx, &thatchdv1alpha2.TestSuite{
+		ObjectMeta: v1.ObjectMeta{Name: "test-suite", Namespace: "thatchd"},
+		Spec: thatchdv1alpha2.TestSuiteSpec{
+			InitialState: &apiextensionsv1.JSON{Raw: []byte(`{"count":0}`)},
+			StateStrategy: thatchdv1alpha2.Strategy{
+				Strategy: strategy.Strategy{Provider: "counter"},
+			},
+			StateStorage: &thatchdv1alpha2.StateStorageSpec{
+				Type:      thatchdv1alpha2.StateStorageConfigMap,
+				ChunkSize: 4,
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := &TestSuiteReconciler{
+		Client:            c,
+		Scheme:            scheme,
+		StrategyProviders: map[string]strategy.StrategyProvider{"counter": counter},
+		Log:               ctrl.Log.Logger,
+	}
+	reconcileSuite := func() error {
+		_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		}})
+		return err
+	}
+
+	if err := reconcileSuite(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed status update doesn't break the stored state
+	c.failStatus = true
+	if err := reconcileSuite(); err == nil {
+		t.Fatal("expected the status update to fail")
+	}
+	c.failStatus = false
+
+	if err := reconcileSuite(); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := &thatchdv1alpha2.TestSuite{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "test-suite", Namespace: "thatchd"}, suite); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := (&state.Store{Client: c, Scheme: scheme}).Load(ctx, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != `{"count":2}` {
+		t.Errorf("expected the state to be updated twice, got %s", stored)
+	}
+
+	// The failed update isn't recorded in the history
+	transitions, err := (&state.History{Client: c, Scheme: scheme}).Load(ctx, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 {
+		t.Errorf("expected 2 recorded transitions, got %d", len(transitions))
+	}
+
+	// Only the chunks of the stored state are kept
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, client.HasLabels{state.ChunkLabel}); err != nil {
+		t.Fatal(err)
+	}
+	if len(configMaps.Items) != len(suite.Status.StateReference.ConfigMaps) {
+		t.Errorf("expected %d state ConfigMaps, got %d", len(suite.Status.StateReference.ConfigMaps), len(configMaps.Items))
+	}
+}
@@ -0,0 +1,124 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+)
+
+// TestSuiteValidator validates the initial state of TestSuites against the
+// schema of their state strategy
+type TestSuiteValidator struct {
+	StrategyProviders map[string]strategy.StrategyProvider
+
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-testing-thatchd-io-testsuite,mutating=false,failurePolicy=fail,groups=testing.thatchd.io,resources=testsuites,verbs=create;update,versions=v1alpha1;v1alpha2,name=vtestsuite.thatchd.io
+
+var _ admission.Handler = &TestSuiteValidator{}
+var _ admission.DecoderInjector = &TestSuiteValidator{}
+
+// SetupWebhookWithManager registers the webhook in the manager webhook server
+func (v *TestSuiteValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/validate-testing-thatchd-io-testsuite", &webhook.Admission{Handler: v})
+	return nil
+}
+
+// InjectDecoder injects the decoder of the admission requests
+func (v *TestSuiteValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *TestSuiteValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance, err := v.decode(req.Kind.Version, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Only validate updates that change the initial state or its strategy,
+	// so suites can still be updated, for example to be deleted, after
+	// their strategy changes
+	if req.Operation == v1beta1.Update {
+		old, err := v.decode(req.Kind.Version, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		if reflect.DeepEqual(old.Spec.InitialState, instance.Spec.InitialState) &&
+			reflect.DeepEqual(old.Spec.StateStrategy, instance.Spec.StateStrategy) {
+			return admission.Allowed("")
+		}
+	}
+
+	if err := v.validate(instance); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
+
+// decode decodes a TestSuite of any version into the hub version
+func (v *TestSuiteValidator) decode(version string, raw runtime.RawExtension) (*thatchdv1alpha2.TestSuite, error) {
+	instance := &thatchdv1alpha2.TestSuite{}
+
+	if version == thatchdv1alpha1.GroupVersion.Version {
+		spoke := &thatchdv1alpha1.TestSuite{}
+		if err := v.decoder.DecodeRaw(raw, spoke); err != nil {
+			return nil, err
+		}
+
+		return instance, spoke.ConvertTo(instance)
+	}
+
+	return instance, v.decoder.DecodeRaw(raw, instance)
+}
+
+func (v *TestSuiteValidator) validate(instance *thatchdv1alpha2.TestSuite) error {
+	str := strategy.Strategy(instance.Spec.StateStrategy.Strategy)
+
+	reconciler, err := testsuite.FromStrategy(&str, v.StrategyProviders)
+	if err != nil {
+		return err
+	}
+
+	initialState := "{}"
+	if instance.Spec.InitialState != nil {
+		initialState = string(instance.Spec.InitialState.Raw)
+	}
+
+	if err := testsuite.ValidateState(reconciler, initialState); err != nil {
+		return fmt.Errorf("invalid initial state: %w", err)
+	}
+
+	return nil
+}
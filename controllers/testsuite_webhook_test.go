@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestTestSuiteValidator(t *testing.T) {
+	scenarios := []struct {
+		Name      string
+		Operation v1beta1.Operation
+		Version   string
+		Object    string
+		OldObject string
+		Allowed   bool
+	}{
+		{
+			Name:      "Valid initial state",
+			Operation: v1beta1.Create,
+			Version:   "v1alpha2",
+			Object:    `{"spec":{"initialState":{"componentA":{"ready":true}},"stateStrategy":{"provider":"testSuiteStrategyProvider"}}}`,
+			Allowed:   true,
+		},
+		{
+			Name:      "Invalid initial state",
+			Operation: v1beta1.Create,
+			Version:   "v1alpha2",
+			Object:    `{"spec":{"initialState":{"componentA":true},"stateStrategy":{"provider":"testSuiteStrategyProvider"}}}`,
+		},
+		{
+			Name:      "Invalid v1alpha1 initial state",
+			Operation: v1beta1.Create,
+			Version:   "v1alpha1",
+			Object:    `{"spec":{"initialContext":"{\"componentA\":true}","stateStrategy":{"provider":"testSuiteStrategyProvider"}}}`,
+		},
+		{
+			Name:      "Unknown strategy",
+			Operation: v1beta1.Create,
+			Version:   "v1alpha2",
+			Object:    `{"spec":{"initialState":{},"stateStrategy":{"provider":"unknown"}}}`,
+		},
+		{
+			Name:      "Update that doesn't change the initial state",
+			Operation: v1beta1.Update,
+			Version:   "v1alpha2",
+			Object:    `{"metadata":{"labels":{"a":"b"}},"spec":{"initialState":{"componentA":true},"stateStrategy":{"provider":"testSuiteStrategyProvider"}}}`,
+			OldObject: `{"spec":{"initialState":{"componentA":true},"stateStrategy":{"provider":"testSuiteStrategyProvider"}}}`,
+			Allowed:   true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			decoder, err := admission.NewDecoder(buildScheme(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			validator := &TestSuiteValidator{
+				StrategyProviders: map[string]strategy.StrategyProvider{
+					"testSuiteStrategyProvider": &testSuiteStrategyProvider{},
+				},
+			}
+			if err := validator.InjectDecoder(decoder); err != nil {
+				t.Fatal(err)
+			}
+
+			request := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{
+				Operation: scenario.Operation,
+				Kind: v1.GroupVersionKind{
+					Group:   "testing.thatchd.io",
+					Version: scenario.Version,
+					Kind:    "TestSuite",
+				},
+				Object:    runtime.RawExtension{Raw: []byte(scenario.Object)},
+				OldObject: runtime.RawExtension{Raw: []byte(scenario.OldObject)},
+			}}
+
+			response := validator.Handle(context.TODO(), request)
+			if response.Allowed != scenario.Allowed {
+				t.Errorf("expected allowed to be %v, got %v: %v", scenario.Allowed, response.Allowed, response.Result)
+			}
+			if !response.Allowed && response.Result.Code != http.StatusForbidden {
+				t.Errorf("expected request to be denied, got %v", response.Result)
+			}
+		})
+	}
+}
@@ -24,16 +24,27 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	testingv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/state"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
 	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
 )
 
+// maxConcurrentWorkers is the number of TestWorkers run at the same time, so
+// a worker that waits doesn't hold back the others
+const maxConcurrentWorkers = 4
+
 // TestWorkerReconciler reconciles a TestWorker object
 type TestWorkerReconciler struct {
 	client.Client
@@ -44,10 +55,22 @@ type TestWorkerReconciler struct {
 
 // +kubebuilder:rbac:groups=testing.thatchd.io,resources=testworkers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=testing.thatchd.io,resources=testworkers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=testing.thatchd.io,resources=testsuites/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// The kinds the built-in actions and chaos workers act on. Workers acting on
+// other kinds need an additional ClusterRole bound to the manager
+
+// +kubebuilder:rbac:groups="",resources=pods;services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments/scale;statefulsets/scale,verbs=get;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 
 func (r *TestWorkerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
-	_ = r.Log.WithValues("testworker", req.NamespacedName)
+	log := r.Log.WithValues("testworker", req.NamespacedName)
 
 	instance := &testingv1alpha1.TestWorker{}
 	err := r.Get(ctx, req.NamespacedName, instance)
@@ -63,112 +86,329 @@ func (r *TestWorkerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, err
 	}
 
+	// Revert the actions of deleted workers before they're removed
+	if instance.DeletionTimestamp != nil {
+		if !controllerutil.ContainsFinalizer(instance, revertActionsFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		log.Info("reverting actions")
+		return ctrl.Result{}, r.revertActions(ctx, instance)
+	}
+
 	// Test worker hasn't been dispatched
 	if instance.Status.DispatchedAt == nil {
 		return ctrl.Result{}, nil
 	}
 
-	// Test worker has already started
+	// Test worker has already finished
+	if instance.Status.FinishedAt != nil {
+		return ctrl.Result{}, nil
+	}
+
+	// Test worker has already started. If it recorded its patches but didn't
+	// finish, the manager stopped before applying them, so replay them
 	if instance.Status.StartedAt != nil {
-		return ctrl.Result{}, err
+		if instance.Status.FailureMessage != nil {
+			return ctrl.Result{}, nil
+		}
+
+		// The worker keeps its changes until they're due to be reverted
+		if instance.Status.RevertAt != nil {
+			return r.revertDue(ctx, instance, log)
+		}
+
+		// If it recorded actions but no patches, the manager stopped while it
+		// was running, so revert them and fail it
+		if len(instance.Status.StatePatches) == 0 {
+			if !pendingActions(instance) {
+				return ctrl.Result{}, nil
+			}
+
+			log.Info("reverting actions of interrupted worker")
+			if err := r.revertActions(ctx, instance); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			original := instance.DeepCopy()
+			failureMessage := "test worker was interrupted"
+			instance.Status.FailureMessage = &failureMessage
+			return ctrl.Result{}, patchStatus(ctx, r, instance, original)
+		}
+
+		log.Info("replaying state patches")
+		return ctrl.Result{}, r.finish(ctx, instance, instance.DeepCopy(), log)
 	}
 
-	// Set the StartedAt field
+	// Set the StartedAt field. The optimistic lock prevents the worker from
+	// being started twice
+	original := instance.DeepCopy()
 	instance.Status.StartedAt = testingv1alpha1.TimeString(time.Now())
-	if err := r.Status().Update(ctx, instance); err != nil {
+	if err := patchStatus(ctx, r, instance, original, client.MergeFromWithOptimisticLock{}); err != nil {
 		return ctrl.Result{}, err
 	}
+	original = instance.DeepCopy()
 
-	// Run the test. If it failed, set the failure message and finish
-	mutateState, err := r.runTest(ctx, instance)
+	// Run the test. If it failed, revert its actions, set the failure
+	// message and finish
+	statePatches, revertAt, err := r.runTest(ctx, instance, log)
 	if err != nil {
 		failureMessage := err.Error()
+		if revertErr := r.revertActions(ctx, instance); revertErr != nil {
+			failureMessage = fmt.Sprintf("%s; failed to revert actions: %v", failureMessage, revertErr)
+		}
+
+		original = instance.DeepCopy()
 		instance.Status.FailureMessage = &failureMessage
 
-		return ctrl.Result{}, r.Status().Update(ctx, instance)
+		// The worker is gone if it was canceled by deleting it
+		return ctrl.Result{}, client.IgnoreNotFound(patchStatus(ctx, r, instance, original))
 	}
 
-	// Update the test suite with the resulting mutating function
-	if err := r.updateSuiteState(ctx, instance, mutateState); err != nil {
-		return ctrl.Result{}, err
+	// The actions that weren't reverted are kept until the worker is deleted
+	if !pendingActions(instance) {
+		if err := r.removeFinalizer(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
+	original = instance.DeepCopy()
 
-	// Set the FinishedAt field
-	instance.Status.FinishedAt = testingv1alpha1.TimeString(time.Now())
-	if err := r.Status().Update(ctx, instance); err != nil {
+	// Record the patches before applying them, so they can be replayed,
+	// along with when the actions are reverted
+	instance.Status.StatePatches = statePatches
+	instance.Status.RevertAt = revertAt
+	if err := patchStatus(ctx, r, instance, original); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	if instance.Status.RevertAt != nil {
+		return r.revertDue(ctx, instance, log)
+	}
+
+	return ctrl.Result{}, r.finish(ctx, instance, instance.DeepCopy(), log)
+}
+
+// finish applies the recorded state patches to the test suite and sets the
+// FinishedAt field
+func (r *TestWorkerReconciler) finish(ctx context.Context, instance *testingv1alpha1.TestWorker, original *testingv1alpha1.TestWorker, log logr.Logger) error {
+	if err := r.updateSuiteState(ctx, instance, log); err != nil {
+		// Conflicts are retried on the next reconciliation
+		if errors.IsConflict(err) {
+			return err
+		}
+
+		failureMessage := err.Error()
+		instance.Status.FailureMessage = &failureMessage
+	}
+
+	instance.Status.FinishedAt = testingv1alpha1.TimeString(time.Now())
+	return patchStatus(ctx, r, instance, original)
 }
 
 func (r *TestWorkerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&testingv1alpha1.TestWorker{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentWorkers}).
 		Complete(r)
 }
 
-func (r *TestWorkerReconciler) runTest(ctx context.Context, instance *testingv1alpha1.TestWorker) (testworker.MutateStateFn, error) {
+// runTest runs the test worker and returns the patches to apply to the
+// state of the test suite, and when its actions are reverted, if it keeps
+// them for a while
+func (r *TestWorkerReconciler) runTest(ctx context.Context, instance *testingv1alpha1.TestWorker, log logr.Logger) ([]testingv1alpha1.StatePatch, *metav1.Time, error) {
 	str := strategy.Strategy(instance.Spec.Strategy.Strategy)
 
 	testWorkerInterface, err := testworker.FromStrategy(&str, r.StrategyProviders)
 	if err != nil {
-		return nil, fmt.Errorf("error obtaining strategy for test worker %s: %v", instance.Name, err)
+		return nil, nil, fmt.Errorf("error obtaining strategy for test worker %s: %v", instance.Name, err)
 	}
 
-	mutateState, err := testWorkerInterface.Run(ctx, instance.Namespace, r)
+	testSuite, err := r.getTestSuite(ctx, instance)
 	if err != nil {
-		testError := err.Error()
-		instance.Status.FailureMessage = &testError
+		return nil, nil, err
+	}
+	log = log.WithValues("testsuite", testSuite.Name)
+
+	var statePatch *testingv1alpha1.StatePatch
+	var revertAt *metav1.Time
+	if recordingInterface, ok := testWorkerInterface.(testworker.RecordingInterface); ok {
+		statePatch, revertAt, err = r.runRecorded(ctx, instance, recordingInterface, log)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if patchInterface, ok := testWorkerInterface.(testworker.PatchInterface); ok {
+		// Actions that wait stop once the TestWorker is deleted
+		runCtx, cancel := r.watchCancellation(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})
+		defer cancel()
+
+		statePatch, err = patchInterface.RunPatch(runCtx, instance.Namespace, r, log)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		mutateState, err := testWorkerInterface.Run(ctx, instance.Namespace, r, log)
+		if err != nil {
+			return nil, nil, err
+		}
 
-		return nil, r.Status().Update(ctx, instance)
+		statePatch, err = r.mutationPatch(ctx, testSuite, mutateState)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return mutateState, nil
+	statePatches := []testingv1alpha1.StatePatch{}
+	if statePatch != nil {
+		statePatches = append(statePatches, *statePatch)
+	}
+	if instance.Spec.StatePatch != nil {
+		statePatches = append(statePatches, *instance.Spec.StatePatch)
+	}
+
+	return statePatches, revertAt, nil
 }
 
-func (r *TestWorkerReconciler) updateSuiteState(ctx context.Context, instance *testingv1alpha1.TestWorker, mutateState testworker.MutateStateFn) error {
-	testSuite, err := r.getTestSuite(ctx, instance)
+// runRecorded runs a worker that records its actions in the status. The
+// finalizer makes sure they're reverted if the TestWorker is deleted, which
+// cancels the context of the worker. Returns when the actions are reverted
+// if the worker asked to keep them for a while
+func (r *TestWorkerReconciler) runRecorded(ctx context.Context, instance *testingv1alpha1.TestWorker, worker testworker.RecordingInterface, log logr.Logger) (*testingv1alpha1.StatePatch, *metav1.Time, error) {
+	if err := r.addFinalizer(ctx, instance); err != nil {
+		return nil, nil, err
+	}
+
+	runCtx, cancel := r.watchCancellation(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})
+	defer cancel()
+
+	recorder := &statusRecorder{client: r.Client, instance: instance}
+	statePatch, err := worker.RunRecorded(runCtx, instance.Namespace, r, recorder, log)
+	if err != nil || recorder.revertAfter <= 0 {
+		return statePatch, nil, err
+	}
+
+	revertAt := metav1.NewTime(time.Now().Add(recorder.revertAfter))
+	return statePatch, &revertAt, nil
+}
+
+// mutationPatch applies the mutation to the current state of the test suite
+// and returns the resulting change as a JSON Patch
+func (r *TestWorkerReconciler) mutationPatch(ctx context.Context, testSuite *thatchdv1alpha2.TestSuite, mutateState testworker.MutateStateFn) (*testingv1alpha1.StatePatch, error) {
+	if mutateState == nil {
+		return nil, nil
+	}
+
+	reconciler, err := r.getTestSuiteReconciler(testSuite)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	currentState, err := r.getCurrentState(ctx, instance, testSuite)
+	stateStore := &state.Store{Client: r.Client, Scheme: r.Scheme}
+	storedState, err := stateStore.Load(ctx, testSuite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load test suite state: %w", err)
+	}
 
-	updatedState, err := mutateState(currentState)
+	previousState, err := currentSuiteState(testSuite, reconciler, storedState)
 	if err != nil {
-		return fmt.Errorf("failed to mutate state: %w", err)
+		return nil, err
 	}
 
-	updateStateString, err := json.Marshal(updatedState)
+	currentState, err := reconciler.ParseState(previousState)
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated state: %w", err)
+		return nil, err
 	}
 
-	testSuite.Status.CurrentState = string(updateStateString)
+	updatedState, err := mutateState(currentState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mutate state: %w", err)
+	}
 
-	return r.Status().Update(ctx, testSuite)
+	updatedStateString, err := json.Marshal(updatedState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal updated state: %w", err)
+	}
+
+	return state.Diff(previousState, string(updatedStateString))
 }
 
-func (r *TestWorkerReconciler) getTestSuite(ctx context.Context, instance *testingv1alpha1.TestWorker) (*testingv1alpha1.TestSuite, error) {
-	testSuiteList := &testingv1alpha1.TestSuiteList{}
-	if err := r.List(ctx, testSuiteList, client.InNamespace(instance.Namespace)); err != nil {
-		return nil, err
+// updateSuiteState applies the recorded patches to the state of the test
+// suite, retrying on conflicts with concurrent writers
+func (r *TestWorkerReconciler) updateSuiteState(ctx context.Context, instance *testingv1alpha1.TestWorker, log logr.Logger) error {
+	if len(instance.Status.StatePatches) == 0 {
+		return nil
+	}
+
+	var testSuite *thatchdv1alpha2.TestSuite
+	var previousState, updatedState string
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		testSuite, err = r.getTestSuite(ctx, instance)
+		if err != nil {
+			return err
+		}
+
+		reconciler, err := r.getTestSuiteReconciler(testSuite)
+		if err != nil {
+			return err
+		}
+
+		original := testSuite.DeepCopy()
+		stateStore := &state.Store{Client: r.Client, Scheme: r.Scheme}
+
+		storedState, err := stateStore.Load(ctx, testSuite)
+		if err != nil {
+			return fmt.Errorf("failed to load test suite state: %w", err)
+		}
+
+		updatedState, err = currentSuiteState(testSuite, reconciler, storedState)
+		if err != nil {
+			return err
+		}
+		previousState = previousSuiteState(storedState, updatedState)
+
+		for _, statePatch := range instance.Status.StatePatches {
+			updatedState, err = state.ApplyPatch(updatedState, statePatch)
+			if err != nil {
+				return fmt.Errorf("failed to apply state patch: %w", err)
+			}
+		}
+
+		if err := testsuite.ValidateState(reconciler, updatedState); err != nil {
+			return fmt.Errorf("invalid state mutation: %w", err)
+		}
+
+		if err := stateStore.Save(ctx, testSuite, updatedState); err != nil {
+			return fmt.Errorf("failed to store updated state: %w", err)
+		}
+		testSuite.Status.StateVersion = testsuite.StateVersion(reconciler)
+
+		return patchStatus(ctx, r, testSuite, original, client.MergeFromWithOptimisticLock{})
+	}); err != nil {
+		return err
 	}
 
-	if len(testSuiteList.Items) == 0 {
-		return nil, fmt.Errorf("no test suite found in namespace %s", instance.Name)
+	// Only the transition that was persisted is recorded, once
+	history := &state.History{Client: r.Client, Scheme: r.Scheme}
+	if err := history.Record(ctx, testSuite, state.WorkerSource(instance.Name), previousState, updatedState); err != nil {
+		log.Error(err, "failed to record state transition")
 	}
 
-	return &testSuiteList.Items[0], nil
+	// The state ConfigMaps replaced by the update are only removed once
+	// nothing references them. The suite prunes them too, so the state
+	// update doesn't fail if they can't be removed yet
+	stateStore := &state.Store{Client: r.Client, Scheme: r.Scheme}
+	if err := stateStore.Prune(ctx, testSuite); err != nil {
+		log.Error(err, "failed to prune state")
+	}
+
+	return nil
 }
 
-func (r *TestWorkerReconciler) getCurrentState(ctx context.Context, instance *testingv1alpha1.TestWorker, testSuite *testingv1alpha1.TestSuite) (interface{}, error) {
-	testSuite, err := r.getTestSuite(ctx, instance)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve test suite: %w", err)
-	}
+func (r *TestWorkerReconciler) getTestSuite(ctx context.Context, instance *testingv1alpha1.TestWorker) (*thatchdv1alpha2.TestSuite, error) {
+	return getNamespaceTestSuite(ctx, r, instance.Namespace)
+}
 
+func (r *TestWorkerReconciler) getTestSuiteReconciler(testSuite *thatchdv1alpha2.TestSuite) (testsuite.Reconciler, error) {
 	testSuiteStr := strategy.Strategy(testSuite.Spec.StateStrategy.Strategy)
 
 	testSuiteInterface, err := testsuite.FromStrategy(&testSuiteStr, r.StrategyProviders)
@@ -176,5 +416,5 @@ func (r *TestWorkerReconciler) getCurrentState(ctx context.Context, instance *te
 		return nil, fmt.Errorf("error obtaining strategy for test suite: %w", err)
 	}
 
-	return testSuiteInterface.ParseState(testSuite.Status.CurrentState)
+	return testSuiteInterface, nil
 }
@@ -0,0 +1,475 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTestWorkerStatePatches(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Provider      string
+		StatePatch    *thatchdv1alpha1.StatePatch
+		RecordedState []thatchdv1alpha1.StatePatch
+		ExpectedState string
+		Failed        bool
+	}{
+		{
+			Name:          "Mutation is recorded as a patch",
+			Provider:      "mutate",
+			ExpectedState: `{"componentA":{"healthy":false,"ready":true},"componentB":{"healthy":false,"ready":false}}`,
+		},
+		{
+			Name:     "Patch from the worker and the spec",
+			Provider: "patch",
+			StatePatch: &thatchdv1alpha1.StatePatch{
+				Type:  thatchdv1alpha1.StateMergePatch,
+				Patch: `{"componentB":{"ready":true}}`,
+			},
+			ExpectedState: `{"componentA":{"healthy":true},"componentB":{"ready":true}}`,
+		},
+		{
+			Name:     "Recorded patches are replayed",
+			Provider: "patch",
+			RecordedState: []thatchdv1alpha1.StatePatch{
+				*testworker.MergePatch(`{"componentB":{"healthy":true}}`),
+			},
+			ExpectedState: `{"componentB":{"healthy":true}}`,
+		},
+		{
+			Name:     "Invalid patch fails the worker",
+			Provider: "patch",
+			StatePatch: &thatchdv1alpha1.StatePatch{
+				Type:  thatchdv1alpha1.StateJSONPatch,
+				Patch: `[{"op":"test","path":"/componentA/healthy","value":false}]`,
+			},
+			ExpectedState: `{}`,
+			Failed:        true,
+		},
+		{
+			Name:     "Invalid state mutation fails the worker",
+			Provider: "patch",
+			StatePatch: &thatchdv1alpha1.StatePatch{
+				Type:  thatchdv1alpha1.StateMergePatch,
+				Patch: `{"componentA":"ready"}`,
+			},
+			ExpectedState: `{}`,
+			Failed:        true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			ctx := context.TODO()
+			scheme := buildScheme(t)
+			c := fake.NewFakeClientWithScheme(scheme)
+
+			if err := c.Create(ctx, &thatchdv1alpha2.TestSuite{
+				ObjectMeta: v1.ObjectMeta{Name: "test-suite", Namespace: "thatchd"},
+				Spec: thatchdv1alpha2.TestSuiteSpec{
+					StateStrategy: thatchdv1alpha2.Strategy{
+						Strategy: strategy.Strategy{Provider: "testSuiteStrategyProvider"},
+					},
+				},
+				Status: thatchdv1alpha2.TestSuiteStatus{
+					CurrentState: &apiextensionsv1.JSON{Raw: []byte(`{}`)},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			worker := &thatchdv1alpha1.TestWorker{
+				ObjectMeta: v1.ObjectMeta{Name: "test-worker", Namespace: "thatchd"},
+				Spec: thatchdv1alpha1.TestWorkerSpec{
+					Strategy: thatchdv1alpha1.Strategy{
+						Strategy: strategy.Strategy{Provider: scenario.Provider},
+					},
+					StatePatch: scenario.StatePatch,
+				},
+				Status: thatchdv1alpha1.TestWorkerStatus{
+					DispatchedAt: addr("2020-01-01T00:00:00Z"),
+				},
+			}
+			if scenario.RecordedState != nil {
+				worker.Status.StartedAt = addr("2020-01-01T00:00:00Z")
+				worker.Status.StatePatches = scenario.RecordedState
+			}
+			if err := c.Create(ctx, worker); err != nil {
+				t.Fatal(err)
+			}
+
+			reconciler := &TestWorkerReconciler{
+				Client: c,
+				Scheme: scheme,
+				Log:    ctrl.Log.Logger,
+				StrategyProviders: map[string]strategy.StrategyProvider{
+					"testSuiteStrategyProvider": &testSuiteStrategyProvider{},
+					"mutate":                    &testWorkerStrategyProvider{patch: false},
+					"patch":                     &testWorkerStrategyProvider{patch: true},
+				},
+			}
+
+			if _, err := reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      "test-worker",
+				Namespace: "thatchd",
+			}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.Get(ctx, types.NamespacedName{Name: "test-worker", Namespace: "thatchd"}, worker); err != nil {
+				t.Fatal(err)
+			}
+			if worker.Status.FinishedAt == nil {
+				t.Errorf("expected worker to be finished")
+			}
+			if failed := worker.Status.FailureMessage != nil; failed != scenario.Failed {
+				t.Errorf("expected failed to be %v, got failure message %v", scenario.Failed, worker.Status.FailureMessage)
+			}
+			if len(worker.Status.StatePatches) == 0 {
+				t.Errorf("expected state patches to be recorded")
+			}
+
+			suite := &thatchdv1alpha2.TestSuite{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "test-suite", Namespace: "thatchd"}, suite); err != nil {
+				t.Fatal(err)
+			}
+			if currentState := string(suite.Status.CurrentState.Raw); currentState != scenario.ExpectedState {
+				t.Errorf("expected state %s, got %s", scenario.ExpectedState, currentState)
+			}
+		})
+	}
+}
+
+func TestTestWorkerActions(t *testing.T) {
+	chaosConfigMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "chaos", Namespace: "thatchd"},
+	}
+	recordedAction := testworker.NewAction("ConfigMapCreated", corev1.SchemeGroupVersion.WithKind("ConfigMap"), chaosConfigMap, &thatchdv1alpha1.ActionRevert{
+		Type: thatchdv1alpha1.RevertDelete,
+	})
+
+	scenarios := []struct {
+		Name      string
+		Provider  string
+		Worker    func(worker *thatchdv1alpha1.TestWorker)
+		Failure   string
+		Reverted  bool
+		Forgotten bool
+		Finalizer bool
+	}{
+		{
+			Name:      "Actions are kept after the worker finishes",
+			Provider:  "record",
+			Finalizer: true,
+		},
+		{
+			Name:     "Actions of a failed worker are reverted",
+			Provider: "recordAndFail",
+			Failure:  "failed after recording",
+			Reverted: true,
+		},
+		{
+			Name:     "Actions of a deleted worker are reverted",
+			Provider: "record",
+			Worker: func(worker *thatchdv1alpha1.TestWorker) {
+				now := v1.Now()
+				worker.DeletionTimestamp = &now
+				worker.Finalizers = []string{revertActionsFinalizer}
+				worker.Status.StartedAt = addr("2020-01-01T00:00:00Z")
+				worker.Status.FinishedAt = addr("2020-01-01T00:00:00Z")
+				worker.Status.Actions = []thatchdv1alpha1.WorkerAction{recordedAction}
+			},
+			Reverted: true,
+		},
+		{
+			Name:     "Actions of an interrupted worker are reverted",
+			Provider: "record",
+			Worker: func(worker *thatchdv1alpha1.TestWorker) {
+				worker.Finalizers = []string{revertActionsFinalizer}
+				worker.Status.StartedAt = addr("2020-01-01T00:00:00Z")
+				worker.Status.Actions = []thatchdv1alpha1.WorkerAction{recordedAction}
+			},
+			Failure:  "test worker was interrupted",
+			Reverted: true,
+		},
+		{
+			Name:     "Actions that failed are forgotten",
+			Provider: "record",
+			Worker: func(worker *thatchdv1alpha1.TestWorker) {
+				worker.Finalizers = []string{revertActionsFinalizer}
+			},
+			Failure:   `configmaps "chaos" already exists`,
+			Forgotten: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			ctx := context.TODO()
+			scheme := buildScheme(t)
+			c := fake.NewFakeClientWithScheme(scheme)
+
+			if err := c.Create(ctx, &thatchdv1alpha2.TestSuite{
+				ObjectMeta: v1.ObjectMeta{Name: "test-suite", Namespace: "thatchd"},
+				Spec: thatchdv1alpha2.TestSuiteSpec{
+					StateStrategy: thatchdv1alpha2.Strategy{
+						Strategy: strategy.Strategy{Provider: "testSuiteStrategyProvider"},
+					},
+				},
+				Status: thatchdv1alpha2.TestSuiteStatus{
+					CurrentState: &apiextensionsv1.JSON{Raw: []byte(`{}`)},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			worker := &thatchdv1alpha1.TestWorker{
+				ObjectMeta: v1.ObjectMeta{Name: "test-worker", Namespace: "thatchd"},
+				Spec: thatchdv1alpha1.TestWorkerSpec{
+					Strategy: thatchdv1alpha1.Strategy{
+						Strategy: strategy.Strategy{Provider: scenario.Provider},
+					},
+				},
+				Status: thatchdv1alpha1.TestWorkerStatus{
+					DispatchedAt: addr("2020-01-01T00:00:00Z"),
+				},
+			}
+			if scenario.Worker != nil {
+				scenario.Worker(worker)
+				if err := c.Create(ctx, chaosConfigMap.DeepCopy()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := c.Create(ctx, worker); err != nil {
+				t.Fatal(err)
+			}
+
+			reconciler := &TestWorkerReconciler{
+				Client: c,
+				Scheme: scheme,
+				Log:    ctrl.Log.Logger,
+				StrategyProviders: map[string]strategy.StrategyProvider{
+					"testSuiteStrategyProvider": &testSuiteStrategyProvider{},
+					"record":                    strategy.NewProviderFunction(func(map[string]string) interface{} { return &testWorkerRecordingMock{} }),
+					"recordAndFail":             strategy.NewProviderFunction(func(map[string]string) interface{} { return &testWorkerRecordingMock{fail: true} }),
+				},
+			}
+
+			if _, err := reconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      "test-worker",
+				Namespace: "thatchd",
+			}}); err != nil {
+				t.Fatal(err)
+			}
+
+			worker = &thatchdv1alpha1.TestWorker{}
+			if err := c.Get(ctx, types.NamespacedName{Name: "test-worker", Namespace: "thatchd"}, worker); err != nil {
+				t.Fatal(err)
+			}
+			if failure := worker.Status.FailureMessage; (failure == nil && scenario.Failure != "") || (failure != nil && *failure != scenario.Failure) {
+				t.Errorf("expected failure %q, got %v", scenario.Failure, failure)
+			}
+			if scenario.Forgotten {
+				if len(worker.Status.Actions) != 0 {
+					t.Errorf("expected no recorded actions, got %v", worker.Status.Actions)
+				}
+			} else if len(worker.Status.Actions) != 1 {
+				t.Fatalf("expected one recorded action, got %v", worker.Status.Actions)
+			} else if reverted := worker.Status.Actions[0].RevertedAt != nil; reverted != scenario.Reverted {
+				t.Errorf("expected reverted to be %v", scenario.Reverted)
+			}
+			if finalizer := controllerutil.ContainsFinalizer(worker, revertActionsFinalizer); finalizer != scenario.Finalizer {
+				t.Errorf("expected finalizer to be %v, got %v", scenario.Finalizer, worker.Finalizers)
+			}
+
+			err := c.Get(ctx, types.NamespacedName{Name: "chaos", Namespace: "thatchd"}, &corev1.ConfigMap{})
+			if exists := err == nil; exists == scenario.Reverted {
+				t.Errorf("expected config map to exist to be %v, got %v", !scenario.Reverted, err)
+			}
+		})
+	}
+}
+
+func TestTestWorkerRevertAfter(t *testing.T) {
+	ctx := context.TODO()
+	scheme := buildScheme(t)
+	c := fake.NewFakeClientWithScheme(scheme)
+	key := types.NamespacedName{Name: "test-worker", Namespace: "thatchd"}
+
+	for _, object := range []runtime.Object{
+		&thatchdv1alpha2.TestSuite{
+			ObjectMeta: v1.ObjectMeta{Name: "test-suite", Namespace: "thatchd"},
+			Spec: thatchdv1alpha2.TestSuiteSpec{
+				StateStrategy: thatchdv1alpha2.Strategy{
+					Strategy: strategy.Strategy{Provider: "testSuiteStrategyProvider"},
+				},
+			},
+			Status: thatchdv1alpha2.TestSuiteStatus{
+				CurrentState: &apiextensionsv1.JSON{Raw: []byte(`{}`)},
+			},
+		},
+		&thatchdv1alpha1.TestWorker{
+			ObjectMeta: v1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: thatchdv1alpha1.TestWorkerSpec{
+				Strategy: thatchdv1alpha1.Strategy{
+					Strategy: strategy.Strategy{Provider: "recordFor"},
+				},
+			},
+			Status: thatchdv1alpha1.TestWorkerStatus{
+				DispatchedAt: addr("2020-01-01T00:00:00Z"),
+			},
+		},
+	} {
+		if err := c.Create(ctx, object); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reconciler := &TestWorkerReconciler{
+		Client: c,
+		Scheme: scheme,
+		Log:    ctrl.Log.Logger,
+		StrategyProviders: map[string]strategy.StrategyProvider{
+			"testSuiteStrategyProvider": &testSuiteStrategyProvider{},
+			"recordFor":                 strategy.NewProviderFunction(func(map[string]string) interface{} { return &testWorkerRecordingMock{revertAfter: time.Hour} }),
+		},
+	}
+	getWorker := func() *thatchdv1alpha1.TestWorker {
+		worker := &thatchdv1alpha1.TestWorker{}
+		if err := c.Get(ctx, key, worker); err != nil {
+			t.Fatal(err)
+		}
+		return worker
+	}
+
+	// The worker is requeued until its actions are due to be reverted,
+	// instead of waiting
+	result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+		t.Errorf("expected the worker to be requeued within 1h, got %v", result.RequeueAfter)
+	}
+
+	worker := getWorker()
+	if worker.Status.RevertAt == nil || worker.Status.FinishedAt != nil || pendingActions(worker) != true {
+		t.Fatalf("expected the worker to keep its actions until they're due, got %v", worker.Status)
+	}
+
+	original := worker.DeepCopy()
+	revertAt := v1.NewTime(time.Now().Add(-time.Second))
+	worker.Status.RevertAt = &revertAt
+	if err := c.Status().Patch(ctx, worker, client.MergeFrom(original)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := reconciler.Reconcile(reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	worker = getWorker()
+	if worker.Status.FinishedAt == nil || worker.Status.FailureMessage != nil || pendingActions(worker) {
+		t.Errorf("expected the worker to revert its actions and finish, got %v", worker.Status)
+	}
+	if controllerutil.ContainsFinalizer(worker, revertActionsFinalizer) {
+		t.Errorf("expected the finalizer to be removed")
+	}
+}
+
+type testWorkerMock struct{}
+
+func (m *testWorkerMock) ShouldRun(_ interface{}, _ logr.Logger) bool {
+	return true
+}
+
+type testWorkerMutateMock struct {
+	testWorkerMock
+}
+
+var _ testworker.Interface = &testWorkerMutateMock{}
+
+func (m *testWorkerMutateMock) Run(_ context.Context, _ string, _ client.Client, _ logr.Logger) (testworker.MutateStateFn, error) {
+	return func(s interface{}) (interface{}, error) {
+		state := s.(testProgramState)
+		state.ComponentA.Ready = true
+		return state, nil
+	}, nil
+}
+
+type testWorkerPatchMock struct {
+	testWorkerMock
+}
+
+var _ testworker.PatchInterface = &testWorkerPatchMock{}
+
+func (m *testWorkerPatchMock) RunPatch(_ context.Context, _ string, _ client.Client, _ logr.Logger) (*thatchdv1alpha1.StatePatch, error) {
+	return testworker.JSONPatch(`[{"op":"add","path":"/componentA","value":{"healthy":true}}]`), nil
+}
+
+// testWorkerRecordingMock creates a ConfigMap that is deleted on revert
+type testWorkerRecordingMock struct {
+	testWorkerMock
+	fail        bool
+	revertAfter time.Duration
+}
+
+var _ testworker.RecordingInterface = &testWorkerRecordingMock{}
+
+func (m *testWorkerRecordingMock) RunRecorded(ctx context.Context, namespace string, c client.Client, recorder testworker.Recorder, _ logr.Logger) (*thatchdv1alpha1.StatePatch, error) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "chaos", Namespace: namespace},
+	}
+	if err := recorder.Record(ctx, testworker.NewAction("ConfigMapCreated", corev1.SchemeGroupVersion.WithKind("ConfigMap"), configMap, &thatchdv1alpha1.ActionRevert{
+		Type: thatchdv1alpha1.RevertDelete,
+	})); err != nil {
+		return nil, err
+	}
+	if err := c.Create(ctx, configMap); err != nil {
+		if forgetErr := recorder.Forget(ctx, testworker.NewAction("ConfigMapCreated", corev1.SchemeGroupVersion.WithKind("ConfigMap"), configMap, nil)); forgetErr != nil {
+			return nil, forgetErr
+		}
+		return nil, err
+	}
+
+	if m.fail {
+		return nil, fmt.Errorf("failed after recording")
+	}
+	if m.revertAfter > 0 {
+		recorder.RevertAfter(m.revertAfter)
+	}
+
+	return nil, nil
+}
+
+type testWorkerStrategyProvider struct {
+	patch bool
+}
+
+var _ strategy.StrategyProvider = &testWorkerStrategyProvider{}
+
+func (p *testWorkerStrategyProvider) New(_ map[string]string) interface{} {
+	if p.patch {
+		return &testWorkerPatchMock{}
+	}
+
+	return &testWorkerMutateMock{}
+}
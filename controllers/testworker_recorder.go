@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	testingv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// revertActionsFinalizer keeps a TestWorker that recorded actions until they
+// are reverted
+const revertActionsFinalizer = "testing.thatchd.io/revert-actions"
+
+// cancellationPollInterval is how often a running worker checks whether the
+// TestWorker was deleted
+var cancellationPollInterval = time.Second
+
+// statusRecorder records the actions of a worker in its status
+type statusRecorder struct {
+	client      client.Client
+	instance    *testingv1alpha1.TestWorker
+	revertAfter time.Duration
+}
+
+var _ testworker.Recorder = &statusRecorder{}
+
+func (r *statusRecorder) Record(ctx context.Context, action testingv1alpha1.WorkerAction) error {
+	original := r.instance.DeepCopy()
+	r.instance.Status.Actions = append(r.instance.Status.Actions, action)
+
+	return patchStatus(ctx, r.client, r.instance, original)
+}
+
+func (r *statusRecorder) Forget(ctx context.Context, action testingv1alpha1.WorkerAction) error {
+	original := r.instance.DeepCopy()
+
+	// The action is removed from the instance first, so it's not reverted
+	// even if the status can't be updated
+	actions := r.instance.Status.Actions
+	for i := len(actions) - 1; i >= 0; i-- {
+		if actions[i].Type == action.Type && actions[i].APIVersion == action.APIVersion && actions[i].Kind == action.Kind &&
+			actions[i].Namespace == action.Namespace && actions[i].Name == action.Name && actions[i].RevertedAt == nil {
+			r.instance.Status.Actions = append(actions[:i:i], actions[i+1:]...)
+			break
+		}
+	}
+
+	return patchStatus(ctx, r.client, r.instance, original)
+}
+
+func (r *statusRecorder) RevertAfter(duration time.Duration) {
+	r.revertAfter = duration
+}
+
+func (r *statusRecorder) Revert(ctx context.Context) error {
+	original := r.instance.DeepCopy()
+
+	var revertErr error
+	for i := range r.instance.Status.Actions {
+		if revertErr = testworker.RevertAction(ctx, r.client, &r.instance.Status.Actions[i]); revertErr != nil {
+			break
+		}
+	}
+
+	// Record the actions that were reverted, even if some failed
+	if err := patchStatus(ctx, r.client, r.instance, original); err != nil {
+		return err
+	}
+
+	return revertErr
+}
+
+// pendingActions returns whether the worker has actions that weren't
+// reverted
+func pendingActions(instance *testingv1alpha1.TestWorker) bool {
+	for _, action := range instance.Status.Actions {
+		if action.RevertedAt == nil && action.Revert != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// revertDue reverts the actions of a worker that kept its changes for a while
+// once they're due, and finishes it. The worker is requeued until then,
+// instead of blocking the reconciliation
+func (r *TestWorkerReconciler) revertDue(ctx context.Context, instance *testingv1alpha1.TestWorker, log logr.Logger) (ctrl.Result, error) {
+	if pendingActions(instance) {
+		if wait := time.Until(instance.Status.RevertAt.Time); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+
+		log.Info("reverting actions")
+		if err := r.revertActions(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, r.finish(ctx, instance, instance.DeepCopy(), log)
+}
+
+// revertActions reverts the pending actions of the worker and removes the
+// finalizer
+func (r *TestWorkerReconciler) revertActions(ctx context.Context, instance *testingv1alpha1.TestWorker) error {
+	recorder := &statusRecorder{client: r.Client, instance: instance}
+	if err := recorder.Revert(ctx); err != nil {
+		return err
+	}
+
+	return r.removeFinalizer(ctx, instance)
+}
+
+func (r *TestWorkerReconciler) addFinalizer(ctx context.Context, instance *testingv1alpha1.TestWorker) error {
+	if controllerutil.ContainsFinalizer(instance, revertActionsFinalizer) {
+		return nil
+	}
+
+	original := instance.DeepCopy()
+	controllerutil.AddFinalizer(instance, revertActionsFinalizer)
+	return r.Patch(ctx, instance, client.MergeFrom(original))
+}
+
+func (r *TestWorkerReconciler) removeFinalizer(ctx context.Context, instance *testingv1alpha1.TestWorker) error {
+	if !controllerutil.ContainsFinalizer(instance, revertActionsFinalizer) {
+		return nil
+	}
+
+	original := instance.DeepCopy()
+	controllerutil.RemoveFinalizer(instance, revertActionsFinalizer)
+	return r.Patch(ctx, instance, client.MergeFrom(original))
+}
+
+// watchCancellation returns a context that is canceled when the TestWorker
+// is deleted, polling it until the returned function is called
+func (r *TestWorkerReconciler) watchCancellation(ctx context.Context, key types.NamespacedName) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		ticker := time.NewTicker(cancellationPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			instance := &testingv1alpha1.TestWorker{}
+			err := r.Get(ctx, key, instance)
+			if errors.IsNotFound(err) || (err == nil && instance.DeletionTimestamp != nil) {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
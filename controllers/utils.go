@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getNamespaceTestSuite returns the TestSuite that drives the tests in the
+// namespace
+func getNamespaceTestSuite(ctx context.Context, c client.Reader, namespace string) (*thatchdv1alpha2.TestSuite, error) {
+	testSuiteList := &thatchdv1alpha2.TestSuiteList{}
+	if err := c.List(ctx, testSuiteList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	if len(testSuiteList.Items) == 0 {
+		return nil, fmt.Errorf("no test suite found in namespace %s", namespace)
+	}
+
+	return &testSuiteList.Items[0], nil
+}
+
+// patchStatus sends a merge patch with the changes made to the status of
+// instance since original was copied from it. No request is made when the
+// status didn't change. Pass client.MergeFromWithOptimisticLock{} to fail
+// with a conflict if the object was modified meanwhile
+func patchStatus(ctx context.Context, c client.Client, instance, original runtime.Object, opts ...client.MergeFromOption) error {
+	if equality.Semantic.DeepEqual(instance, original) {
+		return nil
+	}
+
+	return c.Status().Patch(ctx, instance, client.MergeFromWithOptions(original, opts...))
+}
+
+// currentSuiteState returns the state to reconcile from the stored one: the
+// initial state of new suites, or the stored state migrated to the version
+// of the reconciler
+func currentSuiteState(suite *thatchdv1alpha2.TestSuite, reconciler testsuite.Reconciler, storedState string) (string, error) {
+	if storedState == "" {
+		if suite.Spec.InitialState != nil {
+			return string(suite.Spec.InitialState.Raw), nil
+		}
+
+		return "{}", nil
+	}
+
+	return testsuite.MigrateState(reconciler, storedState, suite.Status.StateVersion)
+}
+
+// previousSuiteState returns the state to record a transition from, so the
+// history includes the migrations of the stored state
+func previousSuiteState(storedState, currentState string) string {
+	if storedState == "" {
+		return currentState
+	}
+
+	return storedState
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPatchStatus(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(buildScheme(t))
+	ctx := context.TODO()
+	key := types.NamespacedName{Name: "test-suite", Namespace: "thatchd"}
+
+	if err := c.Create(ctx, &thatchdv1alpha2.TestSuite{
+		ObjectMeta: v1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	instance := &thatchdv1alpha2.TestSuite{}
+	if err := c.Get(ctx, key, instance); err != nil {
+		t.Fatal(err)
+	}
+	resourceVersion := instance.ResourceVersion
+
+	// An unchanged status doesn't result in a request
+	if err := patchStatus(ctx, c, instance, instance.DeepCopy(), client.MergeFromWithOptimisticLock{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, key, instance); err != nil {
+		t.Fatal(err)
+	}
+	if instance.ResourceVersion != resourceVersion {
+		t.Errorf("expected the TestSuite not to be modified")
+	}
+
+	original := instance.DeepCopy()
+	instance.Status.CurrentState = &apiextensionsv1.JSON{Raw: []byte(`{"ready":true}`)}
+	if err := patchStatus(ctx, c, instance, original, client.MergeFromWithOptimisticLock{}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := &thatchdv1alpha2.TestSuite{}
+	if err := c.Get(ctx, key, updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.CurrentState == nil || string(updated.Status.CurrentState.Raw) != `{"ready":true}` {
+		t.Errorf("expected the status to be patched, got %v", updated.Status.CurrentState)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/go-logr/logr"
 	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -20,13 +21,15 @@ type PodAnnotationTestCase struct {
 
 var _ testcase.Interface = &PodAnnotationTestCase{}
 
-func (tc *PodAnnotationTestCase) ShouldRun(s interface{}) bool {
+func (tc *PodAnnotationTestCase) ShouldRun(s interface{}, _ logr.Logger) bool {
 	state := s.(PodSuiteState)
 	podState, ok := state[tc.PodName]
 	return ok && podState == PodAnnotated
 }
 
-func (tc *PodAnnotationTestCase) Run(c client.Client, namespace string) error {
+func (tc *PodAnnotationTestCase) Run(c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	logger.Info("verifying pod annotation", "pod", tc.PodName, "annotation", tc.ExpectedAnnotation)
+
 	pod := &v1.Pod{}
 	if err := c.Get(context.TODO(), client.ObjectKey{
 		Name:      tc.PodName,
@@ -40,10 +43,13 @@ func (tc *PodAnnotationTestCase) Run(c client.Client, namespace string) error {
 		return errors.New("Pod has no annotations")
 	}
 
-	if value, ok := annotations[tc.ExpectedAnnotation]; !ok || value != tc.ExpectedValue {
-		return fmt.Errorf("Annotation %s: %s not found in Pod", tc.ExpectedAnnotation, tc.ExpectedValue)
+	value, ok := annotations[tc.ExpectedAnnotation]
+	if !result.True("annotation present", ok, fmt.Sprintf("Annotation %s not found in Pod", tc.ExpectedAnnotation)) {
+		return nil
 	}
 
+	result.Equal("annotation value", tc.ExpectedValue, value)
+
 	return nil
 }
 
@@ -1,13 +1,16 @@
 package example
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
+	"reflect"
 
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/schema"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	"github.com/thatchd/thatchd/pkg/thatchd/testsuite/utils"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -27,6 +30,7 @@ type PodSuiteState map[string]PodStatus
 type PodsSuiteReconciler struct{}
 
 var _ testsuite.Reconciler = &PodsSuiteReconciler{}
+var _ testsuite.SchemaProvider = &PodsSuiteReconciler{}
 
 func (r *PodsSuiteReconciler) ParseState(state string) (interface{}, error) {
 	result := PodSuiteState{}
@@ -34,29 +38,46 @@ func (r *PodsSuiteReconciler) ParseState(state string) (interface{}, error) {
 	return result, err
 }
 
-func (r *PodsSuiteReconciler) Reconcile(c client.Client, namespace string, s interface{}) (interface{}, error) {
-	currentState := s.(PodSuiteState)
+// StateSchema derives the schema from the state type, restricting the
+// values to the known pod statuses
+func (r *PodsSuiteReconciler) StateSchema() *apiextensionsv1.JSONSchemaProps {
+	stateSchema := schema.FromType(PodSuiteState{})
+	for _, status := range []PodStatus{PodReady, PodNotReady, PodTested, PodAnnotated} {
+		value, _ := json.Marshal(status)
+		stateSchema.AdditionalProperties.Schema.Enum = append(stateSchema.AdditionalProperties.Schema.Enum, apiextensionsv1.JSON{Raw: value})
+	}
+
+	return stateSchema
+}
 
-	podList := &corev1.PodList{}
-	if err := c.List(context.TODO(), podList, client.InNamespace(namespace)); err != nil {
-		return nil, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
+func (r *PodsSuiteReconciler) Reconcile(c client.Client, namespace string, s interface{}, logger logr.Logger) (interface{}, error) {
+	// Pods that have been tested or annotated keep their status
+	podsReconciler, err := utils.NewMapReconciler(
+		reflect.TypeOf(PodSuiteState{}),
+		utils.DiscoverObjects(&corev1.PodList{}),
+		utils.Sticky(reconcilePodStatus, utils.StickyValues(PodTested, PodAnnotated)),
+		utils.WithRetainedEntries(),
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, pod := range podList.Items {
-		podState, ok := currentState[pod.Name]
-		if ok && (podState == PodTested || podState == PodAnnotated) {
-			continue
-		}
+	return podsReconciler.Reconcile(c, namespace, s, logger)
+}
+
+func reconcilePodStatus(podName string, object interface{}, current interface{}, logger logr.Logger) (interface{}, error) {
+	pod := object.(*corev1.Pod)
 
-		podState = PodNotReady
-		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodRunning {
-			podState = PodReady
-		}
+	podState := PodNotReady
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodRunning {
+		podState = PodReady
+	}
 
-		currentState[pod.Name] = podState
+	if current.(PodStatus) != podState {
+		logger.Info("pod status changed", "pod", podName, "status", podState)
 	}
 
-	return currentState, nil
+	return podState, nil
 }
 
 func NewPodsSuiteProvider() strategy.StrategyProvider {
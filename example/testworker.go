@@ -3,6 +3,7 @@ package example
 import (
 	"context"
 
+	"github.com/go-logr/logr"
 	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -17,14 +18,14 @@ type PodAnnotationTestWorker struct {
 
 var _ testworker.Interface = &PodAnnotationTestWorker{}
 
-func (tw *PodAnnotationTestWorker) ShouldRun(s interface{}) bool {
+func (tw *PodAnnotationTestWorker) ShouldRun(s interface{}, _ logr.Logger) bool {
 	state := s.(PodSuiteState)
 	status, ok := state[tw.PodName]
 
 	return ok && status == PodReady
 }
 
-func (tw *PodAnnotationTestWorker) Run(ctx context.Context, namespace string, client client.Client) (testworker.MutateStateFn, error) {
+func (tw *PodAnnotationTestWorker) Run(ctx context.Context, namespace string, client client.Client, logger logr.Logger) (testworker.MutateStateFn, error) {
 	pod := &v1.Pod{}
 	if err := client.Get(ctx, types.NamespacedName{
 		Name:      tw.PodName,
@@ -33,6 +34,7 @@ func (tw *PodAnnotationTestWorker) Run(ctx context.Context, namespace string, cl
 		return nil, err
 	}
 
+	logger.Info("annotating pod", "pod", tw.PodName, "annotation", tw.Annotation)
 	pod.Annotations[tw.Annotation] = tw.Value
 
 	err := client.Update(ctx, pod)
@@ -17,98 +17,21 @@ limitations under the License.
 package main
 
 import (
-	"flag"
-	"os"
-
 	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
-	testingv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
-	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
-	"github.com/thatchd/thatchd/controllers"
 	"github.com/thatchd/thatchd/example"
+	"github.com/thatchd/thatchd/pkg/thatchd/manager"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
-	// +kubebuilder:scaffold:imports
-)
-
-var (
-	scheme   = runtime.NewScheme()
-	setupLog = ctrl.Log.WithName("setup")
 )
 
-func init() {
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-
-	utilruntime.Must(thatchdv1alpha1.AddToScheme(scheme))
-	utilruntime.Must(testingv1alpha1.AddToScheme(scheme))
-	// +kubebuilder:scaffold:scheme
-}
-
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
-	flag.Parse()
+	// The example strategies only use the types registered by the manager
+	addToScheme := func(*runtime.Scheme) error { return nil }
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
-
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "0af988fb.thatchd.io",
-	})
-	if err != nil {
-		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
-	}
-
-	strategyProviders := map[string]strategy.StrategyProvider{
+	manager.Run(addToScheme, map[string]strategy.StrategyProvider{
 		"PodsSuite":           example.NewPodsSuiteProvider(),
 		"PodAnnotation":       strategy.NewProviderFunction(example.NewTestCase),
 		"PodAnnotationWorker": strategy.NewProviderFunction(example.NewTestWorker),
-	}
-
-	if err = (&controllers.TestSuiteReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("TestSuite"),
-		Scheme:            mgr.GetScheme(),
-		StrategyProviders: strategyProviders,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "TestSuite")
-		os.Exit(1)
-	}
-	if err = (&controllers.TestCaseReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("TestCase"),
-		Scheme:            mgr.GetScheme(),
-		StrategyProviders: strategyProviders,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "TestCase")
-		os.Exit(1)
-	}
-	if err = (&controllers.TestWorkerReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("TestWorker"),
-		Scheme:            mgr.GetScheme(),
-		StrategyProviders: strategyProviders,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "TestWorker")
-		os.Exit(1)
-	}
-	// +kubebuilder:scaffold:builder
-
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
-	}
+	})
 }
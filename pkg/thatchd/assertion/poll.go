@@ -0,0 +1,132 @@
+// Package assertion provides helpers for TestCases written in Go that check
+// a condition repeatedly instead of once, so they don't race with the state
+// of the cluster
+//
+//	err := assertion.Eventually(2*time.Minute, 5*time.Second, func() error {
+//		return checkReplicas(c, namespace)
+//	})
+//
+// Checks that skip the test case, with testcase.Skip, stop the polling
+package assertion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+)
+
+// Eventually calls check every interval until it succeeds. Returns the last
+// error of check if it didn't once the duration elapses
+func Eventually(duration, interval time.Duration, check func() error) error {
+	return (&Poller{Duration: duration, Interval: interval}).Eventually(check)
+}
+
+// Consistently calls check every interval for the duration. Returns the
+// first error of check
+func Consistently(duration, interval time.Duration, check func() error) error {
+	return (&Poller{Duration: duration, Interval: interval}).Consistently(check)
+}
+
+// Poller calls a check repeatedly and keeps track of the attempts. It's safe
+// to read the attempts from another goroutine while polling
+type Poller struct {
+	Duration time.Duration
+	Interval time.Duration
+	// Context stops the polling when it's done. Defaults to
+	// context.Background
+	Context context.Context
+
+	mu          sync.Mutex
+	attempts    int
+	lastFailure error
+}
+
+// Attempts returns the number of times the check was called
+func (p *Poller) Attempts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.attempts
+}
+
+// LastFailure returns the error of the last failed call to the check, even
+// if a later call succeeded
+func (p *Poller) LastFailure() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.lastFailure
+}
+
+// Eventually calls check every interval until it succeeds. Returns the last
+// error of check if it didn't once the duration elapses
+func (p *Poller) Eventually(check func() error) error {
+	return p.poll(check, func(err error, attempt int, elapsed time.Duration, last bool) (bool, error) {
+		switch {
+		case err == nil:
+			return true, nil
+		case last:
+			return true, fmt.Errorf("still failing after %d attempt(s) in %v: %w", attempt, elapsed.Round(time.Millisecond), err)
+		}
+
+		return false, nil
+	})
+}
+
+// Consistently calls check every interval for the duration. Returns the
+// first error of check
+func (p *Poller) Consistently(check func() error) error {
+	return p.poll(check, func(err error, attempt int, elapsed time.Duration, last bool) (bool, error) {
+		if err != nil {
+			return true, fmt.Errorf("failed on attempt %d after %v: %w", attempt, elapsed.Round(time.Millisecond), err)
+		}
+
+		return last, nil
+	})
+}
+
+// stopFunc decides whether to stop polling after an attempt, and with which
+// error. last is true if the duration would elapse before the next attempt
+type stopFunc func(err error, attempt int, elapsed time.Duration, last bool) (bool, error)
+
+func (p *Poller) poll(check func() error, stop stopFunc) error {
+	ctx := p.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	for {
+		err := check()
+
+		p.mu.Lock()
+		p.attempts++
+		attempt := p.attempts
+		if err != nil {
+			p.lastFailure = err
+		}
+		p.mu.Unlock()
+
+		if errors.Is(err, testcase.ErrSkip) {
+			return err
+		}
+
+		elapsed := time.Since(start)
+		if done, err := stop(err, attempt, elapsed, elapsed+p.Interval > p.Duration); done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("polling stopped after %d attempt(s): %w", attempt, err)
+			}
+			return fmt.Errorf("polling stopped after %d attempt(s): %w", attempt, ctx.Err())
+		case <-time.After(p.Interval):
+		}
+	}
+}
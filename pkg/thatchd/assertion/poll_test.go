@@ -0,0 +1,146 @@
+package assertion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+)
+
+// failUntil returns a check that fails until the given attempt
+func failUntil(attempt int) func() error {
+	calls := 0
+	return func() error {
+		calls++
+		if calls < attempt {
+			return fmt.Errorf("attempt %d failed", calls)
+		}
+		return nil
+	}
+}
+
+// failFrom returns a check that fails from the given attempt
+func failFrom(attempt int) func() error {
+	calls := 0
+	return func() error {
+		calls++
+		if calls >= attempt {
+			return fmt.Errorf("attempt %d failed", calls)
+		}
+		return nil
+	}
+}
+
+func TestPoller(t *testing.T) {
+	scenarios := []struct {
+		Name        string
+		Poll        func(p *Poller, check func() error) error
+		Check       func() error
+		Attempts    int
+		LastFailure string
+		Error       string
+	}{
+		{
+			Name:        "Eventually passes",
+			Poll:        (*Poller).Eventually,
+			Check:       failUntil(3),
+			Attempts:    3,
+			LastFailure: "attempt 2 failed",
+		},
+		{
+			Name:        "Eventually fails",
+			Poll:        (*Poller).Eventually,
+			Check:       failUntil(100),
+			Attempts:    5,
+			LastFailure: "attempt 5 failed",
+			Error:       "still failing after 5 attempt(s)",
+		},
+		{
+			Name:     "Consistently passes",
+			Poll:     (*Poller).Consistently,
+			Check:    failFrom(100),
+			Attempts: 5,
+		},
+		{
+			Name:        "Consistently fails",
+			Poll:        (*Poller).Consistently,
+			Check:       failFrom(2),
+			Attempts:    2,
+			LastFailure: "attempt 2 failed",
+			Error:       "failed on attempt 2",
+		},
+		{
+			Name: "Skip stops polling",
+			Poll: (*Poller).Eventually,
+			Check: func() error {
+				return testcase.Skip("not ready")
+			},
+			Attempts:    1,
+			LastFailure: "test case skipped: not ready",
+			Error:       "test case skipped: not ready",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			// Attempts at 0, 20, 40, 60 and 80ms fit in the duration, with some
+			// leeway for the scheduling delays
+			poller := &Poller{Duration: 100 * time.Millisecond, Interval: 20 * time.Millisecond}
+			err := scenario.Poll(poller, scenario.Check)
+
+			if scenario.Error == "" && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if scenario.Error != "" && (err == nil || !strings.Contains(err.Error(), scenario.Error)) {
+				t.Errorf("expected error %s, got %v", scenario.Error, err)
+			}
+
+			if poller.Attempts() != scenario.Attempts {
+				t.Errorf("expected %d attempts, got %d", scenario.Attempts, poller.Attempts())
+			}
+
+			lastFailure := ""
+			if poller.LastFailure() != nil {
+				lastFailure = poller.LastFailure().Error()
+			}
+			if lastFailure != scenario.LastFailure {
+				t.Errorf("expected last failure %q, got %q", scenario.LastFailure, lastFailure)
+			}
+		})
+	}
+}
+
+func TestPollerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	poller := &Poller{Duration: 2 * time.Hour, Interval: time.Hour, Context: ctx}
+
+	done := make(chan error)
+	go func() {
+		done <- poller.Eventually(func() error {
+			return errors.New("not ready")
+		})
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "polling stopped after 1 attempt(s): not ready" {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected polling to stop")
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	if err := Eventually(time.Second, time.Millisecond, failUntil(2)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := Consistently(5*time.Millisecond, time.Millisecond, failFrom(100)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package config parses the values of the configuration of the built-in
+// strategies
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration returns the duration set for the key, or the default value if
+// it's not set
+func Duration(configuration map[string]string, key string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := configuration[key]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return parsed, nil
+}
+
+// Bool returns the boolean set for the key, or false if it's not set
+func Bool(configuration map[string]string, key string) (bool, error) {
+	value, ok := configuration[key]
+	if !ok {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return parsed, nil
+}
+
+// Int returns the integer set for the key, or the default value if it's not
+// set
+func Int(configuration map[string]string, key string, defaultValue int) (int, error) {
+	value, ok := configuration[key]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return parsed, nil
+}
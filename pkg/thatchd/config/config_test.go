@@ -0,0 +1,135 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Configuration map[string]string
+		Expected      time.Duration
+		ExpectError   bool
+	}{
+		{
+			Name:          "Value set",
+			Configuration: map[string]string{"timeout": "30s"},
+			Expected:      30 * time.Second,
+		},
+		{
+			Name:          "Value not set",
+			Configuration: map[string]string{},
+			Expected:      time.Minute,
+		},
+		{
+			Name:          "Invalid value",
+			Configuration: map[string]string{"timeout": "soon"},
+			ExpectError:   true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			value, err := Duration(scenario.Configuration, "timeout", time.Minute)
+			if scenario.ExpectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value != scenario.Expected {
+				t.Errorf("expected %v, got %v", scenario.Expected, value)
+			}
+		})
+	}
+}
+
+func TestBool(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Configuration map[string]string
+		Expected      bool
+		ExpectError   bool
+	}{
+		{
+			Name:          "Value set",
+			Configuration: map[string]string{"previous": "true"},
+			Expected:      true,
+		},
+		{
+			Name:          "Value not set",
+			Configuration: map[string]string{},
+			Expected:      false,
+		},
+		{
+			Name:          "Invalid value",
+			Configuration: map[string]string{"previous": "maybe"},
+			ExpectError:   true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			value, err := Bool(scenario.Configuration, "previous")
+			if scenario.ExpectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value != scenario.Expected {
+				t.Errorf("expected %v, got %v", scenario.Expected, value)
+			}
+		})
+	}
+}
+
+func TestInt(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Configuration map[string]string
+		Expected      int
+		ExpectError   bool
+	}{
+		{
+			Name:          "Value set",
+			Configuration: map[string]string{"count": "3"},
+			Expected:      3,
+		},
+		{
+			Name:          "Value not set",
+			Configuration: map[string]string{},
+			Expected:      1,
+		},
+		{
+			Name:          "Invalid value",
+			Configuration: map[string]string{"count": "three"},
+			ExpectError:   true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			value, err := Int(scenario.Configuration, "count", 1)
+			if scenario.ExpectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value != scenario.Expected {
+				t.Errorf("expected %v, got %v", scenario.Expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/storage"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultResources are the kinds of resources collected when the diagnostics
+// spec doesn't list any
+var DefaultResources = []thatchdv1alpha1.ResourceKind{
+	{APIVersion: "v1", Kind: "Pod"},
+	{APIVersion: "v1", Kind: "Service"},
+	{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+	{APIVersion: "apps/v1", Kind: "Deployment"},
+	{APIVersion: "apps/v1", Kind: "StatefulSet"},
+	{APIVersion: "apps/v1", Kind: "DaemonSet"},
+}
+
+// Default size limits of the bundle, so it fits in a ConfigMap or a Secret,
+// limited to 1MiB
+const (
+	DefaultMaxFileSize   = 128 * 1024
+	DefaultMaxBundleSize = 768 * 1024
+)
+
+// Collector gathers a diagnostics bundle of the namespace of a test case. The
+// Clientset is used to retrieve container logs, that aren't available through
+// the controller-runtime client
+type Collector struct {
+	Client    client.Client
+	Clientset kubernetes.Interface
+	// MaxFileSize is the size each file of the bundle is truncated to.
+	// Defaults to DefaultMaxFileSize
+	MaxFileSize int
+	// MaxBundleSize is the total size of the files of the bundle. The files
+	// that don't fit are left out. Defaults to DefaultMaxBundleSize
+	MaxBundleSize int
+}
+
+// Collect builds the bundle as a set of files: one YAML file per kind of
+// resource, the namespace events and the logs of each container. Secrets are
+// only collected when the bundle is stored in a Secret. Failing to
+// collect a part of the bundle doesn't prevent the rest from being collected,
+// the errors are written in the errors.txt file instead. With a selector,
+// only the events of the selected resources and pods are collected
+func (c *Collector) Collect(ctx context.Context, namespace string, spec *thatchdv1alpha1.DiagnosticsSpec) (map[string][]byte, error) {
+	selector := labels.Everything()
+	if spec.Selector != nil {
+		var err error
+		selector, err = v1.LabelSelectorAsSelector(spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+
+	files := map[string][]byte{}
+	errs := []string{}
+
+	resources := spec.Resources
+	if len(resources) == 0 {
+		resources = DefaultResources
+	}
+
+	// The selected objects, by kind and name, to filter the events
+	selected := map[string]bool{}
+
+	for _, resource := range resources {
+		if isSecret(resource) && spec.Storage != thatchdv1alpha1.StorageSecret {
+			errs = append(errs, fmt.Sprintf("%s: only collected when the bundle is stored in a Secret", resource.Kind))
+			continue
+		}
+
+		content, err := c.collectResources(ctx, namespace, selector, resource, selected)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", resource.Kind, err))
+			continue
+		}
+
+		files[resourceFileName(resource)] = content
+	}
+
+	var pods *corev1.PodList
+	if spec.LogTailLines != nil || (spec.Events && spec.Selector != nil) {
+		pods = &corev1.PodList{}
+		if err := c.Client.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			errs = append(errs, fmt.Sprintf("pods: %v", err))
+			pods = nil
+		}
+	}
+	if pods != nil {
+		for _, pod := range pods.Items {
+			selected[objectKey("Pod", pod.Name)] = true
+		}
+	}
+
+	if spec.Events {
+		var involved map[string]bool
+		if spec.Selector != nil {
+			involved = selected
+		}
+
+		content, err := c.collectEvents(ctx, namespace, involved)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("events: %v", err))
+		} else {
+			files["events.txt"] = content
+		}
+	}
+
+	if spec.LogTailLines != nil && pods != nil {
+		logErrs := c.collectLogs(ctx, namespace, pods, *spec.LogTailLines, files)
+		errs = append(errs, logErrs...)
+	}
+
+	errs = append(errs, c.limit(files)...)
+	if len(errs) > 0 {
+		files["errors.txt"] = storage.Truncate("errors.txt", []byte(strings.Join(errs, "\n")), c.maxFileSize())
+	}
+
+	return files, nil
+}
+
+// resourceFileName names the file of a kind of resource after the kind and
+// its group, so kinds with the same name in different groups don't collide
+func resourceFileName(resource thatchdv1alpha1.ResourceKind) string {
+	name := strings.ToLower(resource.Kind)
+	if gv, err := schema.ParseGroupVersion(resource.APIVersion); err == nil && gv.Group != "" {
+		name = fmt.Sprintf("%s.%s", name, gv.Group)
+	}
+
+	return fmt.Sprintf("%s.yaml", name)
+}
+
+// isSecret is true for the core Secrets, whose data mustn't be copied to a
+// less protected storage
+func isSecret(resource thatchdv1alpha1.ResourceKind) bool {
+	return resource.APIVersion == "v1" && resource.Kind == "Secret"
+}
+
+func objectKey(kind, name string) string {
+	return fmt.Sprintf("%s/%s", kind, name)
+}
+
+func (c *Collector) collectResources(ctx context.Context, namespace string, selector labels.Selector, resource thatchdv1alpha1.ResourceKind, selected map[string]bool) ([]byte, error) {
+	gv, err := schema.ParseGroupVersion(resource.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gv.WithKind(resource.Kind + "List"))
+	if err := c.Client.List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, item := range list.Items {
+		content, err := yaml.Marshal(item.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.WriteString("---\n")
+		buffer.Write(content)
+		selected[objectKey(resource.Kind, item.GetName())] = true
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// collectEvents lists the events of the namespace. If involved isn't nil,
+// only the events of the objects in it are collected
+func (c *Collector) collectEvents(ctx context.Context, namespace string, involved map[string]bool) ([]byte, error) {
+	list := &corev1.EventList{}
+	if err := c.Client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	events := &corev1.EventList{}
+	for _, event := range list.Items {
+		if involved == nil || involved[objectKey(event.InvolvedObject.Kind, event.InvolvedObject.Name)] {
+			events.Items = append(events.Items, event)
+		}
+	}
+
+	sort.Slice(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+
+	buffer := &bytes.Buffer{}
+	for _, event := range events.Items {
+		fmt.Fprintf(buffer, "%s\t%s\t%s\t%s/%s\t%s\n",
+			event.LastTimestamp.UTC().Format(thatchdv1alpha1.DateTimeFormat),
+			event.Type,
+			event.Reason,
+			event.InvolvedObject.Kind,
+			event.InvolvedObject.Name,
+			event.Message,
+		)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// collectLogs adds the logs of every container of the selected pods to the
+// files. The logs of the previous instance of the container are also
+// collected when it has restarted
+func (c *Collector) collectLogs(ctx context.Context, namespace string, pods *corev1.PodList, tailLines int64, files map[string][]byte) []string {
+	errs := []string{}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			previous := []bool{false}
+			if status.RestartCount > 0 {
+				previous = append(previous, true)
+			}
+
+			for _, p := range previous {
+				content, err := c.Clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+					Container: status.Name,
+					TailLines: &tailLines,
+					Previous:  p,
+				}).DoRaw(ctx)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("logs %s/%s: %v", pod.Name, status.Name, err))
+					continue
+				}
+
+				name := fmt.Sprintf("%s.%s.log", pod.Name, status.Name)
+				if p {
+					name = fmt.Sprintf("%s.%s.previous.log", pod.Name, status.Name)
+				}
+				files[name] = content
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c *Collector) maxFileSize() int {
+	if c.MaxFileSize > 0 {
+		return c.MaxFileSize
+	}
+	return DefaultMaxFileSize
+}
+
+func (c *Collector) maxBundleSize() int {
+	if c.MaxBundleSize > 0 {
+		return c.MaxBundleSize
+	}
+	return DefaultMaxBundleSize
+}
+
+// limit truncates the files to the maximum file size, and leaves out the
+// ones that don't fit in the bundle, the logs first. Returns the files that
+// were left out as errors. Room is kept for the errors file
+func (c *Collector) limit(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name, content := range files {
+		files[name] = storage.Truncate(name, content, c.maxFileSize())
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		iLog, jLog := strings.HasSuffix(names[i], ".log"), strings.HasSuffix(names[j], ".log")
+		if iLog != jLog {
+			return jLog
+		}
+		return names[i] < names[j]
+	})
+
+	errs := []string{}
+	size := 0
+	for _, name := range names {
+		if size+len(files[name]) > c.maxBundleSize()-c.maxFileSize() {
+			errs = append(errs, fmt.Sprintf("%s: left out, the bundle exceeds %d bytes", name, c.maxBundleSize()))
+			delete(files, name)
+			continue
+		}
+		size += len(files[name])
+	}
+
+	return errs
+}
@@ -0,0 +1,202 @@
+package diagnostics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCollect(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := []runtime.Object{
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "subject",
+				Namespace: "thatchd",
+				Labels:    map[string]string{"app": "subject"},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "other",
+				Namespace: "thatchd",
+				Labels:    map[string]string{"app": "other"},
+			},
+		},
+		&corev1.Event{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "subject.1",
+				Namespace: "thatchd",
+			},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "subject"},
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+		},
+		&corev1.Event{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "other.1",
+				Namespace: "thatchd",
+			},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "other"},
+			Reason:         "Pulled",
+			Message:        "Container image already present",
+		},
+	}
+
+	collector := &Collector{
+		Client: fake.NewFakeClientWithScheme(scheme, objects...),
+	}
+
+	files, err := collector.Collect(context.TODO(), "thatchd", &thatchdv1alpha1.DiagnosticsSpec{
+		Selector: &v1.LabelSelector{
+			MatchLabels: map[string]string{"app": "subject"},
+		},
+		Resources: []thatchdv1alpha1.ResourceKind{
+			{APIVersion: "v1", Kind: "Pod"},
+		},
+		Events: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if errs, ok := files["errors.txt"]; ok {
+		t.Errorf("unexpected errors collecting diagnostics: %s", errs)
+	}
+
+	pods := string(files["pod.yaml"])
+	if !strings.Contains(pods, "name: subject") || strings.Contains(pods, "name: other") {
+		t.Errorf("expected only the selected pod to be collected, got:\n%s", pods)
+	}
+
+	events := string(files["events.txt"])
+	if !strings.Contains(events, "Back-off restarting failed container") || strings.Contains(events, "Container image already present") {
+		t.Errorf("expected only the events of the selected pod to be collected, got:\n%s", events)
+	}
+}
+
+func TestCollectLimits(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := []runtime.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: "large", Namespace: "thatchd"},
+			Data:       map[string]string{"config": strings.Repeat("x", 1000)},
+		},
+		&corev1.Service{
+			ObjectMeta: v1.ObjectMeta{Name: "service", Namespace: "thatchd"},
+		},
+	}
+
+	collector := &Collector{
+		Client:        fake.NewFakeClientWithScheme(scheme, objects...),
+		MaxFileSize:   400,
+		MaxBundleSize: 900,
+	}
+
+	files, err := collector.Collect(context.TODO(), "thatchd", &thatchdv1alpha1.DiagnosticsSpec{
+		Resources: []thatchdv1alpha1.ResourceKind{
+			{APIVersion: "v1", Kind: "ConfigMap"},
+			{APIVersion: "v1", Kind: "Secret"},
+			{APIVersion: "v1", Kind: "Service"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for name, content := range files {
+		if len(content) > 400 {
+			t.Errorf("expected %s to be truncated to 400 bytes, got %d", name, len(content))
+		}
+		total += len(content)
+	}
+	if total > 900 {
+		t.Errorf("expected the bundle to be at most 900 bytes, got %d", total)
+	}
+
+	if !strings.Contains(string(files["configmap.yaml"]), "... truncated") {
+		t.Errorf("expected the ConfigMaps to be truncated, got:\n%s", files["configmap.yaml"])
+	}
+	if _, ok := files["service.yaml"]; ok {
+		t.Errorf("expected the Services to be left out of the bundle")
+	}
+	if !strings.Contains(string(files["errors.txt"]), "service.yaml: left out") {
+		t.Errorf("expected the left out file to be reported, got:\n%s", files["errors.txt"])
+	}
+}
+
+func TestResourceFileName(t *testing.T) {
+	for resource, expected := range map[thatchdv1alpha1.ResourceKind]string{
+		{APIVersion: "v1", Kind: "Event"}:                    "event.yaml",
+		{APIVersion: "events.k8s.io/v1beta1", Kind: "Event"}: "event.events.k8s.io.yaml",
+		{APIVersion: "apps/v1", Kind: "Deployment"}:          "deployment.apps.yaml",
+	} {
+		if name := resourceFileName(resource); name != expected {
+			t.Errorf("expected %s to be collected in %s, got %s", resource, expected, name)
+		}
+	}
+}
+
+func TestCollectSecrets(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Name: "credentials", Namespace: "thatchd"},
+			StringData: map[string]string{"password": "hunter2"},
+		},
+	}
+
+	for _, scenario := range []struct {
+		name      string
+		storage   thatchdv1alpha1.StorageType
+		collected bool
+	}{
+		{name: "Default storage", storage: "", collected: false},
+		{name: "ConfigMap storage", storage: thatchdv1alpha1.StorageConfigMap, collected: false},
+		{name: "Directory storage", storage: thatchdv1alpha1.StorageDirectory, collected: false},
+		{name: "Secret storage", storage: thatchdv1alpha1.StorageSecret, collected: true},
+	} {
+		t.Run(scenario.name, func(t *testing.T) {
+			collector := &Collector{
+				Client: fake.NewFakeClientWithScheme(scheme, objects...),
+			}
+
+			files, err := collector.Collect(context.TODO(), "thatchd", &thatchdv1alpha1.DiagnosticsSpec{
+				Resources: []thatchdv1alpha1.ResourceKind{
+					{APIVersion: "v1", Kind: "Secret"},
+				},
+				Storage: scenario.storage,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, collected := files["secret.yaml"]
+			if collected != scenario.collected {
+				t.Errorf("expected the Secrets to be collected: %v, got %v", scenario.collected, collected)
+			}
+			if !collected && !strings.Contains(string(files["errors.txt"]), "Secret: only collected when the bundle is stored in a Secret") {
+				t.Errorf("expected the Secrets left out to be reported, got:\n%s", files["errors.txt"])
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package dispatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Configuration keys of the dispatch Condition
+const (
+	// WhenKey is the JSONPath expression evaluated against the suite state
+	WhenKey = "dispatchWhen"
+	// EqualsKey is the value the expression must result in. If not set, the
+	// expression must result in a value other than empty or "false"
+	EqualsKey = "dispatchEquals"
+)
+
+// Condition dispatches the built-in strategies, which are configured through
+// the strategy configuration, when a value of the suite state is set. It's
+// always dispatched if the configuration doesn't set an expression
+type Condition struct {
+	path   *jsonpath.JSONPath
+	equals *string
+}
+
+var _ Dispatchable = &Condition{}
+
+// NewCondition creates the Condition of a strategy configuration
+func NewCondition(configuration map[string]string) (*Condition, error) {
+	condition := &Condition{}
+
+	if when, ok := configuration[WhenKey]; ok {
+		condition.path = jsonpath.New(WhenKey).AllowMissingKeys(true)
+		if err := condition.path.Parse(when); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", WhenKey, err)
+		}
+	}
+	if equals, ok := configuration[EqualsKey]; ok {
+		condition.equals = &equals
+	}
+
+	return condition, nil
+}
+
+func (c *Condition) ShouldRun(state interface{}, logger logr.Logger) bool {
+	if c.path == nil {
+		return true
+	}
+
+	// Evaluate the expression against the JSON representation of the state
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		logger.Error(err, "failed to marshal state")
+		return false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		logger.Error(err, "failed to unmarshal state")
+		return false
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := c.path.Execute(buffer, decoded); err != nil {
+		logger.Error(err, "failed to evaluate dispatch condition")
+		return false
+	}
+
+	if c.equals != nil {
+		return buffer.String() == *c.equals
+	}
+
+	return buffer.Len() > 0 && buffer.String() != "false"
+}
@@ -0,0 +1,69 @@
+package dispatch
+
+import (
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestCondition(t *testing.T) {
+	state := map[string]interface{}{
+		"my-pod":   "Annotated",
+		"ready":    false,
+		"replicas": 3,
+	}
+
+	scenarios := []struct {
+		Name          string
+		Configuration map[string]string
+		Expected      bool
+	}{
+		{
+			Name:          "No expression",
+			Configuration: map[string]string{},
+			Expected:      true,
+		},
+		{
+			Name:          "Equal value",
+			Configuration: map[string]string{WhenKey: "{.my-pod}", EqualsKey: "Annotated"},
+			Expected:      true,
+		},
+		{
+			Name:          "Different value",
+			Configuration: map[string]string{WhenKey: "{.replicas}", EqualsKey: "2"},
+			Expected:      false,
+		},
+		{
+			Name:          "False value",
+			Configuration: map[string]string{WhenKey: "{.ready}"},
+			Expected:      false,
+		},
+		{
+			Name:          "Missing value",
+			Configuration: map[string]string{WhenKey: "{.other-pod}"},
+			Expected:      false,
+		},
+		{
+			Name:          "Set value",
+			Configuration: map[string]string{WhenKey: "{.replicas}"},
+			Expected:      true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			condition, err := NewCondition(scenario.Configuration)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if shouldRun := condition.ShouldRun(state, ctrl.Log); shouldRun != scenario.Expected {
+				t.Errorf("expected ShouldRun to be %v, got %v", scenario.Expected, shouldRun)
+			}
+		})
+	}
+
+	if _, err := NewCondition(map[string]string{WhenKey: "{.ready"}); err == nil {
+		t.Errorf("expected error for invalid expression")
+	}
+}
@@ -0,0 +1,43 @@
+package dispatch
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// Configured is embedded by the built-in strategies to hold their dispatch
+// Condition and the error of their configuration, if any. Configuration
+// errors don't prevent the dispatch, instead they fail the strategy when it
+// runs so they're reported in its status
+type Configured struct {
+	Condition *Condition
+	Err       error
+}
+
+var _ Dispatchable = &Configured{}
+
+// Configure creates the Configured of a strategy configuration, with the
+// error of its dispatch Condition
+func Configure(configuration map[string]string) Configured {
+	condition, err := NewCondition(configuration)
+	return Configured{Condition: condition, Err: err}
+}
+
+func (c *Configured) ShouldRun(state interface{}, logger logr.Logger) bool {
+	if c.Err != nil {
+		return true
+	}
+
+	return c.Condition.ShouldRun(state, logger)
+}
+
+// ConfigurationError returns the error to fail the strategy with when it
+// runs, or nil if the configuration is valid
+func (c *Configured) ConfigurationError() error {
+	if c.Err != nil {
+		return fmt.Errorf("invalid configuration: %w", c.Err)
+	}
+
+	return nil
+}
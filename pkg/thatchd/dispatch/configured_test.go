@@ -0,0 +1,60 @@
+package dispatch
+
+import (
+	"errors"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestConfigured(t *testing.T) {
+	state := map[string]interface{}{"ready": false}
+
+	scenarios := []struct {
+		Name              string
+		Configuration     map[string]string
+		Err               error
+		ExpectedShouldRun bool
+		ExpectError       bool
+	}{
+		{
+			Name:              "Valid configuration",
+			Configuration:     map[string]string{WhenKey: "{.ready}"},
+			ExpectedShouldRun: false,
+		},
+		{
+			Name:              "Invalid condition",
+			Configuration:     map[string]string{WhenKey: "{.ready"},
+			ExpectedShouldRun: true,
+			ExpectError:       true,
+		},
+		{
+			Name:              "Invalid strategy configuration",
+			Configuration:     map[string]string{WhenKey: "{.ready}"},
+			Err:               errors.New("target is required"),
+			ExpectedShouldRun: true,
+			ExpectError:       true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			configured := Configure(scenario.Configuration)
+			if scenario.Err != nil {
+				configured.Err = scenario.Err
+			}
+
+			if shouldRun := configured.ShouldRun(state, ctrl.Log); shouldRun != scenario.ExpectedShouldRun {
+				t.Errorf("expected ShouldRun to be %v, got %v", scenario.ExpectedShouldRun, shouldRun)
+			}
+
+			err := configured.ConfigurationError()
+			if scenario.ExpectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !scenario.ExpectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package dispatch
 
+import "github.com/go-logr/logr"
+
 type Dispatchable interface {
-	ShouldRun(state interface{}) bool
+	ShouldRun(state interface{}, logger logr.Logger) bool
 }
@@ -10,8 +10,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
 	"github.com/thatchd/thatchd/controllers"
+	"github.com/thatchd/thatchd/pkg/thatchd/diagnostics"
+	"github.com/thatchd/thatchd/pkg/thatchd/storage"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 )
 
@@ -24,19 +28,33 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(thatchdv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(thatchdv1alpha2.AddToScheme(scheme))
 }
 
 // Run starts the Thatchd manager. Applies the schemeFn to the scheme used in
-// the manager client, and injects the strategyProviders in the controllers
+// the manager client, and injects the strategyProviders in the controllers,
+// along with the built-in providers. The strategyProviders take precedence
+// over the built-in providers with the same name
 func Run(schemeFn func(*runtime.Scheme) error, strategyProviders map[string]strategy.StrategyProvider) {
 	utilruntime.Must(schemeFn(scheme))
 
 	var metricsAddr string
 	var enableLeaderElection bool
+	var storageDirectory string
+	var s3Endpoint, s3Bucket, s3Region string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&storageDirectory, "storage-directory", "",
+		"Directory where the Directory storage persists files, usually backed by a PersistentVolumeClaim. "+
+			"The Directory storage is disabled if not set.")
+	flag.StringVar(&s3Endpoint, "s3-endpoint", "",
+		"URL of the S3 compatible endpoint where the S3 storage persists files, such as a MinIO service. "+
+			"The credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables. "+
+			"The S3 storage is disabled if not set.")
+	flag.StringVar(&s3Bucket, "s3-bucket", "thatchd", "Bucket where the S3 storage persists files.")
+	flag.StringVar(&s3Region, "s3-region", "us-east-1", "Region of the S3 compatible endpoint.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -53,6 +71,26 @@ func Run(schemeFn func(*runtime.Scheme) error, strategyProviders map[string]stra
 		os.Exit(1)
 	}
 
+	clientset := kubernetes.NewForConfigOrDie(mgr.GetConfig())
+
+	providers := map[string]strategy.StrategyProvider{}
+	RegisterBuiltinProviders(providers, mgr.GetConfig(), clientset)
+	for name, provider := range strategyProviders {
+		providers[name] = provider
+	}
+	strategyProviders = providers
+
+	stores := storage.NewStores(mgr.GetClient(), mgr.GetScheme(), storageDirectory)
+	if s3Endpoint != "" {
+		stores[thatchdv1alpha1.StorageS3] = &storage.S3Store{
+			Endpoint:        s3Endpoint,
+			Bucket:          s3Bucket,
+			Region:          s3Region,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		}
+	}
+
 	if err = (&controllers.TestSuiteReconciler{
 		Client:            mgr.GetClient(),
 		Log:               ctrl.Log.WithName("controllers").WithName("TestSuite"),
@@ -67,6 +105,11 @@ func Run(schemeFn func(*runtime.Scheme) error, strategyProviders map[string]stra
 		Log:               ctrl.Log.WithName("controllers").WithName("TestCase"),
 		Scheme:            mgr.GetScheme(),
 		StrategyProviders: strategyProviders,
+		Diagnostics: &diagnostics.Collector{
+			Client:    mgr.GetClient(),
+			Clientset: clientset,
+		},
+		Stores: stores,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "TestCase")
 		os.Exit(1)
@@ -80,6 +123,28 @@ func Run(schemeFn func(*runtime.Scheme) error, strategyProviders map[string]stra
 		setupLog.Error(err, "unable to create controller", "controller", "TestWorker")
 		os.Exit(1)
 	}
+	if err = (&controllers.TestMonitorReconciler{
+		Client:            mgr.GetClient(),
+		Log:               ctrl.Log.WithName("controllers").WithName("TestMonitor"),
+		Scheme:            mgr.GetScheme(),
+		StrategyProviders: strategyProviders,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TestMonitor")
+		os.Exit(1)
+	}
+
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&thatchdv1alpha2.TestSuite{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TestSuite")
+			os.Exit(1)
+		}
+		if err = (&controllers.TestSuiteValidator{
+			StrategyProviders: strategyProviders,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TestSuiteValidator")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
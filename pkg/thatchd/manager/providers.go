@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase/assertions"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase/golden"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase/pods"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase/policy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase/probe"
+	"github.com/thatchd/thatchd/pkg/thatchd/testsuite/utils"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker/actions"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker/chaos"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// RegisterBuiltinProviders adds the built-in TestSuite, TestCase and
+// TestWorker providers to the strategy providers. The config and the clientset are used
+// for the requests the controller-runtime client doesn't support, such as
+// exec, logs and evictions
+func RegisterBuiltinProviders(providers map[string]strategy.StrategyProvider, config *rest.Config, clientset kubernetes.Interface) {
+	providers[utils.ResourcesProvider] = utils.NewResourceReconcilerProvider()
+	assertions.Register(providers)
+	golden.Register(providers)
+	probe.Register(providers, config)
+	pods.Register(providers, config, clientset)
+	policy.Register(providers)
+	actions.Register(providers)
+	chaos.Register(providers, clientset)
+}
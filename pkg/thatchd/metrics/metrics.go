@@ -0,0 +1,73 @@
+// Package metrics exports the measurements reported by the test cases as
+// Prometheus metrics, served on the metrics endpoint of the manager
+package metrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Measurements is the gauge of the last value measured by each test case
+var Measurements = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "thatchd_testcase_measurement",
+	Help: "Last value of a measurement reported by a test case",
+}, []string{"namespace", "testcase", "measurement", "unit"})
+
+// recorded holds the labels of the series of each test case, so they're
+// deleted along with it
+var recorded = struct {
+	sync.Mutex
+	series map[types.NamespacedName][]prometheus.Labels
+}{series: map[types.NamespacedName][]prometheus.Labels{}}
+
+func init() {
+	metrics.Registry.MustRegister(Measurements)
+}
+
+// RecordMeasurements sets the gauge of each measurement of a test case,
+// replacing the ones it recorded before. Measurements with a value that
+// isn't a number are ignored
+func RecordMeasurements(testCase *thatchdv1alpha1.TestCase) {
+	key := types.NamespacedName{Namespace: testCase.Namespace, Name: testCase.Name}
+
+	recorded.Lock()
+	defer recorded.Unlock()
+
+	deleteSeries(key)
+	for _, measurement := range testCase.Status.Measurements {
+		value, err := strconv.ParseFloat(measurement.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		labels := prometheus.Labels{
+			"namespace":   testCase.Namespace,
+			"testcase":    testCase.Name,
+			"measurement": measurement.Name,
+			"unit":        measurement.Unit,
+		}
+		Measurements.With(labels).Set(value)
+		recorded.series[key] = append(recorded.series[key], labels)
+	}
+}
+
+// DeleteMeasurements deletes the gauges of a test case once it's deleted, so
+// the series don't grow with every test case ever run
+func DeleteMeasurements(key types.NamespacedName) {
+	recorded.Lock()
+	defer recorded.Unlock()
+
+	deleteSeries(key)
+}
+
+func deleteSeries(key types.NamespacedName) {
+	for _, labels := range recorded.series[key] {
+		Measurements.Delete(labels)
+	}
+	delete(recorded.series, key)
+}
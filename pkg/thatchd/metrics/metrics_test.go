@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRecordMeasurements(t *testing.T) {
+	RecordMeasurements(&thatchdv1alpha1.TestCase{
+		ObjectMeta: v1.ObjectMeta{Name: "test-case", Namespace: "thatchd"},
+		Status: thatchdv1alpha1.TestCaseStatus{
+			Measurements: []thatchdv1alpha1.Measurement{
+				{Name: "latency", Value: "0.25", Unit: "s"},
+				{Name: "replicas", Value: "3"},
+				{Name: "invalid", Value: "fast"},
+			},
+		},
+	})
+
+	expected := `
+# HELP thatchd_testcase_measurement Last value of a measurement reported by a test case
+# TYPE thatchd_testcase_measurement gauge
+thatchd_testcase_measurement{measurement="latency",namespace="thatchd",testcase="test-case",unit="s"} 0.25
+thatchd_testcase_measurement{measurement="replicas",namespace="thatchd",testcase="test-case",unit=""} 3
+`
+	if err := testutil.CollectAndCompare(Measurements, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	// Measurements that aren't reported anymore are removed
+	RecordMeasurements(&thatchdv1alpha1.TestCase{
+		ObjectMeta: v1.ObjectMeta{Name: "test-case", Namespace: "thatchd"},
+		Status: thatchdv1alpha1.TestCaseStatus{
+			Measurements: []thatchdv1alpha1.Measurement{
+				{Name: "replicas", Value: "2"},
+			},
+		},
+	})
+
+	expected = `
+# HELP thatchd_testcase_measurement Last value of a measurement reported by a test case
+# TYPE thatchd_testcase_measurement gauge
+thatchd_testcase_measurement{measurement="replicas",namespace="thatchd",testcase="test-case",unit=""} 2
+`
+	if err := testutil.CollectAndCompare(Measurements, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	DeleteMeasurements(types.NamespacedName{Name: "test-case", Namespace: "thatchd"})
+	if err := testutil.CollectAndCompare(Measurements, strings.NewReader("")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,237 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultMaxLines is the number of lines kept by a buffer when no maximum is
+// configured
+const DefaultMaxLines = 1000
+
+// maxLineLength is the length after which captured lines are truncated, so a
+// single line can't exhaust the space of the buffer
+const maxLineLength = 1024
+
+// Buffer keeps the last lines logged by a test, discarding the oldest ones
+// once the maximum is reached. It's safe to use from multiple goroutines
+type Buffer struct {
+	mu       sync.Mutex
+	maxLines int
+	lines    []string
+	dropped  int
+}
+
+// NewBuffer creates a buffer that keeps up to maxLines lines, or
+// DefaultMaxLines if maxLines is not positive
+func NewBuffer(maxLines int) *Buffer {
+	if maxLines <= 0 {
+		maxLines = DefaultMaxLines
+	}
+
+	return &Buffer{
+		maxLines: maxLines,
+	}
+}
+
+// Write appends a line to the buffer
+func (b *Buffer) Write(line string) {
+	if len(line) > maxLineLength {
+		line = line[:maxLineLength] + "..."
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines = append(b.lines, line)
+	if len(b.lines) > b.maxLines {
+		b.dropped += len(b.lines) - b.maxLines
+		b.lines = b.lines[len(b.lines)-b.maxLines:]
+	}
+}
+
+// Len returns the number of lines written to the buffer, including the
+// discarded ones
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.lines) + b.dropped
+}
+
+// Bytes returns the lines in the buffer, preceded by a notice of how many
+// lines were discarded, if any
+func (b *Buffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	buffer := &bytes.Buffer{}
+	if b.dropped > 0 {
+		fmt.Fprintf(buffer, "... %d lines discarded\n", b.dropped)
+	}
+	for _, line := range b.lines {
+		buffer.WriteString(line)
+		buffer.WriteString("\n")
+	}
+
+	return buffer.Bytes()
+}
+
+// TailWriter is an io.Writer that keeps the last bytes written to it,
+// discarding the oldest ones once the maximum is reached. It's safe to use
+// from multiple goroutines
+type TailWriter struct {
+	mu        sync.Mutex
+	maxSize   int
+	content   []byte
+	discarded int
+}
+
+// NewTailWriter creates a writer that keeps up to maxSize bytes
+func NewTailWriter(maxSize int) *TailWriter {
+	return &TailWriter{
+		maxSize: maxSize,
+	}
+}
+
+// Write appends p to the writer, discarding the oldest bytes above the
+// maximum
+func (w *TailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.content = append(w.content, p...)
+	if excess := len(w.content) - w.maxSize; excess > 0 {
+		w.discarded += excess
+		w.content = append(w.content[:0], w.content[excess:]...)
+	}
+
+	return len(p), nil
+}
+
+// Len returns the number of bytes kept
+func (w *TailWriter) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.content)
+}
+
+// Bytes returns the bytes kept, preceded by a notice of how many bytes were
+// discarded, if any
+func (w *TailWriter) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buffer := &bytes.Buffer{}
+	if w.discarded > 0 {
+		fmt.Fprintf(buffer, "... %d bytes discarded\n", w.discarded)
+	}
+	buffer.Write(w.content)
+
+	return buffer.Bytes()
+}
+
+// logger is a logr.Logger that writes into a delegate logger, and captures
+// every line into a buffer
+type logger struct {
+	delegate logr.Logger
+	buffer   *Buffer
+	name     string
+	values   []interface{}
+	level    int
+}
+
+var _ logr.Logger = &logger{}
+
+// NewLogger returns a logger that writes into delegate, capturing the lines
+// into the buffer regardless of their verbosity
+func NewLogger(delegate logr.Logger, buffer *Buffer) logr.Logger {
+	return &logger{
+		delegate: delegate,
+		buffer:   buffer,
+	}
+}
+
+func (l *logger) Enabled() bool {
+	return true
+}
+
+func (l *logger) Info(msg string, keysAndValues ...interface{}) {
+	l.delegate.V(l.level).Info(msg, keysAndValues...)
+
+	severity := "INFO"
+	if l.level > 0 {
+		severity = fmt.Sprintf("V(%d)", l.level)
+	}
+	l.capture(severity, msg, keysAndValues)
+}
+
+func (l *logger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.delegate.Error(err, msg, keysAndValues...)
+
+	l.capture("ERROR", msg, append(keysAndValues, "error", err))
+}
+
+func (l *logger) V(level int) logr.InfoLogger {
+	return &logger{
+		delegate: l.delegate,
+		buffer:   l.buffer,
+		name:     l.name,
+		values:   l.values,
+		level:    l.level + level,
+	}
+}
+
+func (l *logger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	values := make([]interface{}, 0, len(l.values)+len(keysAndValues))
+	values = append(values, l.values...)
+	values = append(values, keysAndValues...)
+
+	return &logger{
+		delegate: l.delegate.WithValues(keysAndValues...),
+		buffer:   l.buffer,
+		name:     l.name,
+		values:   values,
+		level:    l.level,
+	}
+}
+
+func (l *logger) WithName(name string) logr.Logger {
+	fullName := name
+	if l.name != "" {
+		fullName = fmt.Sprintf("%s.%s", l.name, name)
+	}
+
+	return &logger{
+		delegate: l.delegate.WithName(name),
+		buffer:   l.buffer,
+		name:     fullName,
+		values:   l.values,
+		level:    l.level,
+	}
+}
+
+func (l *logger) capture(severity, msg string, keysAndValues []interface{}) {
+	parts := []string{time.Now().UTC().Format(time.RFC3339), severity}
+	if l.name != "" {
+		parts = append(parts, l.name)
+	}
+	parts = append(parts, msg)
+
+	values := append(append([]interface{}{}, l.values...), keysAndValues...)
+	for i := 0; i < len(values); i += 2 {
+		if i+1 < len(values) {
+			parts = append(parts, fmt.Sprintf("%v=%v", values[i], values[i+1]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v", values[i]))
+		}
+	}
+
+	l.buffer.Write(strings.Join(parts, " "))
+}
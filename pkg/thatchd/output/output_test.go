@@ -0,0 +1,50 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestLogger(t *testing.T) {
+	buffer := NewBuffer(2)
+	logger := NewLogger(ctrl.Log, buffer).WithName("test").WithValues("pod", "subject")
+
+	logger.Info("first")
+	logger.V(1).Info("second", "replicas", 3)
+	logger.Error(errors.New("not ready"), "third")
+
+	if buffer.Len() != 3 {
+		t.Errorf("expected 3 lines to be written, got %d", buffer.Len())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(buffer.Bytes()), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected the discarded notice and 2 lines, got:\n%s", buffer.Bytes())
+	}
+
+	for i, expected := range []string{
+		"... 1 lines discarded",
+		"V(1) test second pod=subject replicas=3",
+		"ERROR test third pod=subject error=not ready",
+	} {
+		if !strings.HasSuffix(lines[i], expected) {
+			t.Errorf("expected line %d to end with %q, got %q", i, expected, lines[i])
+		}
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	writer := NewTailWriter(8)
+	for _, chunk := range []string{"first ", "second ", "third"} {
+		if _, err := writer.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if content := string(writer.Bytes()); content != "... 10 bytes discarded\nnd third" {
+		t.Errorf("expected the last 8 bytes to be kept, got %q", content)
+	}
+}
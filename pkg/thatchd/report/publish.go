@@ -0,0 +1,58 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// JSONKey is the key of the ConfigMap that holds the JSON report
+	JSONKey = "report.json"
+	// JUnitKey is the key of the ConfigMap that holds the JUnit report
+	JUnitKey = "junit.xml"
+)
+
+// ConfigMapName returns the name of the ConfigMap where the report of the
+// suite is published
+func ConfigMapName(suite *thatchdv1alpha2.TestSuite) string {
+	return fmt.Sprintf("%s-report", suite.Name)
+}
+
+// Publish stores the report in a ConfigMap owned by the suite. The ConfigMap
+// is only updated when the report changes
+func Publish(ctx context.Context, c client.Client, scheme *runtime.Scheme, suite *thatchdv1alpha2.TestSuite, report *Report) error {
+	jsonReport, err := report.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	junitReport, err := report.JUnit()
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      ConfigMapName(suite),
+			Namespace: suite.Namespace,
+		},
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, c, configMap, func() error {
+		configMap.Data = map[string]string{
+			JSONKey:  string(jsonReport),
+			JUnitKey: string(junitReport),
+		}
+
+		return controllerutil.SetControllerReference(suite, configMap, scheme)
+	})
+
+	return err
+}
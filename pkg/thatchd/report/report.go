@@ -0,0 +1,268 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+)
+
+// Report summarizes the results of the test cases of a suite
+type Report struct {
+	Suite     string                            `json:"suite"`
+	Namespace string                            `json:"namespace"`
+	Verdict   thatchdv1alpha2.TestSuiteVerdict  `json:"verdict"`
+	Summary   *thatchdv1alpha2.TestSuiteSummary `json:"summary"`
+	TestCases []TestCaseReport                  `json:"testCases"`
+	Monitors  []MonitorReport                   `json:"monitors,omitempty"`
+}
+
+// TestCaseReport is the result of a single test case
+type TestCaseReport struct {
+	Name           string                                `json:"name"`
+	Status         thatchdv1alpha1.TestCaseCurrentStatus `json:"status,omitempty"`
+	StartedAt      *string                               `json:"startedAt,omitempty"`
+	FinishedAt     *string                               `json:"finishedAt,omitempty"`
+	FailureMessage *string                               `json:"failureMessage,omitempty"`
+	ExpectFailure  *thatchdv1alpha1.ExpectedFailure      `json:"expectFailure,omitempty"`
+	Assertions     []thatchdv1alpha1.AssertionResult     `json:"assertions,omitempty"`
+	Artifacts      []thatchdv1alpha1.ArtifactReference   `json:"artifacts,omitempty"`
+	Attempts       int                                   `json:"attempts,omitempty"`
+	Measurements   []thatchdv1alpha1.Measurement         `json:"measurements,omitempty"`
+}
+
+// MonitorReport is the result of the checks of a monitor
+type MonitorReport struct {
+	Name           string                                   `json:"name"`
+	Status         thatchdv1alpha1.TestMonitorCurrentStatus `json:"status,omitempty"`
+	Checks         int                                      `json:"checks"`
+	ViolationCount int                                      `json:"violationCount"`
+	Violations     []thatchdv1alpha1.MonitorViolation       `json:"violations,omitempty"`
+}
+
+// New builds the report of a test suite from its test cases and monitors.
+// They're sorted by name so the report is stable between reconciliations
+func New(suite *thatchdv1alpha2.TestSuite, testCases []thatchdv1alpha1.TestCase, monitors []thatchdv1alpha1.TestMonitor) *Report {
+	verdict, summary := Summarize(testCases, monitors)
+
+	result := &Report{
+		Suite:     suite.Name,
+		Namespace: suite.Namespace,
+		Verdict:   verdict,
+		Summary:   summary,
+		TestCases: make([]TestCaseReport, 0, len(testCases)),
+	}
+
+	for _, testCase := range testCases {
+		result.TestCases = append(result.TestCases, TestCaseReport{
+			Name:           testCase.Name,
+			Status:         testCase.Status.Status,
+			StartedAt:      testCase.Status.StartedAt,
+			FinishedAt:     testCase.Status.FinishedAt,
+			FailureMessage: testCase.Status.FailureMessage,
+			ExpectFailure:  testCase.Spec.ExpectFailure,
+			Assertions:     testCase.Status.Assertions,
+			Artifacts:      testCase.Status.Artifacts,
+			Attempts:       testCase.Status.Attempts,
+			Measurements:   testCase.Status.Measurements,
+		})
+	}
+
+	sort.Slice(result.TestCases, func(i, j int) bool {
+		return result.TestCases[i].Name < result.TestCases[j].Name
+	})
+
+	for _, monitor := range monitors {
+		result.Monitors = append(result.Monitors, MonitorReport{
+			Name:           monitor.Name,
+			Status:         monitor.Status.Status,
+			Checks:         monitor.Status.Checks,
+			ViolationCount: monitor.Status.ViolationCount,
+			Violations:     monitor.Status.Violations,
+		})
+	}
+	sort.Slice(result.Monitors, func(i, j int) bool {
+		return result.Monitors[i].Name < result.Monitors[j].Name
+	})
+
+	return result
+}
+
+// JSON returns the indented JSON representation of the report
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// JUnit returns the report in the JUnit XML format, mapping every test case
+// into a JUnit test case, and listing its assertions, measurements and
+// artifacts in the output. Artifacts use the [[ATTACHMENT|path]] convention
+// understood by CI servers. Monitors are mapped into JUnit test cases too, failing with their
+// violations
+func (r *Report) JUnit() ([]byte, error) {
+	suite := junitTestSuite{
+		Name:  fmt.Sprintf("%s/%s", r.Namespace, r.Suite),
+		Tests: len(r.TestCases) + len(r.Monitors),
+		Cases: make([]junitTestCase, 0, len(r.TestCases)+len(r.Monitors)),
+	}
+
+	for _, testCase := range r.TestCases {
+		junitCase := junitTestCase{
+			Name:      testCase.Name,
+			ClassName: suite.Name,
+			Time:      duration(testCase.StartedAt, testCase.FinishedAt),
+			SystemOut: systemOut(testCase),
+		}
+
+		message := ""
+		if testCase.FailureMessage != nil {
+			message = *testCase.FailureMessage
+		}
+
+		switch testCase.Status {
+		case thatchdv1alpha1.TestCaseFailed:
+			suite.Failures++
+			junitCase.Failure = &junitMessage{Message: message, Body: message}
+		case thatchdv1alpha1.TestCaseCanceled:
+			suite.Errors++
+			junitCase.Error = &junitMessage{Message: message, Body: message}
+		case thatchdv1alpha1.TestCaseSkipped:
+			suite.Skipped++
+			junitCase.Skipped = &junitMessage{Message: message}
+		case thatchdv1alpha1.TestCaseXFailed:
+			suite.Skipped++
+			junitCase.Skipped = &junitMessage{Message: fmt.Sprintf("expected failure: %s", testCase.expectedFailureReason()), Body: message}
+		case thatchdv1alpha1.TestCaseXPassed:
+			if testCase.ExpectFailure != nil && testCase.ExpectFailure.Quarantine {
+				break
+			}
+			suite.Failures++
+			junitCase.Failure = &junitMessage{Message: fmt.Sprintf("test case passed but was expected to fail: %s", testCase.expectedFailureReason())}
+		case thatchdv1alpha1.TestCaseFinished:
+		default:
+			suite.Skipped++
+			junitCase.Skipped = &junitMessage{Message: fmt.Sprintf("test case is %s", statusOrCreated(testCase.Status))}
+		}
+
+		suite.Cases = append(suite.Cases, junitCase)
+	}
+
+	for _, monitor := range r.Monitors {
+		junitCase := junitTestCase{
+			Name:      monitor.Name,
+			ClassName: suite.Name + "/monitors",
+			SystemOut: fmt.Sprintf("%d check(s)", monitor.Checks),
+		}
+
+		switch {
+		case monitor.ViolationCount > 0:
+			suite.Failures++
+			junitCase.Failure = &junitMessage{
+				Message: fmt.Sprintf("monitor violated %d time(s)", monitor.ViolationCount),
+				Body:    violations(monitor.Violations),
+			}
+		case monitor.Checks == 0:
+			suite.Skipped++
+			junitCase.Skipped = &junitMessage{Message: "monitor was never checked"}
+		}
+
+		suite.Cases = append(suite.Cases, junitCase)
+	}
+
+	output, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+func (r *TestCaseReport) expectedFailureReason() string {
+	if r.ExpectFailure == nil {
+		return ""
+	}
+
+	return r.ExpectFailure.Reason
+}
+
+func systemOut(testCase TestCaseReport) string {
+	lines := make([]string, 0, len(testCase.Assertions)+len(testCase.Measurements)+len(testCase.Artifacts))
+	for _, assertion := range testCase.Assertions {
+		line := fmt.Sprintf("[%s] %s", assertion.Outcome, assertion.Name)
+		if assertion.Message != "" {
+			line = fmt.Sprintf("%s: %s", line, assertion.Message)
+		}
+		lines = append(lines, line)
+	}
+	for _, measurement := range testCase.Measurements {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("[Measurement] %s: %s %s", measurement.Name, measurement.Value, measurement.Unit)))
+	}
+	for _, artifact := range testCase.Artifacts {
+		lines = append(lines, fmt.Sprintf("[[ATTACHMENT|%s/%s]]", artifact.Storage.Location, artifact.Name))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func violations(violations []thatchdv1alpha1.MonitorViolation) string {
+	lines := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		lines = append(lines, fmt.Sprintf("%s: %s", violation.Time.UTC().Format(time.RFC3339), violation.Message))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func duration(startedAt, finishedAt *string) string {
+	if startedAt == nil || finishedAt == nil {
+		return ""
+	}
+
+	start, err := time.Parse(thatchdv1alpha1.DateTimeFormat, *startedAt)
+	if err != nil {
+		return ""
+	}
+	finish, err := time.Parse(thatchdv1alpha1.DateTimeFormat, *finishedAt)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%.3f", finish.Sub(start).Seconds())
+}
+
+func statusOrCreated(status thatchdv1alpha1.TestCaseCurrentStatus) thatchdv1alpha1.TestCaseCurrentStatus {
+	if status == "" {
+		return thatchdv1alpha1.TestCaseCreated
+	}
+
+	return status
+}
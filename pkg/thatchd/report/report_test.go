@@ -0,0 +1,185 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJUnit(t *testing.T) {
+	failureMessage := "1 assertion(s) failed: ready"
+
+	report := New(&thatchdv1alpha2.TestSuite{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		},
+	}, []thatchdv1alpha1.TestCase{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "test-case-b"},
+			Status: thatchdv1alpha1.TestCaseStatus{
+				Status:         thatchdv1alpha1.TestCaseFailed,
+				FailureMessage: &failureMessage,
+				Assertions: []thatchdv1alpha1.AssertionResult{
+					{Name: "replicas", Outcome: thatchdv1alpha1.AssertionPassed},
+					{Name: "ready", Outcome: thatchdv1alpha1.AssertionFailed, Message: "expected true, got false"},
+				},
+				Artifacts: []thatchdv1alpha1.ArtifactReference{
+					{
+						Name: "deployment.yaml",
+						Size: 512,
+						Storage: thatchdv1alpha1.StorageReference{
+							Type:     thatchdv1alpha1.StorageConfigMap,
+							Location: "test-case-b-artifacts",
+						},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "test-case-a"},
+			Status: thatchdv1alpha1.TestCaseStatus{
+				Status: thatchdv1alpha1.TestCaseFinished,
+				Measurements: []thatchdv1alpha1.Measurement{
+					{Name: "time-to-ready", Value: "12.5", Unit: "s"},
+					{Name: "restarts", Value: "0"},
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "test-case-c"},
+		},
+	}, []thatchdv1alpha1.TestMonitor{
+		{
+			ObjectMeta: v1.ObjectMeta{Name: "api-available"},
+			Status: thatchdv1alpha1.TestMonitorStatus{
+				Status:         thatchdv1alpha1.TestMonitorViolated,
+				Checks:         12,
+				ViolationCount: 1,
+				Violations: []thatchdv1alpha1.MonitorViolation{
+					{
+						Time:    v1.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC),
+						Message: "no response after 5s",
+					},
+				},
+			},
+		},
+	})
+
+	if report.TestCases[0].Name != "test-case-a" {
+		t.Errorf("expected test cases to be sorted by name, but got %s first", report.TestCases[0].Name)
+	}
+
+	junit, err := report.JUnit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<testsuite name="thatchd/test-suite" tests="4" failures="2" errors="0" skipped="1">`,
+		`<failure message="monitor violated 1 time(s)">2020-08-01T12:00:00Z: no response after 5s</failure>`,
+		`<failure message="1 assertion(s) failed: ready">`,
+		`[Failed] ready: expected true, got false`,
+		`[[ATTACHMENT|test-case-b-artifacts/deployment.yaml]]`,
+		`[Measurement] time-to-ready: 12.5 s`,
+		`[Measurement] restarts: 0</system-out>`,
+		`<skipped message="test case is Created">`,
+	} {
+		if !strings.Contains(string(junit), expected) {
+			t.Errorf("expected JUnit report to contain %s, got:\n%s", expected, junit)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	scenarios := []struct {
+		Name            string
+		Statuses        []thatchdv1alpha1.TestCaseCurrentStatus
+		Quarantined     bool
+		Violated        bool
+		ExpectedVerdict thatchdv1alpha2.TestSuiteVerdict
+	}{
+		{
+			Name:            "No test cases finished",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{"", thatchdv1alpha1.TestCaseCreated},
+			ExpectedVerdict: thatchdv1alpha2.TestSuitePending,
+		},
+		{
+			Name:            "Test cases in progress",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished, thatchdv1alpha1.TestCaseRunning},
+			ExpectedVerdict: thatchdv1alpha2.TestSuiteRunning,
+		},
+		{
+			Name:            "Test cases waiting to be dispatched",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished, thatchdv1alpha1.TestCaseCreated, ""},
+			ExpectedVerdict: thatchdv1alpha2.TestSuiteRunning,
+		},
+		{
+			Name:            "Quarantined test cases waiting are ignored",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished, thatchdv1alpha1.TestCaseCreated},
+			Quarantined:     true,
+			ExpectedVerdict: thatchdv1alpha2.TestSuitePassed,
+		},
+		{
+			Name:            "Skipped and expected failures pass",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished, thatchdv1alpha1.TestCaseSkipped, thatchdv1alpha1.TestCaseXFailed},
+			ExpectedVerdict: thatchdv1alpha2.TestSuitePassed,
+		},
+		{
+			Name:            "Unexpected pass fails",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished, thatchdv1alpha1.TestCaseXPassed},
+			ExpectedVerdict: thatchdv1alpha2.TestSuiteFailed,
+		},
+		{
+			Name:            "Quarantined unexpected pass is ignored",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished, thatchdv1alpha1.TestCaseXPassed},
+			Quarantined:     true,
+			ExpectedVerdict: thatchdv1alpha2.TestSuitePassed,
+		},
+		{
+			Name:            "Violated monitor fails",
+			Statuses:        []thatchdv1alpha1.TestCaseCurrentStatus{thatchdv1alpha1.TestCaseFinished},
+			Violated:        true,
+			ExpectedVerdict: thatchdv1alpha2.TestSuiteFailed,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			testCases := make([]thatchdv1alpha1.TestCase, 0, len(scenario.Statuses))
+			for _, status := range scenario.Statuses {
+				testCase := thatchdv1alpha1.TestCase{
+					Status: thatchdv1alpha1.TestCaseStatus{Status: status},
+				}
+				if status == thatchdv1alpha1.TestCaseXPassed || status == thatchdv1alpha1.TestCaseCreated {
+					testCase.Spec.ExpectFailure = &thatchdv1alpha1.ExpectedFailure{
+						Quarantine: scenario.Quarantined,
+					}
+				}
+				testCases = append(testCases, testCase)
+			}
+
+			monitors := []thatchdv1alpha1.TestMonitor{{
+				Status: thatchdv1alpha1.TestMonitorStatus{Status: thatchdv1alpha1.TestMonitorHealthy, Checks: 3},
+			}}
+			if scenario.Violated {
+				monitors[0].Status.ViolationCount = 1
+			}
+
+			verdict, summary := Summarize(testCases, monitors)
+			if verdict != scenario.ExpectedVerdict {
+				t.Errorf("expected verdict %s, got %s", scenario.ExpectedVerdict, verdict)
+			}
+			if scenario.Violated != (summary.ViolatedMonitors == 1) {
+				t.Errorf("unexpected violated monitors in summary: %d", summary.ViolatedMonitors)
+			}
+			if summary.Total != len(scenario.Statuses) {
+				t.Errorf("expected %d test cases in summary, got %d", len(scenario.Statuses), summary.Total)
+			}
+		})
+	}
+}
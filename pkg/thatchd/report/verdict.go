@@ -0,0 +1,69 @@
+package report
+
+import (
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+)
+
+// Summarize counts the test cases by their result and computes the verdict
+// of the suite. The suite fails when any test case failed, was canceled or
+// unexpectedly passed, ignoring quarantined test cases, or any monitor was
+// violated. Otherwise, it's pending until a test case starts, running while
+// test cases that aren't quarantined are waiting or in progress, and passes
+// once all of them have finished
+func Summarize(testCases []thatchdv1alpha1.TestCase, monitors []thatchdv1alpha1.TestMonitor) (thatchdv1alpha2.TestSuiteVerdict, *thatchdv1alpha2.TestSuiteSummary) {
+	summary := &thatchdv1alpha2.TestSuiteSummary{
+		Total: len(testCases),
+	}
+
+	failed, waiting, started := false, false, false
+
+	for _, testCase := range testCases {
+		quarantined := testCase.Spec.ExpectFailure != nil && testCase.Spec.ExpectFailure.Quarantine
+
+		switch testCase.Status.Status {
+		case thatchdv1alpha1.TestCaseFinished:
+			summary.Passed++
+			started = true
+		case thatchdv1alpha1.TestCaseFailed, thatchdv1alpha1.TestCaseCanceled:
+			summary.Failed++
+			failed = failed || !quarantined
+			started = true
+		case thatchdv1alpha1.TestCaseSkipped:
+			summary.Skipped++
+			started = true
+		case thatchdv1alpha1.TestCaseXFailed:
+			summary.XFailed++
+			started = true
+		case thatchdv1alpha1.TestCaseXPassed:
+			summary.XPassed++
+			failed = failed || !quarantined
+			started = true
+		case thatchdv1alpha1.TestCaseDispatched, thatchdv1alpha1.TestCaseRunning:
+			summary.Pending++
+			waiting = waiting || !quarantined
+			started = true
+		default:
+			summary.Pending++
+			waiting = waiting || !quarantined
+		}
+	}
+
+	for _, monitor := range monitors {
+		if monitor.Status.ViolationCount > 0 {
+			summary.ViolatedMonitors++
+			failed = true
+		}
+	}
+
+	switch {
+	case failed:
+		return thatchdv1alpha2.TestSuiteFailed, summary
+	case !started:
+		return thatchdv1alpha2.TestSuitePending, summary
+	case waiting:
+		return thatchdv1alpha2.TestSuiteRunning, summary
+	}
+
+	return thatchdv1alpha2.TestSuitePassed, summary
+}
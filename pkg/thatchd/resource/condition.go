@@ -0,0 +1,18 @@
+package resource
+
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+// ConditionStatus returns the status of the condition of the object, or an
+// empty string if it's not set
+func ConditionStatus(object *unstructured.Unstructured, conditionType string) string {
+	conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+	for _, condition := range conditions {
+		condition, ok := condition.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			status, _ := condition["status"].(string)
+			return status
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,39 @@
+package resource
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestConditionStatus(t *testing.T) {
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+				map[string]interface{}{"type": "Progressing", "status": "False"},
+			},
+		},
+	}}
+
+	scenarios := []struct {
+		ConditionType string
+		Expected      string
+	}{
+		{ConditionType: "Available", Expected: "True"},
+		{ConditionType: "Progressing", Expected: "False"},
+		{ConditionType: "ReplicaFailure", Expected: ""},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.ConditionType, func(t *testing.T) {
+			if status := ConditionStatus(object, scenario.ConditionType); status != scenario.Expected {
+				t.Errorf("expected status %q, got %q", scenario.Expected, status)
+			}
+		})
+	}
+
+	if status := ConditionStatus(&unstructured.Unstructured{Object: map[string]interface{}{}}, "Available"); status != "" {
+		t.Errorf("expected no status for an object without conditions, got %q", status)
+	}
+}
@@ -0,0 +1,70 @@
+// Package resourcetest builds the resources that the tests of the built-in
+// strategies run against
+package resourcetest
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Namespace is the namespace of the test resources
+const Namespace = "thatchd"
+
+// Labels returns the labels of the test resources
+func Labels() map[string]string {
+	return map[string]string{"app": "test"}
+}
+
+// Deployment returns a labeled deployment in the test namespace, with the
+// replicas set unless negative
+func Deployment(name string, replicas int32) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: Namespace,
+			Labels:    Labels(),
+		},
+	}
+	if replicas >= 0 {
+		deployment.Spec.Replicas = &replicas
+	}
+
+	return deployment
+}
+
+// Pod returns a labeled pod in the test namespace
+func Pod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: Namespace,
+			Labels:    Labels(),
+		},
+	}
+}
+
+// Deployments returns the "ready" deployment, available with its two
+// replicas ready, and the "unavailable" deployment
+func Deployments() []runtime.Object {
+	ready := Deployment("ready", 2)
+	ready.Annotations = map[string]string{"owner": "team-a"}
+	ready.Spec.Template.Spec.Containers = []corev1.Container{{Image: "app:v2"}}
+	ready.Status = appsv1.DeploymentStatus{
+		ReadyReplicas: 2,
+		Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+		},
+	}
+
+	unavailable := Deployment("unavailable", -1)
+	unavailable.Spec.Template.Spec.Containers = []corev1.Container{{Image: "app:v1"}}
+	unavailable.Status = appsv1.DeploymentStatus{
+		Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse},
+		},
+	}
+
+	return []runtime.Object{ready, unavailable}
+}
@@ -0,0 +1,143 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Configuration keys of a Target
+const (
+	APIVersionKey    = "apiVersion"
+	KindKey          = "kind"
+	NameKey          = "name"
+	NamespaceKey     = "namespace"
+	LabelSelectorKey = "labelSelector"
+)
+
+// Target selects the resources that a built-in strategy works on, of any
+// kind, by name or label selector. They're looked up in the namespace of the
+// test unless the configuration sets a namespace
+type Target struct {
+	GVK       schema.GroupVersionKind
+	Name      string
+	Namespace string
+	Selector  labels.Selector
+}
+
+// NewTarget creates the Target of a strategy configuration
+func NewTarget(configuration map[string]string) (*Target, error) {
+	if configuration[APIVersionKey] == "" || configuration[KindKey] == "" {
+		return nil, fmt.Errorf("%s and %s are required", APIVersionKey, KindKey)
+	}
+
+	groupVersion, err := schema.ParseGroupVersion(configuration[APIVersionKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", APIVersionKey, err)
+	}
+
+	selector := labels.Everything()
+	if configuration[LabelSelectorKey] != "" {
+		if selector, err = labels.Parse(configuration[LabelSelectorKey]); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", LabelSelectorKey, err)
+		}
+	}
+
+	return &Target{
+		GVK:       groupVersion.WithKind(configuration[KindKey]),
+		Name:      configuration[NameKey],
+		Namespace: configuration[NamespaceKey],
+		Selector:  selector,
+	}, nil
+}
+
+// List returns the resources selected by the target. A named resource that
+// doesn't exist results in an empty list
+func (t *Target) List(ctx context.Context, c client.Client, namespace string) ([]unstructured.Unstructured, error) {
+	if t.Namespace != "" {
+		namespace = t.Namespace
+	}
+
+	if t.Name != "" {
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(t.GVK)
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: t.Name}, object); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to get %s: %w", t, err)
+		}
+		if !t.Selector.Matches(labels.Set(object.GetLabels())) {
+			return nil, nil
+		}
+
+		return []unstructured.Unstructured{*object}, nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(t.GVK.GroupVersion().WithKind(t.GVK.Kind + "List"))
+	if err := c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: t.Selector}); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", t, err)
+	}
+
+	return list.Items, nil
+}
+
+// String describes the target, such as "Deployment app=my-operator"
+func (t *Target) String() string {
+	description := t.GVK.Kind
+	if t.Name != "" {
+		description += " " + t.Name
+	}
+	if !t.Selector.Empty() {
+		description += " " + t.Selector.String()
+	}
+
+	return description
+}
+
+// JSONPath renders the result of the JSONPath expression, like kubectl
+// does. Missing fields result in an empty string
+func JSONPath(object *unstructured.Unstructured, expression string) (string, error) {
+	path := jsonpath.New("").AllowMissingKeys(true)
+	if err := path.Parse(expression); err != nil {
+		return "", fmt.Errorf("invalid JSONPath %s: %w", expression, err)
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := path.Execute(buffer, object.UnstructuredContent()); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// DecodeManifest decodes the YAML or JSON documents of an inline manifest
+func DecodeManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		object := &unstructured.Unstructured{}
+		if err := decoder.Decode(&object.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if len(object.Object) > 0 {
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// FromType derives the schema of the JSON encoding of the value's Go type.
+// Struct fields are named after their json tag, and unknown fields are
+// rejected. Fields aren't required, so a partial state such as an empty
+// initial state is valid. Types with a custom JSON encoding accept any value
+func FromType(value interface{}) *apiextensionsv1.JSONSchemaProps {
+	return fromType(reflect.TypeOf(value), map[reflect.Type]bool{})
+}
+
+func fromType(t reflect.Type, visiting map[reflect.Type]bool) *apiextensionsv1.JSONSchemaProps {
+	if t == nil {
+		return &apiextensionsv1.JSONSchemaProps{}
+	}
+
+	if t.Kind() == reflect.Ptr {
+		schema := fromType(t.Elem(), visiting)
+		schema.Nullable = schema.Type != ""
+		return schema
+	}
+
+	// Recursive types and types encoded by themselves accept any value
+	if visiting[t] || t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return &apiextensionsv1.JSONSchemaProps{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return &apiextensionsv1.JSONSchemaProps{Type: "string"}
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &apiextensionsv1.JSONSchemaProps{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &apiextensionsv1.JSONSchemaProps{Type: "number"}
+	case reflect.String:
+		return &apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &apiextensionsv1.JSONSchemaProps{Type: "string", Nullable: true}
+		}
+
+		return &apiextensionsv1.JSONSchemaProps{
+			Type:     "array",
+			Nullable: t.Kind() == reflect.Slice,
+			Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+				Schema: fromType(t.Elem(), visiting),
+			},
+		}
+	case reflect.Map:
+		return &apiextensionsv1.JSONSchemaProps{
+			Type:     "object",
+			Nullable: true,
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+				Allows: true,
+				Schema: fromType(t.Elem(), visiting),
+			},
+		}
+	case reflect.Struct:
+		schema := &apiextensionsv1.JSONSchemaProps{
+			Type:       "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{},
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+				Allows: false,
+			},
+		}
+		addFields(schema, t, visiting)
+		return schema
+	}
+
+	// Interfaces, and types that can't be encoded
+	return &apiextensionsv1.JSONSchemaProps{}
+}
+
+// addFields adds the properties of the struct fields, including the fields
+// of embedded structs, following the encoding/json rules
+func addFields(schema *apiextensionsv1.JSONSchemaProps, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		fieldType := f.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if f.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addFields(schema, fieldType, visiting)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		schema.Properties[name] = *fromType(f.Type, visiting)
+	}
+}
@@ -0,0 +1,38 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate validates the JSON encoded state against the schema, the same way
+// the API server validates custom resources
+func Validate(schema *apiextensionsv1.JSONSchemaProps, state string) error {
+	if schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(state), &value); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(schema, internal, nil); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	validator, _, err := validation.NewSchemaValidator(&apiextensions.CustomResourceValidation{
+		OpenAPIV3Schema: internal,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return validation.ValidateCustomResource(field.NewPath("state"), value, validator).ToAggregate()
+}
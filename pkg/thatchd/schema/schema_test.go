@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+type testComponent struct {
+	Ready    bool      `json:"ready"`
+	Replicas int32     `json:"replicas,omitempty"`
+	Since    time.Time `json:"since,omitempty"`
+}
+
+type testState struct {
+	Components map[string]testComponent `json:"components"`
+	Phases     []string                 `json:"phases,omitempty"`
+	Primary    *testComponent           `json:"primary,omitempty"`
+	Ignored    string                   `json:"-"`
+	testEmbedded
+}
+
+type testEmbedded struct {
+	Version string `json:"version"`
+}
+
+func TestFromType(t *testing.T) {
+	schema := FromType(testState{})
+
+	scenarios := []struct {
+		Name   string
+		State  string
+		Errors []string
+	}{
+		{
+			Name:  "Empty state",
+			State: `{}`,
+		},
+		{
+			Name:  "Valid state",
+			State: `{"components":{"a":{"ready":true,"replicas":3,"since":"2020-01-01T00:00:00Z"}},"phases":null,"primary":null,"version":"v1"}`,
+		},
+		{
+			Name:   "Invalid types",
+			State:  `{"components":{"a":{"ready":"yes","replicas":1.5}},"phases":[1]}`,
+			Errors: []string{`state.components.a.ready`, `state.components.a.replicas`, `state.phases`},
+		},
+		{
+			Name:   "Unknown fields",
+			State:  `{"Ignored":"value","primary":{"healthy":true}}`,
+			Errors: []string{`.Ignored in body is a forbidden property`, `primary.healthy in body is a forbidden property`},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			assertErrors(t, Validate(schema, scenario.State), scenario.Errors)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	minimum := 1.0
+	maxLength := int64(3)
+	schema := &apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"name": {Type: "string", MaxLength: &maxLength, Pattern: "^[a-z]+$"},
+			"replicas": {
+				Type:             "integer",
+				Minimum:          &minimum,
+				ExclusiveMinimum: true,
+			},
+			"phase": {
+				Enum: []apiextensionsv1.JSON{{Raw: []byte(`"Ready"`)}, {Raw: []byte(`"NotReady"`)}},
+			},
+			"ids": {
+				Type:        "array",
+				UniqueItems: true,
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1.JSONSchemaProps{Type: "integer"},
+				},
+			},
+			"value": {
+				OneOf: []apiextensionsv1.JSONSchemaProps{{Type: "string"}, {Type: "integer"}},
+			},
+		},
+	}
+
+	scenarios := []struct {
+		Name   string
+		State  string
+		Errors []string
+	}{
+		{
+			Name:  "Valid state",
+			State: `{"name":"abc","replicas":2,"phase":"Ready","ids":[1,2],"value":"x","extra":true}`,
+		},
+		{
+			Name:   "Missing required field",
+			State:  `{}`,
+			Errors: []string{`state.name: Required value`},
+		},
+		{
+			Name:   "Invalid values",
+			State:  `{"name":"Abcd","replicas":1,"phase":"Unknown","ids":[1,1],"value":true}`,
+			Errors: []string{`state.name`, `state.replicas`, `state.phase`, `state.ids`, `state.value`},
+		},
+		{
+			Name:   "Invalid pattern",
+			State:  `{"name":"A1"}`,
+			Errors: []string{`state.name`, `should match '^[a-z]+$'`},
+		},
+		{
+			Name:   "Invalid JSON",
+			State:  `{"name":`,
+			Errors: []string{`invalid state`},
+		},
+		{
+			Name:   "Null state",
+			State:  `null`,
+			Errors: []string{`must be of type object`},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			assertErrors(t, Validate(schema, scenario.State), scenario.Errors)
+		})
+	}
+}
+
+func assertErrors(t *testing.T, err error, expected []string) {
+	if len(expected) == 0 {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+
+	if err == nil {
+		t.Fatalf("expected errors %v", expected)
+	}
+	for _, message := range expected {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected error to contain %s, got %v", message, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	gojsonpatch "gomodules.xyz/jsonpatch/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// DefaultHistoryLimit is the number of transitions kept when the suite
+// doesn't configure it
+const DefaultHistoryLimit = 100
+
+// HistoryKey is the key of the ConfigMap that holds the history
+const HistoryKey = "history.json"
+
+// maxHistorySize is the size in bytes after which the oldest transitions
+// are discarded, regardless of the limit, so the ConfigMap fits in etcd
+const maxHistorySize = 768 * 1024
+
+// SuiteSource is the source of the transitions made by the TestSuite
+// reconciliation
+const SuiteSource = "suite"
+
+// WorkerSource returns the source of the transitions made by a TestWorker
+func WorkerSource(name string) string {
+	return fmt.Sprintf("worker/%s", name)
+}
+
+// Transition is a change in the state of a TestSuite
+type Transition struct {
+	Time   string `json:"time"`
+	Source string `json:"source"`
+	// Diff is the JSON Patch that transforms the previous state into the
+	// new one
+	Diff []gojsonpatch.Operation `json:"diff"`
+	// Truncated is set when the diff is too large to be recorded, and left
+	// out
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// History records the transitions of the state of a TestSuite in a
+// ConfigMap owned by the suite, keeping the most recent ones
+type History struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+// HistoryConfigMapName returns the name of the ConfigMap where the history
+// of the suite is recorded
+func HistoryConfigMapName(suite *thatchdv1alpha2.TestSuite) string {
+	return fmt.Sprintf("%s-state-history", suite.Name)
+}
+
+// Record appends the transition from previous to current to the history,
+// unless they're semantically equal or the history is disabled
+func (h *History) Record(ctx context.Context, suite *thatchdv1alpha2.TestSuite, source, previous, current string) error {
+	limit := DefaultHistoryLimit
+	if suite.Spec.StateHistoryLimit != nil {
+		limit = *suite.Spec.StateHistoryLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	if previous == "" {
+		previous = "{}"
+	}
+
+	diff, err := gojsonpatch.CreatePatch([]byte(previous), []byte(current))
+	if err != nil {
+		return fmt.Errorf("failed to compute state diff: %w", err)
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+
+	transition := Transition{
+		Time:   time.Now().UTC().Format(thatchdv1alpha1.DateTimeFormat),
+		Source: source,
+		Diff:   diff,
+	}
+	if content, err := json.Marshal(transition); err != nil {
+		return err
+	} else if len(content) > maxHistorySize {
+		transition.Diff = []gojsonpatch.Operation{}
+		transition.Truncated = true
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      HistoryConfigMapName(suite),
+			Namespace: suite.Namespace,
+		},
+	}
+
+	// The suite and its workers record transitions concurrently, so the
+	// history is read again when it changed in between
+	err = retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}, func() error {
+		_, err := controllerutil.CreateOrUpdate(ctx, h.Client, configMap, h.appendTransition(suite, configMap, transition, limit))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record state history: %w", err)
+	}
+
+	return nil
+}
+
+// appendTransition returns the mutation of the history ConfigMap that
+// appends the transition, discarding the oldest ones above the limit or the
+// maximum size
+func (h *History) appendTransition(suite *thatchdv1alpha2.TestSuite, configMap *corev1.ConfigMap, transition Transition, limit int) controllerutil.MutateFn {
+	return func() error {
+		transitions, err := parseHistory(configMap)
+		if err != nil {
+			return err
+		}
+
+		transitions = append(transitions, transition)
+		if len(transitions) > limit {
+			transitions = transitions[len(transitions)-limit:]
+		}
+
+		content, err := json.Marshal(transitions)
+		if err != nil {
+			return err
+		}
+		for len(content) > maxHistorySize && len(transitions) > 1 {
+			transitions = transitions[1:]
+			if content, err = json.Marshal(transitions); err != nil {
+				return err
+			}
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[HistoryKey] = string(content)
+
+		return controllerutil.SetControllerReference(suite, configMap, h.Scheme)
+	}
+}
+
+// Load returns the recorded transitions of the suite, oldest first
+func (h *History) Load(ctx context.Context, suite *thatchdv1alpha2.TestSuite) ([]Transition, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: suite.Namespace, Name: HistoryConfigMapName(suite)}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return []Transition{}, nil
+		}
+
+		return nil, err
+	}
+
+	return parseHistory(configMap)
+}
+
+func parseHistory(configMap *corev1.ConfigMap) ([]Transition, error) {
+	transitions := []Transition{}
+
+	content, ok := configMap.Data[HistoryKey]
+	if !ok || content == "" {
+		return transitions, nil
+	}
+
+	if err := json.Unmarshal([]byte(content), &transitions); err != nil {
+		return nil, fmt.Errorf("failed to parse state history: %w", err)
+	}
+
+	return transitions, nil
+}
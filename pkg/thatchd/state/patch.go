@@ -0,0 +1,137 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	gojsonpatch "gomodules.xyz/jsonpatch/v2"
+)
+
+// ApplyPatch applies a JSON Patch or a JSON Merge Patch to the JSON encoded
+// state
+func ApplyPatch(state string, patch thatchdv1alpha1.StatePatch) (string, error) {
+	switch patch.Type {
+	case thatchdv1alpha1.StateJSONPatch:
+		decoded, err := jsonpatch.DecodePatch([]byte(patch.Patch))
+		if err != nil {
+			return "", fmt.Errorf("invalid JSON Patch: %w", err)
+		}
+
+		result, err := decoded.Apply([]byte(state))
+		if err != nil {
+			return "", err
+		}
+
+		return string(result), nil
+	case thatchdv1alpha1.StateMergePatch:
+		result, err := jsonpatch.MergePatch([]byte(state), []byte(patch.Patch))
+		if err != nil {
+			return "", err
+		}
+
+		return string(result), nil
+	}
+
+	return "", fmt.Errorf("unsupported patch type %s", patch.Type)
+}
+
+// Diff returns the JSON Patch that transforms the previous state into the
+// current one, or nil if they're semantically equal. The patch tests the
+// previous value of every path it replaces or removes, and of every array it
+// changes, so applying it to a state that changed since fails instead of
+// overwriting the change
+func Diff(previous, current string) (*thatchdv1alpha1.StatePatch, error) {
+	operations, err := gojsonpatch.CreatePatch([]byte(previous), []byte(current))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute state diff: %w", err)
+	}
+	if len(operations) == 0 {
+		return nil, nil
+	}
+
+	var previousDocument interface{}
+	if err := json.Unmarshal([]byte(previous), &previousDocument); err != nil {
+		return nil, fmt.Errorf("failed to compute state diff: %w", err)
+	}
+
+	tests := []gojsonpatch.Operation{}
+	tested := map[string]bool{}
+	for _, operation := range operations {
+		path, value, ok := testedValue(previousDocument, operation)
+		if !ok || tested[path] {
+			continue
+		}
+
+		tested[path] = true
+		tests = append(tests, gojsonpatch.Operation{Operation: "test", Path: path, Value: value})
+	}
+
+	content, err := json.Marshal(append(tests, operations...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &thatchdv1alpha1.StatePatch{
+		Type:  thatchdv1alpha1.StateJSONPatch,
+		Patch: string(content),
+	}, nil
+}
+
+// testedValue returns the path and previous value that an operation relies
+// on. Array elements are addressed by index, so the whole array is tested.
+// Adding an object key relies on nothing that can be tested
+func testedValue(document interface{}, operation gojsonpatch.Operation) (string, interface{}, bool) {
+	if operation.Path == "" {
+		return "", document, true
+	}
+
+	parentPath := operation.Path[:strings.LastIndex(operation.Path, "/")]
+	parent, ok := pointerValue(document, parentPath)
+	if !ok {
+		return "", nil, false
+	}
+	if _, isArray := parent.([]interface{}); isArray {
+		return parentPath, parent, true
+	}
+
+	if operation.Operation == "add" {
+		return "", nil, false
+	}
+	value, ok := pointerValue(document, operation.Path)
+	return operation.Path, value, ok
+}
+
+// pointerValue returns the value of a JSON document at a JSON Pointer
+func pointerValue(document interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return document, true
+	}
+
+	value := document
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch container := value.(type) {
+		case map[string]interface{}:
+			child, ok := container[token]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(container) {
+				return nil, false
+			}
+			value = container[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
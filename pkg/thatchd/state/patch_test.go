@@ -0,0 +1,174 @@
+package state
+
+import (
+	"testing"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+)
+
+func TestApplyPatch(t *testing.T) {
+	scenarios := []struct {
+		Name     string
+		Patch    thatchdv1alpha1.StatePatch
+		Expected string
+		Error    bool
+	}{
+		{
+			Name: "JSON Patch",
+			Patch: thatchdv1alpha1.StatePatch{
+				Type:  thatchdv1alpha1.StateJSONPatch,
+				Patch: `[{"op":"test","path":"/ready","value":false},{"op":"replace","path":"/ready","value":true}]`,
+			},
+			Expected: `{"pods":["a"],"ready":true}`,
+		},
+		{
+			Name: "Failed JSON Patch test",
+			Patch: thatchdv1alpha1.StatePatch{
+				Type:  thatchdv1alpha1.StateJSONPatch,
+				Patch: `[{"op":"test","path":"/ready","value":true}]`,
+			},
+			Error: true,
+		},
+		{
+			Name: "Merge Patch",
+			Patch: thatchdv1alpha1.StatePatch{
+				Type:  thatchdv1alpha1.StateMergePatch,
+				Patch: `{"pods":null,"healthy":true}`,
+			},
+			Expected: `{"healthy":true,"ready":false}`,
+		},
+		{
+			Name: "Unsupported type",
+			Patch: thatchdv1alpha1.StatePatch{
+				Type:  "Strategic",
+				Patch: `{}`,
+			},
+			Error: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			result, err := ApplyPatch(`{"pods":["a"],"ready":false}`, scenario.Patch)
+			if scenario.Error {
+				if err == nil {
+					t.Errorf("expected error, got state %s", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			canonical, err := Canonical(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if canonical != scenario.Expected {
+				t.Errorf("expected state %s, got %s", scenario.Expected, canonical)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	patch, err := Diff(`{"ready":false}`, `{"ready": false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patch != nil {
+		t.Errorf("expected no patch for equal states, got %v", patch)
+	}
+
+	previous := `{"pods":{"a":"Ready"},"ready":false}`
+	current := `{"pods":{"a":"Annotated","b":"Ready"},"ready":false}`
+
+	patch, err = Diff(previous, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patch == nil || patch.Type != thatchdv1alpha1.StateJSONPatch {
+		t.Fatalf("expected JSON Patch, got %v", patch)
+	}
+
+	result, err := ApplyPatch(previous, *patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ = Canonical(result); result != current {
+		t.Errorf("expected patch to result in %s, got %s", current, result)
+	}
+}
+
+func TestDiffReplay(t *testing.T) {
+	scenarios := []struct {
+		Name     string
+		Previous string
+		Current  string
+		// Concurrent is the state after a concurrent change to the previous
+		// one, which the patch is replayed on
+		Concurrent string
+		Expected   string
+		Error      bool
+	}{
+		{
+			Name:       "Replaced value changed",
+			Previous:   `{"pods":{"a":"Ready"}}`,
+			Current:    `{"pods":{"a":"Annotated"}}`,
+			Concurrent: `{"pods":{"a":"Deleted"}}`,
+			Error:      true,
+		},
+		{
+			Name:       "Removed value changed",
+			Previous:   `{"pods":{"a":"Ready"},"ready":true}`,
+			Current:    `{"pods":{},"ready":true}`,
+			Concurrent: `{"pods":{"a":"Annotated"},"ready":true}`,
+			Error:      true,
+		},
+		{
+			Name:       "Array appended twice",
+			Previous:   `{"pods":["a"]}`,
+			Current:    `{"pods":["a","b"]}`,
+			Concurrent: `{"pods":["a","b"]}`,
+			Error:      true,
+		},
+		{
+			Name:       "Unrelated value changed",
+			Previous:   `{"pods":{"a":"Ready"},"ready":false}`,
+			Current:    `{"pods":{"a":"Annotated"},"ready":false}`,
+			Concurrent: `{"pods":{"a":"Ready"},"ready":true}`,
+			Expected:   `{"pods":{"a":"Annotated"},"ready":true}`,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			patch, err := Diff(scenario.Previous, scenario.Current)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The patch applies to the state it was computed from
+			if result, err := ApplyPatch(scenario.Previous, *patch); err != nil {
+				t.Fatal(err)
+			} else if result, _ = Canonical(result); result != scenario.Current {
+				t.Errorf("expected patch to result in %s, got %s", scenario.Current, result)
+			}
+
+			result, err := ApplyPatch(scenario.Concurrent, *patch)
+			if scenario.Error {
+				if err == nil {
+					t.Errorf("expected the replayed patch to fail, got state %s", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result, _ = Canonical(result); result != scenario.Expected {
+				t.Errorf("expected state %s, got %s", scenario.Expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// DefaultChunkSize is the maximum size in bytes of the state stored in each
+// ConfigMap when the suite doesn't configure it, leaving room below the
+// etcd object size limit
+const DefaultChunkSize = 512 * 1024
+
+// MaxChunkSize is the maximum size in bytes of the state stored in each
+// ConfigMap, leaving room for its metadata below the 1MiB ConfigMap limit
+const MaxChunkSize = 1000 * 1024
+
+// Key is the key of the ConfigMaps that holds the state, or a chunk of it
+const Key = "state.json"
+
+// ChunkLabel marks the ConfigMaps that hold a chunk of the state, so the ones
+// that are no longer referenced can be pruned
+const ChunkLabel = "testing.thatchd.io/state-chunk"
+
+// Store persists the state of a TestSuite where its spec configures it:
+// inline in the currentState field of its status, or offloaded to one or
+// more ConfigMaps owned by the suite, keeping only a reference in the status.
+// The ConfigMaps are named after the hash of the state, so saving a state
+// never overwrites the chunks referenced by the persisted status
+type Store struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+// Load returns the persisted state of the suite, or an empty string if it
+// hasn't been persisted yet
+func (s *Store) Load(ctx context.Context, suite *thatchdv1alpha2.TestSuite) (string, error) {
+	reference := suite.Status.StateReference
+	if reference == nil {
+		if suite.Status.CurrentState == nil {
+			return "", nil
+		}
+
+		return string(suite.Status.CurrentState.Raw), nil
+	}
+
+	chunks := make([]string, 0, len(reference.ConfigMaps))
+	for _, name := range reference.ConfigMaps {
+		configMap := &corev1.ConfigMap{}
+		if err := s.Client.Get(ctx, client.ObjectKey{Namespace: suite.Namespace, Name: name}, configMap); err != nil {
+			return "", fmt.Errorf("failed to retrieve state ConfigMap %s: %w", name, err)
+		}
+
+		chunks = append(chunks, configMap.Data[Key])
+	}
+
+	state := strings.Join(chunks, "")
+	if hash(state) != reference.Hash {
+		return "", fmt.Errorf("state stored in ConfigMaps doesn't match hash %s", reference.Hash)
+	}
+
+	return state, nil
+}
+
+// Save persists the state and updates the status of the suite to reflect
+// it. The caller is responsible of updating the suite status, and of calling
+// Prune once it's updated. The state is stored in its canonical form, so it
+// only changes when its content does, and the state ConfigMaps are only
+// written when it changes
+func (s *Store) Save(ctx context.Context, suite *thatchdv1alpha2.TestSuite, state string) error {
+	state, err := Canonical(state)
+	if err != nil {
+		return err
+	}
+
+	storage := suite.Spec.StateStorage
+	if storage == nil || storage.Type == "" || storage.Type == thatchdv1alpha2.StateStorageStatus {
+		suite.Status.CurrentState = &apiextensionsv1.JSON{Raw: []byte(state)}
+		suite.Status.StateReference = nil
+		return nil
+	}
+
+	stateHash := hash(state)
+	if suite.Status.StateReference != nil && suite.Status.StateReference.Hash == stateHash {
+		suite.Status.CurrentState = nil
+		return nil
+	}
+
+	chunkSize := storage.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > MaxChunkSize {
+		chunkSize = MaxChunkSize
+	}
+
+	chunks := split(state, chunkSize)
+	names := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		name := ConfigMapName(suite, stateHash, i)
+		if err := s.writeChunk(ctx, suite, name, chunk); err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+
+	suite.Status.CurrentState = nil
+	suite.Status.StateReference = &thatchdv1alpha2.StateReference{
+		ConfigMaps: names,
+		Hash:       stateHash,
+		Size:       len(state),
+	}
+
+	return nil
+}
+
+// Prune removes the state ConfigMaps of the suite that its status doesn't
+// reference. It must only be called once the status is persisted, so the
+// chunks of the persisted state are never removed, while the chunks written
+// by a Save whose status update failed are
+func (s *Store) Prune(ctx context.Context, suite *thatchdv1alpha2.TestSuite) error {
+	referenced := map[string]bool{}
+	if suite.Status.StateReference != nil {
+		for _, name := range suite.Status.StateReference.ConfigMaps {
+			referenced[name] = true
+		}
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := s.Client.List(ctx, configMaps, client.InNamespace(suite.Namespace), client.HasLabels{ChunkLabel}); err != nil {
+		return fmt.Errorf("failed to list state ConfigMaps: %w", err)
+	}
+
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if referenced[configMap.Name] || !v1.IsControlledBy(configMap, suite) {
+			continue
+		}
+
+		if err := s.Client.Delete(ctx, configMap); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete state ConfigMap %s: %w", configMap.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Canonical returns the compact JSON encoding of the state with sorted
+// object keys, which is how the API server returns it
+func Canonical(state string) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(state))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("state is not valid JSON: %w", err)
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// ConfigMapName returns the name of the ConfigMap holding the i-th chunk of
+// the suite state with the given hash
+func ConfigMapName(suite *thatchdv1alpha2.TestSuite, stateHash string, i int) string {
+	if len(stateHash) > 12 {
+		stateHash = stateHash[:12]
+	}
+
+	return fmt.Sprintf("%s-state-%s-%d", suite.Name, stateHash, i)
+}
+
+func (s *Store) writeChunk(ctx context.Context, suite *thatchdv1alpha2.TestSuite, name, chunk string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: suite.Namespace,
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, s.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels[ChunkLabel] = "true"
+		configMap.Data = map[string]string{Key: chunk}
+		return controllerutil.SetControllerReference(suite, configMap, s.Scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to store state ConfigMap %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// split divides the state into chunks of at most size bytes, without
+// splitting multi-byte characters
+func split(state string, size int) []string {
+	if len(state) <= size {
+		return []string{state}
+	}
+
+	chunks := []string{}
+	for len(state) > size {
+		end := size
+		for end > 0 && !utf8.RuneStart(state[end]) {
+			end--
+		}
+		if end == 0 {
+			end = size
+		}
+
+		chunks = append(chunks, state[:end])
+		state = state[end:]
+	}
+
+	return append(chunks, state)
+}
+
+func hash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
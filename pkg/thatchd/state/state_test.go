@@ -0,0 +1,290 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	thatchdv1alpha2 "github.com/thatchd/thatchd/api/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStore(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := thatchdv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := &thatchdv1alpha2.TestSuite{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		},
+		Spec: thatchdv1alpha2.TestSuiteSpec{
+			StateStorage: &thatchdv1alpha2.StateStorageSpec{
+				Type:      thatchdv1alpha2.StateStorageConfigMap,
+				ChunkSize: 10,
+			},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(scheme, suite)
+	store := &Store{Client: c, Scheme: scheme}
+	ctx := context.TODO()
+
+	state := `{"pods":["añb","cñd","eñf"]}`
+	if err := store.Save(ctx, suite, state); err != nil {
+		t.Fatal(err)
+	}
+
+	if suite.Status.CurrentState != nil {
+		t.Errorf("expected the state to be offloaded, got %s", suite.Status.CurrentState.Raw)
+	}
+	if suite.Status.StateReference == nil || len(suite.Status.StateReference.ConfigMaps) != 4 {
+		t.Fatalf("expected the state to be split in 4 ConfigMaps, got %v", suite.Status.StateReference)
+	}
+	if suite.Status.StateReference.Size != len(state) {
+		t.Errorf("expected size %d, got %d", len(state), suite.Status.StateReference.Size)
+	}
+
+	loaded, err := store.Load(ctx, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != state {
+		t.Errorf("expected loaded state %s, got %s", state, loaded)
+	}
+
+	// A save whose status update fails leaves the persisted state intact,
+	// and its chunks are pruned once another update succeeds
+	failed := suite.DeepCopy()
+	if err := store.Save(ctx, failed, `{"pods":["gñh"]}`); err != nil {
+		t.Fatal(err)
+	}
+	assertConfigMapCount(t, c, 4+2)
+
+	if loaded, err := store.Load(ctx, suite); err != nil || loaded != state {
+		t.Errorf("expected the persisted state %s to load, got %s: %v", state, loaded, err)
+	}
+	if err := store.Prune(ctx, suite); err != nil {
+		t.Fatal(err)
+	}
+	assertConfigMapCount(t, c, 4)
+	for _, name := range failed.Status.StateReference.ConfigMaps {
+		assertConfigMapExists(t, c, name, false)
+	}
+
+	// Shrinking the state replaces the chunks, removing the previous ones
+	// once pruned
+	previous := suite.Status.StateReference.ConfigMaps
+	if err := store.Save(ctx, suite, `{"pods":[]}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(suite.Status.StateReference.ConfigMaps) != 2 {
+		t.Errorf("expected the state to be split in 2 ConfigMaps, got %v", suite.Status.StateReference.ConfigMaps)
+	}
+	assertConfigMapExists(t, c, previous[0], true)
+	if err := store.Prune(ctx, suite); err != nil {
+		t.Fatal(err)
+	}
+	assertConfigMapExists(t, c, previous[0], false)
+	assertConfigMapCount(t, c, 2)
+
+	// Storing the state in the status removes the ConfigMaps
+	suite.Spec.StateStorage = nil
+	if err := store.Save(ctx, suite, `{"pods":[]}`); err != nil {
+		t.Fatal(err)
+	}
+	if suite.Status.StateReference != nil || suite.Status.CurrentState == nil || string(suite.Status.CurrentState.Raw) != `{"pods":[]}` {
+		t.Errorf("expected the state to be stored in the status, got %v", suite.Status)
+	}
+	if err := store.Prune(ctx, suite); err != nil {
+		t.Fatal(err)
+	}
+	assertConfigMapCount(t, c, 0)
+}
+
+func TestStoreChunkSize(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := thatchdv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		Name      string
+		ChunkSize int
+		Chunks    int
+	}{
+		{
+			Name:   "Default chunk size",
+			Chunks: 4,
+		},
+		{
+			Name:      "Chunk size above the maximum",
+			ChunkSize: 4 * MaxChunkSize,
+			Chunks:    2,
+		},
+	}
+
+	state := fmt.Sprintf(`{"logs":%q}`, strings.Repeat("x", 3*DefaultChunkSize))
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			suite := &thatchdv1alpha2.TestSuite{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "test-suite",
+					Namespace: "thatchd",
+				},
+				Spec: thatchdv1alpha2.TestSuiteSpec{
+					StateStorage: &thatchdv1alpha2.StateStorageSpec{
+						Type:      thatchdv1alpha2.StateStorageConfigMap,
+						ChunkSize: scenario.ChunkSize,
+					},
+				},
+			}
+
+			store := &Store{Client: fake.NewFakeClientWithScheme(scheme, suite), Scheme: scheme}
+			if err := store.Save(context.TODO(), suite, state); err != nil {
+				t.Fatal(err)
+			}
+			if chunks := len(suite.Status.StateReference.ConfigMaps); chunks != scenario.Chunks {
+				t.Errorf("expected the state to be split in %d ConfigMaps, got %d", scenario.Chunks, chunks)
+			}
+		})
+	}
+}
+
+func assertConfigMapCount(t *testing.T, c client.Client, expected int) {
+	t.Helper()
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(context.TODO(), configMaps, client.HasLabels{ChunkLabel}); err != nil {
+		t.Fatal(err)
+	}
+	if len(configMaps.Items) != expected {
+		t.Errorf("expected %d state ConfigMaps, got %d", expected, len(configMaps.Items))
+	}
+}
+
+func assertConfigMapExists(t *testing.T, c client.Client, name string, expected bool) {
+	t.Helper()
+
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: "thatchd", Name: name}, &corev1.ConfigMap{})
+	if expected && err != nil {
+		t.Errorf("expected ConfigMap %s to exist: %v", name, err)
+	}
+	if !expected && !errors.IsNotFound(err) {
+		t.Errorf("expected ConfigMap %s to be deleted, got %v", name, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := thatchdv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := 2
+	suite := &thatchdv1alpha2.TestSuite{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		},
+		Spec: thatchdv1alpha2.TestSuiteSpec{
+			StateHistoryLimit: &limit,
+		},
+	}
+
+	history := &History{Client: fake.NewFakeClientWithScheme(scheme, suite), Scheme: scheme}
+	ctx := context.TODO()
+
+	for _, transition := range []struct {
+		source, previous, current string
+	}{
+		{SuiteSource, "", `{"ready":false}`},
+		{SuiteSource, `{"ready":false}`, "{\n  \"ready\": false\n}"},
+		{WorkerSource("annotate"), `{"ready":false}`, `{"ready":true}`},
+		{SuiteSource, `{"ready":true}`, `{"ready":true,"pods":["a"]}`},
+	} {
+		if err := history.Record(ctx, suite, transition.source, transition.previous, transition.current); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transitions, err := history.Load(ctx, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The transition that only changes the formatting isn't recorded, and
+	// the first one is discarded by the limit
+	if len(transitions) != 2 {
+		t.Fatalf("expected 2 transitions, got %v", transitions)
+	}
+
+	if transitions[0].Source != "worker/annotate" {
+		t.Errorf("expected the worker transition first, got %s", transitions[0].Source)
+	}
+	if len(transitions[0].Diff) != 1 || transitions[0].Diff[0].Operation != "replace" || transitions[0].Diff[0].Path != "/ready" {
+		t.Errorf("unexpected diff %v", transitions[0].Diff)
+	}
+	if len(transitions[1].Diff) != 1 || transitions[1].Diff[0].Operation != "add" || transitions[1].Diff[0].Path != "/pods" {
+		t.Errorf("unexpected diff %v", transitions[1].Diff)
+	}
+}
+
+func TestHistoryLargeTransition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := thatchdv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := &thatchdv1alpha2.TestSuite{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "test-suite",
+			Namespace: "thatchd",
+		},
+	}
+
+	history := &History{Client: fake.NewFakeClientWithScheme(scheme, suite), Scheme: scheme}
+	ctx := context.TODO()
+
+	large := fmt.Sprintf(`{"logs":%q}`, strings.Repeat("x", maxHistorySize))
+	if err := history.Record(ctx, suite, SuiteSource, `{"ready":false}`, `{"ready":true}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Record(ctx, suite, SuiteSource, `{"ready":true}`, large); err != nil {
+		t.Fatal(err)
+	}
+
+	transitions, err := history.Load(ctx, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The large transition is recorded without its diff, keeping the
+	// previous transitions
+	if len(transitions) != 2 {
+		t.Fatalf("expected 2 transitions, got %d", len(transitions))
+	}
+	if !transitions[1].Truncated || len(transitions[1].Diff) != 0 {
+		t.Errorf("expected the diff of the large transition to be left out, got %d operations", len(transitions[1].Diff))
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+)
+
+// S3Store stores the files as objects in a bucket of an S3 compatible
+// endpoint, such as MinIO, under a <namespace>/<name> prefix. Requests are
+// path style and signed with AWS Signature Version 4
+type S3Store struct {
+	// Endpoint is the base URL of the service, such as http://minio:9000
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Client is the HTTP client used for the requests. Defaults to a client
+	// that times out after DefaultS3Timeout
+	Client *http.Client
+
+	// now returns the signing time, and is replaced in tests
+	now func() time.Time
+}
+
+var _ Store = &S3Store{}
+
+// DefaultS3Timeout bounds the requests of the default client, so an
+// unresponsive endpoint doesn't block the reconcile when the context has no
+// deadline
+const DefaultS3Timeout = time.Minute
+
+var defaultS3Client = &http.Client{Timeout: DefaultS3Timeout}
+
+func (s *S3Store) Save(ctx context.Context, owner Owner, name string, files map[string][]byte) (*thatchdv1alpha1.StorageReference, error) {
+	if err := validateKeys(files); err != nil {
+		return nil, err
+	}
+
+	prefix := path.Join(owner.GetNamespace(), name)
+
+	for key, value := range files {
+		if err := s.putObject(ctx, path.Join(prefix, key), value); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+	}
+
+	return &thatchdv1alpha1.StorageReference{
+		Type:     thatchdv1alpha1.StorageS3,
+		Location: path.Join(s.Bucket, prefix),
+	}, nil
+}
+
+func (s *S3Store) putObject(ctx context.Context, key string, content []byte) error {
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	endpoint.Path = path.Join("/", endpoint.Path, s.Bucket, key)
+
+	request, err := http.NewRequest(http.MethodPut, endpoint.String(), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.ContentLength = int64(len(content))
+
+	s.sign(request, content)
+
+	client := s.Client
+	if client == nil {
+		client = defaultS3Client
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("unexpected status %s: %s", response.Status, body)
+	}
+
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers to the request
+func (s *S3Store) sign(request *http.Request, content []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	timestamp := now().UTC()
+	date := timestamp.Format("20060102")
+
+	payloadHash := sha256Hex(content)
+	request.Header.Set("Host", request.URL.Host)
+	request.Header.Set("X-Amz-Date", timestamp.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headerNames := []string{}
+	for name := range request.Header {
+		headerNames = append(headerNames, strings.ToLower(name))
+	}
+	sort.Strings(headerNames)
+
+	canonicalHeaders := &strings.Builder{}
+	for _, name := range headerNames {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", name, strings.TrimSpace(request.Header.Get(name)))
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.Region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		request.Header.Get("X-Amz-Date"),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func sha256Hex(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, content string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(content))
+	return mac.Sum(nil)
+}
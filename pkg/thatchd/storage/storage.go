@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Owner is a Kubernetes object that owns the stored files
+type Owner interface {
+	v1.Object
+	runtime.Object
+}
+
+// Store persists a set of named files on behalf of an owner object. File
+// names must be valid ConfigMap keys, so they're portable between stores
+type Store interface {
+	Save(ctx context.Context, owner Owner, name string, files map[string][]byte) (*thatchdv1alpha1.StorageReference, error)
+}
+
+// Stores selects the store for each storage type
+type Stores map[thatchdv1alpha1.StorageType]Store
+
+// NewStores creates the built in stores. The Directory store is only
+// available if directory is not empty
+func NewStores(c client.Client, scheme *runtime.Scheme, directory string) Stores {
+	stores := Stores{
+		thatchdv1alpha1.StorageConfigMap: &ConfigMapStore{Client: c, Scheme: scheme},
+		thatchdv1alpha1.StorageSecret:    &SecretStore{Client: c, Scheme: scheme},
+	}
+
+	if directory != "" {
+		stores[thatchdv1alpha1.StorageDirectory] = &DirectoryStore{Path: directory}
+	}
+
+	return stores
+}
+
+// Get returns the store for the storage type, defaulting to ConfigMap
+func (s Stores) Get(storageType thatchdv1alpha1.StorageType) (Store, error) {
+	if storageType == "" {
+		storageType = thatchdv1alpha1.StorageConfigMap
+	}
+
+	store, ok := s[storageType]
+	if !ok {
+		return nil, fmt.Errorf("storage %s is not available", storageType)
+	}
+
+	return store, nil
+}
+
+// ConfigMapStore stores the files in a ConfigMap owned by the owner object,
+// so it's garbage collected with it. Existing ConfigMaps that aren't owned by
+// the owner object are left untouched
+type ConfigMapStore struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+var _ Store = &ConfigMapStore{}
+
+func (s *ConfigMapStore) Save(ctx context.Context, owner Owner, name string, files map[string][]byte) (*thatchdv1alpha1.StorageReference, error) {
+	if err := validateKeys(files); err != nil {
+		return nil, err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: owner.GetNamespace(),
+		},
+	}
+
+	if err := checkController(ctx, s.Client, &corev1.ConfigMap{}, owner, name); err != nil {
+		return nil, fmt.Errorf("failed to store ConfigMap %s: %w", name, err)
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, s.Client, configMap, func() error {
+		configMap.Data = map[string]string{}
+		configMap.BinaryData = map[string][]byte{}
+		for key, value := range files {
+			if utf8.Valid(value) {
+				configMap.Data[key] = string(value)
+			} else {
+				configMap.BinaryData[key] = value
+			}
+		}
+
+		return controllerutil.SetControllerReference(owner, configMap, s.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store ConfigMap %s: %w", name, err)
+	}
+
+	return &thatchdv1alpha1.StorageReference{
+		Type:     thatchdv1alpha1.StorageConfigMap,
+		Location: name,
+	}, nil
+}
+
+// SecretStore stores the files in a Secret owned by the owner object. Use it
+// when the files may contain sensitive information. Existing Secrets that
+// aren't owned by the owner object are left untouched
+type SecretStore struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+}
+
+var _ Store = &SecretStore{}
+
+func (s *SecretStore) Save(ctx context.Context, owner Owner, name string, files map[string][]byte) (*thatchdv1alpha1.StorageReference, error) {
+	if err := validateKeys(files); err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: owner.GetNamespace(),
+		},
+	}
+
+	if err := checkController(ctx, s.Client, &corev1.Secret{}, owner, name); err != nil {
+		return nil, fmt.Errorf("failed to store Secret %s: %w", name, err)
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, s.Client, secret, func() error {
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = files
+
+		return controllerutil.SetControllerReference(owner, secret, s.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store Secret %s: %w", name, err)
+	}
+
+	return &thatchdv1alpha1.StorageReference{
+		Type:     thatchdv1alpha1.StorageSecret,
+		Location: name,
+	}, nil
+}
+
+// DirectoryStore stores the files in a local directory, under a
+// <namespace>/<name> subdirectory. The directory is expected to be backed by
+// a PersistentVolumeClaim mounted in the manager
+type DirectoryStore struct {
+	Path string
+}
+
+var _ Store = &DirectoryStore{}
+
+func (s *DirectoryStore) Save(ctx context.Context, owner Owner, name string, files map[string][]byte) (*thatchdv1alpha1.StorageReference, error) {
+	if err := validateKeys(files); err != nil {
+		return nil, err
+	}
+
+	location := filepath.Join(owner.GetNamespace(), name)
+
+	for key, value := range files {
+		path := filepath.Join(s.Path, location, key)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", key, err)
+		}
+
+		if err := ioutil.WriteFile(path, value, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", key, err)
+		}
+	}
+
+	return &thatchdv1alpha1.StorageReference{
+		Type:     thatchdv1alpha1.StorageDirectory,
+		Location: location,
+	}, nil
+}
+
+// checkController refuses to overwrite an existing object that isn't
+// controlled by the owner, such as a ConfigMap of the application under test
+// with the same name
+func checkController(ctx context.Context, c client.Client, object Owner, owner Owner, name string) error {
+	err := c.Get(ctx, client.ObjectKey{Namespace: owner.GetNamespace(), Name: name}, object)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !v1.IsControlledBy(object, owner) {
+		return fmt.Errorf("%s already exists and isn't controlled by %s", name, owner.GetName())
+	}
+
+	return nil
+}
+
+func validateKeys(files map[string][]byte) error {
+	for key := range files {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid file name %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// Truncate limits the content of a file to the maximum size, keeping the
+// end of logs and the beginning of anything else. The cut is marked in the
+// content
+func Truncate(name string, content []byte, maxSize int) []byte {
+	if len(content) <= maxSize {
+		return content
+	}
+
+	marker := fmt.Sprintf("\n... truncated %d bytes ...\n", len(content)-maxSize)
+	if len(marker) >= maxSize {
+		return nil
+	}
+	keep := maxSize - len(marker)
+
+	if strings.HasSuffix(name, ".log") {
+		start := len(content) - keep
+		for start < len(content) && !utf8.RuneStart(content[start]) {
+			start++
+		}
+		return append([]byte(marker[1:]), content[start:]...)
+	}
+
+	end := keep
+	for end > 0 && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return append(append([]byte{}, content[:end]...), marker...)
+}
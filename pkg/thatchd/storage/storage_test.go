@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStoresOwnership(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := thatchdv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := &thatchdv1alpha1.TestCase{
+		ObjectMeta: v1.ObjectMeta{Name: "test-case", Namespace: "thatchd", UID: types.UID("test-case")},
+	}
+
+	scenarios := []struct {
+		Name    string
+		Storage thatchdv1alpha1.StorageType
+		Error   string
+	}{
+		{
+			Name:    "New ConfigMap",
+			Storage: thatchdv1alpha1.StorageConfigMap,
+		},
+		{
+			Name:    "ConfigMap of the owner",
+			Storage: thatchdv1alpha1.StorageConfigMap,
+		},
+		{
+			Name:    "New Secret",
+			Storage: thatchdv1alpha1.StorageSecret,
+		},
+		{
+			Name:    "ConfigMap of the application",
+			Storage: thatchdv1alpha1.StorageConfigMap,
+			Error:   "failed to store ConfigMap application: application already exists and isn't controlled by test-case",
+		},
+		{
+			Name:    "Secret of the application",
+			Storage: thatchdv1alpha1.StorageSecret,
+			Error:   "failed to store Secret application: application already exists and isn't controlled by test-case",
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(scheme,
+		&corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: "application", Namespace: "thatchd"},
+			Data:       map[string]string{"config": "original"},
+		},
+		&corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Name: "application", Namespace: "thatchd"},
+			Data:       map[string][]byte{"password": []byte("original")},
+		},
+	)
+	stores := NewStores(c, scheme, "")
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			store, err := stores.Get(scenario.Storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name := "test-case-artifacts"
+			if scenario.Error != "" {
+				name = "application"
+			}
+
+			_, err = store.Save(context.TODO(), owner, name, map[string][]byte{"config": []byte("stored")})
+			if scenario.Error == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if scenario.Error != "" && (err == nil || err.Error() != scenario.Error) {
+				t.Errorf("expected error %s, got %v", scenario.Error, err)
+			}
+		})
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "application", Namespace: "thatchd"}, configMap); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(configMap.Data["config"], "original") || len(configMap.OwnerReferences) > 0 {
+		t.Errorf("expected the ConfigMap of the application to be left untouched, got %v", configMap)
+	}
+}
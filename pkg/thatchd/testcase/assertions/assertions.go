@@ -0,0 +1,242 @@
+// Package assertions provides built-in TestCase strategies that assert the
+// resources of any kind, configured through the strategy configuration
+package assertions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the built-in providers
+const (
+	ResourceExistsProvider    = "ResourceExists"
+	ResourceAbsentProvider    = "ResourceAbsent"
+	FieldEqualsProvider       = "FieldEquals"
+	FieldMatchesProvider      = "FieldMatches"
+	ConditionTrueProvider     = "ConditionTrue"
+	ReplicasReadyProvider     = "ReplicasReady"
+	LabelPresentProvider      = "LabelPresent"
+	AnnotationPresentProvider = "AnnotationPresent"
+)
+
+// Register adds the built-in assertion providers to the strategy providers
+func Register(providers map[string]strategy.StrategyProvider) {
+	providers[ResourceExistsProvider] = provider(resourceExists)
+	providers[ResourceAbsentProvider] = provider(resourceAbsent)
+	providers[FieldEqualsProvider] = provider(fieldEquals)
+	providers[FieldMatchesProvider] = provider(fieldMatches)
+	providers[ConditionTrueProvider] = provider(conditionTrue)
+	providers[ReplicasReadyProvider] = provider(replicasReady)
+	providers[LabelPresentProvider] = provider(metadataPresent("label", (*unstructured.Unstructured).GetLabels))
+	providers[AnnotationPresentProvider] = provider(metadataPresent("annotation", (*unstructured.Unstructured).GetAnnotations))
+}
+
+// assertFunc asserts the resources selected by the test case target
+type assertFunc func(target *resource.Target, objects []unstructured.Unstructured, result *testcase.Result) error
+
+// assertionFactory creates the assertFunc from the strategy configuration
+type assertionFactory func(configuration map[string]string) (assertFunc, error)
+
+// assertion is a TestCase that asserts the resources selected by its target
+type assertion struct {
+	dispatch.Configured
+
+	target *resource.Target
+	assert assertFunc
+}
+
+var _ testcase.Interface = &assertion{}
+
+func provider(factory assertionFactory) strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		a := &assertion{Configured: dispatch.Configure(configuration)}
+		if a.Err != nil {
+			return a
+		}
+		if a.target, a.Err = resource.NewTarget(configuration); a.Err != nil {
+			return a
+		}
+		a.assert, a.Err = factory(configuration)
+		return a
+	})
+}
+
+func (a *assertion) Run(c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	if err := a.ConfigurationError(); err != nil {
+		return err
+	}
+
+	objects, err := a.target.List(result.Context(), c, namespace)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("asserting resources", "target", a.target.String(), "count", len(objects))
+	return a.assert(a.target, objects, result)
+}
+
+// forEach asserts each of the objects, failing if there are none
+func forEach(assert func(object *unstructured.Unstructured, result *testcase.Result) error) assertFunc {
+	return func(target *resource.Target, objects []unstructured.Unstructured, result *testcase.Result) error {
+		if !result.True("exists", len(objects) > 0, fmt.Sprintf("no %s found", target)) {
+			return nil
+		}
+
+		for i := range objects {
+			if err := assert(&objects[i], result); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func resourceExists(configuration map[string]string) (assertFunc, error) {
+	count, ok := configuration["count"]
+	if !ok {
+		return func(target *resource.Target, objects []unstructured.Unstructured, result *testcase.Result) error {
+			result.True("exists", len(objects) > 0, fmt.Sprintf("no %s found", target))
+			return nil
+		}, nil
+	}
+
+	expected, err := strconv.Atoi(count)
+	if err != nil {
+		return nil, fmt.Errorf("invalid count: %w", err)
+	}
+
+	return func(_ *resource.Target, objects []unstructured.Unstructured, result *testcase.Result) error {
+		result.Equal("count", expected, len(objects))
+		return nil
+	}, nil
+}
+
+func resourceAbsent(_ map[string]string) (assertFunc, error) {
+	return func(target *resource.Target, objects []unstructured.Unstructured, result *testcase.Result) error {
+		names := make([]string, 0, len(objects))
+		for _, object := range objects {
+			names = append(names, object.GetName())
+		}
+
+		result.True("absent", len(objects) == 0, fmt.Sprintf("found %s: %v", target, names))
+		return nil
+	}, nil
+}
+
+func fieldEquals(configuration map[string]string) (assertFunc, error) {
+	expression, value := configuration["jsonPath"], configuration["value"]
+	if expression == "" {
+		return nil, fmt.Errorf("jsonPath is required")
+	}
+
+	return forEach(func(object *unstructured.Unstructured, result *testcase.Result) error {
+		actual, err := resource.JSONPath(object, expression)
+		if err != nil {
+			return err
+		}
+
+		result.Equal(fmt.Sprintf("%s %s", object.GetName(), expression), value, actual)
+		return nil
+	}), nil
+}
+
+func fieldMatches(configuration map[string]string) (assertFunc, error) {
+	expression := configuration["jsonPath"]
+	if expression == "" {
+		return nil, fmt.Errorf("jsonPath is required")
+	}
+
+	pattern, err := regexp.Compile(configuration["pattern"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	return forEach(func(object *unstructured.Unstructured, result *testcase.Result) error {
+		actual, err := resource.JSONPath(object, expression)
+		if err != nil {
+			return err
+		}
+
+		result.True(
+			fmt.Sprintf("%s %s", object.GetName(), expression),
+			pattern.MatchString(actual),
+			fmt.Sprintf("%q doesn't match %s", actual, pattern),
+		)
+		return nil
+	}), nil
+}
+
+func conditionTrue(configuration map[string]string) (assertFunc, error) {
+	conditionType := configuration["conditionType"]
+	if conditionType == "" {
+		return nil, fmt.Errorf("conditionType is required")
+	}
+
+	status := configuration["status"]
+	if status == "" {
+		status = "True"
+	}
+
+	return forEach(func(object *unstructured.Unstructured, result *testcase.Result) error {
+		actual := resource.ConditionStatus(object, conditionType)
+		result.Equal(fmt.Sprintf("%s %s", object.GetName(), conditionType), status, actual)
+		return nil
+	}), nil
+}
+
+func replicasReady(configuration map[string]string) (assertFunc, error) {
+	replicas, err := config.Int(configuration, "replicas", -1)
+	if err != nil {
+		return nil, err
+	}
+	expected := int64(replicas)
+
+	return forEach(func(object *unstructured.Unstructured, result *testcase.Result) error {
+		// Default to the desired replicas of the resource, which default to 1
+		desired := expected
+		if desired < 0 {
+			replicas, found, _ := unstructured.NestedInt64(object.Object, "spec", "replicas")
+			desired = 1
+			if found {
+				desired = replicas
+			}
+		}
+
+		ready, _, _ := unstructured.NestedInt64(object.Object, "status", "readyReplicas")
+		result.Equal(fmt.Sprintf("%s ready replicas", object.GetName()), desired, ready)
+		return nil
+	}), nil
+}
+
+func metadataPresent(field string, get func(*unstructured.Unstructured) map[string]string) assertionFactory {
+	return func(configuration map[string]string) (assertFunc, error) {
+		key := configuration["key"]
+		if key == "" {
+			return nil, fmt.Errorf("key is required")
+		}
+		value, checkValue := configuration["value"]
+
+		return forEach(func(object *unstructured.Unstructured, result *testcase.Result) error {
+			name := fmt.Sprintf("%s %s %s", object.GetName(), field, key)
+
+			actual, ok := get(object)[key]
+			if !result.True(name, ok, fmt.Sprintf("%s %s not found", field, key)) || !checkValue {
+				return nil
+			}
+
+			result.Equal(name+" value", value, actual)
+			return nil
+		}), nil
+	}
+}
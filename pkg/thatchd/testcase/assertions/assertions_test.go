@@ -0,0 +1,143 @@
+package assertions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/resource/resourcetest"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAssertions(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Provider      string
+		Configuration map[string]string
+		Failures      []string
+		Error         string
+	}{
+		{
+			Name:          "Named resource exists",
+			Provider:      ResourceExistsProvider,
+			Configuration: map[string]string{"name": "ready"},
+		},
+		{
+			Name:          "Resource count",
+			Provider:      ResourceExistsProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "count": "3"},
+			Failures:      []string{"count: expected 3, got 2"},
+		},
+		{
+			Name:          "Resource absent",
+			Provider:      ResourceAbsentProvider,
+			Configuration: map[string]string{"name": "deleted"},
+		},
+		{
+			Name:          "Resource not absent",
+			Provider:      ResourceAbsentProvider,
+			Configuration: map[string]string{"labelSelector": "app=test"},
+			Failures:      []string{"absent: found Deployment app=test: [ready unavailable]"},
+		},
+		{
+			Name:          "Field equals",
+			Provider:      FieldEqualsProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "jsonPath": "{.spec.template.spec.containers[0].image}", "value": "app:v2"},
+			Failures:      []string{"unavailable {.spec.template.spec.containers[0].image}: expected app:v2, got app:v1"},
+		},
+		{
+			Name:          "Field matches",
+			Provider:      FieldMatchesProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "jsonPath": "{.spec.template.spec.containers[0].image}", "pattern": "^app:v[0-9]+$"},
+		},
+		{
+			Name:          "Condition true",
+			Provider:      ConditionTrueProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "conditionType": "Available"},
+			Failures:      []string{"unavailable Available: expected True, got False"},
+		},
+		{
+			Name:          "Replicas ready",
+			Provider:      ReplicasReadyProvider,
+			Configuration: map[string]string{"labelSelector": "app=test"},
+			Failures:      []string{"unavailable ready replicas: expected 1, got 0"},
+		},
+		{
+			Name:          "Label present",
+			Provider:      LabelPresentProvider,
+			Configuration: map[string]string{"name": "ready", "key": "app", "value": "test"},
+		},
+		{
+			Name:          "Annotation missing",
+			Provider:      AnnotationPresentProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "key": "owner"},
+			Failures:      []string{"unavailable annotation owner: annotation owner not found"},
+		},
+		{
+			Name:          "No resources",
+			Provider:      ConditionTrueProvider,
+			Configuration: map[string]string{"name": "deleted", "conditionType": "Available"},
+			Failures:      []string{"exists: no Deployment deleted found"},
+		},
+		{
+			Name:          "Invalid configuration",
+			Provider:      FieldEqualsProvider,
+			Configuration: map[string]string{"name": "ready"},
+			Error:         "invalid configuration: jsonPath is required",
+		},
+	}
+
+	providers := map[string]strategy.StrategyProvider{}
+	Register(providers)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			configuration := map[string]string{"apiVersion": "apps/v1", "kind": "Deployment"}
+			for key, value := range scenario.Configuration {
+				configuration[key] = value
+			}
+
+			testCase, err := testcase.FromStrategy(&strategy.Strategy{
+				Provider:      scenario.Provider,
+				Configuration: configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testCase.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test case to be dispatched")
+			}
+
+			result := testcase.NewResult()
+			err = testCase.Run(fake.NewFakeClient(resourcetest.Deployments()...), "thatchd", result, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resultErr := result.Err()
+			if len(scenario.Failures) == 0 {
+				if resultErr != nil {
+					t.Errorf("unexpected failures: %v", resultErr)
+				}
+				return
+			}
+			if resultErr == nil {
+				t.Fatalf("expected failures %v", scenario.Failures)
+			}
+			for _, failure := range scenario.Failures {
+				if !strings.Contains(resultErr.Error(), failure) {
+					t.Errorf("expected failure %s, got %v", failure, resultErr)
+				}
+			}
+		})
+	}
+}
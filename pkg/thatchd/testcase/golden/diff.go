@@ -0,0 +1,253 @@
+package golden
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// path locates a field of an object. Each segment is a key, or an index
+// written as "[0]"
+type path []string
+
+// wildcardKey and wildcardIndex match any key and any index in an ignore path
+const (
+	wildcardKey   = "*"
+	wildcardIndex = "[*]"
+)
+
+// parsePath parses a path such as spec.containers[0].image. Keys that contain
+// dots are written in brackets, such as metadata.annotations[example.com/key],
+// and * or [*] match any key or index
+func parsePath(value string) (path, error) {
+	p := path{}
+	key := ""
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '.':
+			if key != "" {
+				p = append(p, key)
+			}
+			key = ""
+		case '[':
+			if key != "" {
+				p = append(p, key)
+			}
+			key = ""
+
+			end := strings.IndexByte(value[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %s: unclosed bracket", value)
+			}
+			segment := value[i+1 : i+end]
+			if segment == "" {
+				return nil, fmt.Errorf("invalid path %s: empty brackets", value)
+			}
+			if _, err := strconv.Atoi(segment); err == nil || segment == wildcardKey {
+				segment = "[" + segment + "]"
+			}
+			p = append(p, segment)
+			i += end
+		default:
+			key += string(value[i])
+		}
+	}
+	if key != "" {
+		p = append(p, key)
+	}
+
+	if len(p) == 0 {
+		return nil, fmt.Errorf("invalid path %s: empty", value)
+	}
+
+	return p, nil
+}
+
+func (p path) child(segment string) path {
+	child := make(path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, segment)
+}
+
+func (p path) matches(pattern path) bool {
+	if len(p) != len(pattern) {
+		return false
+	}
+
+	for i, segment := range pattern {
+		index := isIndex(p[i])
+		switch {
+		case segment == wildcardKey && !index:
+		case segment == wildcardIndex && index:
+		case segment != p[i]:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p path) String() string {
+	builder := &strings.Builder{}
+	for _, segment := range p {
+		switch {
+		case isIndex(segment):
+			builder.WriteString(segment)
+		case strings.ContainsAny(segment, ".[]"):
+			builder.WriteString("[" + segment + "]")
+		default:
+			if builder.Len() > 0 {
+				builder.WriteString(".")
+			}
+			builder.WriteString(segment)
+		}
+	}
+
+	if builder.Len() == 0 {
+		return "."
+	}
+
+	return builder.String()
+}
+
+func isIndex(segment string) bool {
+	return strings.HasPrefix(segment, "[") && strings.HasSuffix(segment, "]")
+}
+
+func index(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+// comparison compares a live object to its golden manifest, field by field
+type comparison struct {
+	// exact reports the fields of the live object missing from the golden
+	// manifest, which are otherwise ignored. Null fields are the same as
+	// missing ones
+	exact  bool
+	ignore []path
+	diffs  []string
+}
+
+func (c *comparison) ignored(p path) bool {
+	for _, pattern := range c.ignore {
+		if p.matches(pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *comparison) report(p path, format string, args ...interface{}) {
+	c.diffs = append(c.diffs, fmt.Sprintf("%s: %s", p, fmt.Sprintf(format, args...)))
+}
+
+func (c *comparison) compare(p path, expected, actual interface{}) {
+	if c.ignored(p) {
+		return
+	}
+
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		actual, ok := actual.(map[string]interface{})
+		if !ok {
+			c.report(p, "expected an object, got %s", format(actual))
+			return
+		}
+
+		for _, key := range sortedKeys(expected) {
+			child := p.child(key)
+			value, found := actual[key]
+			switch {
+			case c.ignored(child):
+			case !found && expected[key] != nil:
+				c.report(child, "missing, expected %s", format(expected[key]))
+			case found:
+				c.compare(child, expected[key], value)
+			}
+		}
+
+		if !c.exact {
+			return
+		}
+		for _, key := range sortedKeys(actual) {
+			child := p.child(key)
+			if _, found := expected[key]; !found && actual[key] != nil && !c.ignored(child) {
+				c.report(child, "unexpected %s", format(actual[key]))
+			}
+		}
+	case []interface{}:
+		actual, ok := actual.([]interface{})
+		if !ok {
+			c.report(p, "expected a list, got %s", format(actual))
+			return
+		}
+		if len(expected) != len(actual) {
+			c.report(p, "expected %d items, got %d", len(expected), len(actual))
+			return
+		}
+
+		for i := range expected {
+			c.compare(p.child(index(i)), expected[i], actual[i])
+		}
+	default:
+		if !scalarEqual(expected, actual) {
+			c.report(p, "expected %s, got %s", format(expected), format(actual))
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// scalarEqual compares numbers by value, as the golden manifest and the live
+// object don't necessarily decode them to the same type
+func scalarEqual(expected, actual interface{}) bool {
+	expectedNumber, ok := number(expected)
+	if !ok {
+		return reflect.DeepEqual(expected, actual)
+	}
+
+	actualNumber, ok := number(actual)
+	return ok && expectedNumber == actualNumber
+}
+
+func number(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float32:
+		return float64(value), true
+	case float64:
+		return value, true
+	case json.Number:
+		parsed, err := value.Float64()
+		return parsed, err == nil
+	}
+
+	return 0, false
+}
+
+// format renders a value as compact JSON for the diff
+func format(value interface{}) string {
+	rendered, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(rendered)
+}
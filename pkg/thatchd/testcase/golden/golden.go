@@ -0,0 +1,247 @@
+// Package golden provides a built-in TestCase strategy that compares live
+// resources to their golden manifest, configured through the strategy
+// configuration
+package golden
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// GoldenResourceProvider is the name of the built-in provider
+const GoldenResourceProvider = "GoldenResource"
+
+// Match modes of the comparison
+const (
+	MatchSubset = "subset"
+	MatchExact  = "exact"
+)
+
+// defaultIgnore are the fields set by the API server, that are ignored in
+// golden manifests copied from kubectl get -o yaml
+var defaultIgnore = []string{
+	"metadata.uid",
+	"metadata.resourceVersion",
+	"metadata.generation",
+	"metadata.creationTimestamp",
+	"metadata.managedFields",
+	"metadata.selfLink",
+	"metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]",
+}
+
+// Register adds the built-in golden provider to the strategy providers
+func Register(providers map[string]strategy.StrategyProvider) {
+	providers[GoldenResourceProvider] = strategy.NewProviderFunction(newGoldenResource)
+}
+
+// goldenResource is a TestCase that compares the live resources to the
+// documents of a golden manifest, inline or stored in a ConfigMap
+type goldenResource struct {
+	dispatch.Configured
+
+	configuration map[string]string
+	// manifest is the inline golden manifest, otherwise it's read from the
+	// key of the ConfigMap when the test case runs
+	manifest  []*unstructured.Unstructured
+	configMap string
+	key       string
+	exact     bool
+	ignore    []path
+}
+
+var _ testcase.Interface = &goldenResource{}
+
+func newGoldenResource(configuration map[string]string) interface{} {
+	g := &goldenResource{Configured: dispatch.Configure(configuration), configuration: configuration}
+	if g.Err != nil {
+		return g
+	}
+	g.Err = g.configure(configuration)
+	return g
+}
+
+func (g *goldenResource) configure(configuration map[string]string) error {
+	inline, configMap := configuration["golden"], configuration["goldenConfigMap"]
+	switch {
+	case inline == "" && configMap == "":
+		return fmt.Errorf("golden or goldenConfigMap is required")
+	case inline != "" && configMap != "":
+		return fmt.Errorf("golden and goldenConfigMap are mutually exclusive")
+	case inline != "":
+		manifest, err := decode(inline)
+		if err != nil {
+			return err
+		}
+		g.manifest = manifest
+	}
+	g.configMap, g.key = configMap, configuration["goldenKey"]
+
+	switch configuration["match"] {
+	case "", MatchSubset:
+	case MatchExact:
+		g.exact = true
+	default:
+		return fmt.Errorf("invalid match %s, expected %s or %s", configuration["match"], MatchSubset, MatchExact)
+	}
+
+	ignore := append([]string{}, defaultIgnore...)
+	ignore = append(ignore, strings.FieldsFunc(configuration["ignore"], func(r rune) bool {
+		return r == ',' || r == '\n'
+	})...)
+	for _, value := range ignore {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+
+		p, err := parsePath(value)
+		if err != nil {
+			return fmt.Errorf("invalid ignore: %w", err)
+		}
+		g.ignore = append(g.ignore, p)
+	}
+
+	return nil
+}
+
+func (g *goldenResource) Run(c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	if err := g.ConfigurationError(); err != nil {
+		return err
+	}
+
+	ctx := result.Context()
+	manifest := g.manifest
+	if manifest == nil {
+		var err error
+		if manifest, err = g.load(ctx, c, namespace); err != nil {
+			return err
+		}
+	}
+
+	for _, document := range manifest {
+		target, err := resource.NewTarget(g.targetConfiguration(document))
+		if err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+
+		objects, err := target.List(ctx, c, namespace)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("comparing resources to golden manifest", "target", target.String(), "count", len(objects))
+		if !result.True(fmt.Sprintf("%s exists", target), len(objects) > 0, fmt.Sprintf("no %s found", target)) {
+			continue
+		}
+
+		for i := range objects {
+			if err := g.compare(target.GVK.Kind, document, &objects[i], result); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// targetConfiguration selects the live resources of a golden document. The
+// configuration takes precedence over the identity of the document, so a
+// golden manifest can be reused for resources with other names
+func (g *goldenResource) targetConfiguration(document *unstructured.Unstructured) map[string]string {
+	configuration := map[string]string{
+		resource.APIVersionKey:    document.GetAPIVersion(),
+		resource.KindKey:          document.GetKind(),
+		resource.NamespaceKey:     document.GetNamespace(),
+		resource.LabelSelectorKey: g.configuration[resource.LabelSelectorKey],
+	}
+	if configuration[resource.LabelSelectorKey] == "" {
+		configuration[resource.NameKey] = document.GetName()
+	}
+
+	for _, key := range []string{resource.APIVersionKey, resource.KindKey, resource.NameKey, resource.NamespaceKey} {
+		if value := g.configuration[key]; value != "" {
+			configuration[key] = value
+		}
+	}
+
+	return configuration
+}
+
+// compare asserts that the live object matches the golden document, and
+// attaches the live object when it doesn't. The identity of the document is
+// used to find the object, so it's not compared
+func (g *goldenResource) compare(kind string, document, object *unstructured.Unstructured, result *testcase.Result) error {
+	c := &comparison{exact: g.exact, ignore: g.ignore}
+	c.compare(path{}, withoutIdentity(document), withoutIdentity(object))
+
+	name := fmt.Sprintf("%s %s", kind, object.GetName())
+	message := fmt.Sprintf("%d fields differ from the golden manifest:\n%s", len(c.diffs), strings.Join(c.diffs, "\n"))
+	if result.True(name+" matches golden", len(c.diffs) == 0, message) {
+		return nil
+	}
+
+	live, err := yaml.Marshal(object.Object)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	return result.Attach(strings.ToLower(fmt.Sprintf("%s-%s.yaml", kind, object.GetName())), live)
+}
+
+func withoutIdentity(object *unstructured.Unstructured) map[string]interface{} {
+	content := object.DeepCopy().Object
+	unstructured.RemoveNestedField(content, "apiVersion")
+	unstructured.RemoveNestedField(content, "kind")
+	unstructured.RemoveNestedField(content, "metadata", "name")
+	unstructured.RemoveNestedField(content, "metadata", "namespace")
+
+	return content
+}
+
+// load reads the golden manifest from the key of the ConfigMap, which can be
+// omitted if it's the only one
+func (g *goldenResource) load(ctx context.Context, c client.Client, namespace string) ([]*unstructured.Unstructured, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: g.configMap}, configMap); err != nil {
+		return nil, fmt.Errorf("failed to get golden ConfigMap %s: %w", g.configMap, err)
+	}
+
+	key := g.key
+	if key == "" {
+		if len(configMap.Data) != 1 {
+			return nil, fmt.Errorf("invalid configuration: goldenKey is required, ConfigMap %s has %d keys", g.configMap, len(configMap.Data))
+		}
+		for only := range configMap.Data {
+			key = only
+		}
+	}
+
+	manifest, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in golden ConfigMap %s", key, g.configMap)
+	}
+
+	return decode(manifest)
+}
+
+func decode(manifest string) ([]*unstructured.Unstructured, error) {
+	documents, err := resource.DecodeManifest(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid golden manifest: %w", err)
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("golden manifest has no resources")
+	}
+
+	return documents, nil
+}
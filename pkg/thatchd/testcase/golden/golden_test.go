@@ -0,0 +1,205 @@
+package golden
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/resource/resourcetest"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const deploymentGolden = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+  uid: 0a1b2c3d
+  resourceVersion: "42"
+  labels:
+    app: operator
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: manager
+          image: operator:v2
+`
+
+func testObjects() []runtime.Object {
+	deployment := func(name, image string) *appsv1.Deployment {
+		deployment := resourcetest.Deployment(name, 2)
+		deployment.Labels = map[string]string{"app": "operator", "tier": "control-plane"}
+		deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "manager", Image: image}}
+		return deployment
+	}
+
+	return []runtime.Object{
+		deployment("operator", "operator:v2"),
+		deployment("outdated", "operator:v1"),
+		&corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: "golden", Namespace: resourcetest.Namespace},
+			Data:       map[string]string{"operator.yaml": deploymentGolden},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: "goldens", Namespace: resourcetest.Namespace},
+			Data:       map[string]string{"operator.yaml": deploymentGolden, "other.yaml": deploymentGolden},
+		},
+	}
+}
+
+func TestGoldenResource(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Configuration map[string]string
+		Failures      []string
+		Artifacts     []string
+		Error         string
+	}{
+		{
+			Name:          "Inline subset",
+			Configuration: map[string]string{"golden": deploymentGolden},
+		},
+		{
+			Name:          "ConfigMap with a single key",
+			Configuration: map[string]string{"goldenConfigMap": "golden"},
+		},
+		{
+			Name:          "Name overrides the golden identity",
+			Configuration: map[string]string{"golden": deploymentGolden, "name": "outdated"},
+			Failures:      []string{`spec.template.spec.containers[0].image: expected "operator:v2", got "operator:v1"`},
+			Artifacts:     []string{"deployment-outdated.yaml"},
+		},
+		{
+			Name:          "Ignored paths",
+			Configuration: map[string]string{"golden": deploymentGolden, "labelSelector": "app=operator", "ignore": "spec.template.spec.containers[*].image"},
+		},
+		{
+			Name:          "Exact match",
+			Configuration: map[string]string{"goldenConfigMap": "goldens", "goldenKey": "operator.yaml", "match": MatchExact},
+			Failures:      []string{`metadata.labels.tier: unexpected "control-plane"`},
+		},
+		{
+			Name:          "Missing fields",
+			Configuration: map[string]string{"golden": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: operator\n  annotations:\n    example.com/owner: team-a\nspec:\n  paused: true\n"},
+			Failures: []string{
+				`metadata.annotations: missing, expected {"example.com/owner":"team-a"}`,
+				"spec.paused: missing, expected true",
+			},
+		},
+		{
+			Name:          "Missing resource",
+			Configuration: map[string]string{"golden": deploymentGolden, "name": "missing"},
+			Failures:      []string{"no Deployment missing found"},
+		},
+		{
+			Name:          "ConfigMap with several keys",
+			Configuration: map[string]string{"goldenConfigMap": "goldens"},
+			Error:         "invalid configuration: goldenKey is required, ConfigMap goldens has 2 keys",
+		},
+		{
+			Name:          "Invalid match",
+			Configuration: map[string]string{"golden": deploymentGolden, "match": "partial"},
+			Error:         "invalid configuration: invalid match partial, expected subset or exact",
+		},
+	}
+
+	providers := map[string]strategy.StrategyProvider{}
+	Register(providers)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			testCase, err := testcase.FromStrategy(&strategy.Strategy{
+				Provider:      GoldenResourceProvider,
+				Configuration: scenario.Configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testCase.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test case to be dispatched")
+			}
+
+			result := testcase.NewResult()
+			err = testCase.Run(fake.NewFakeClient(testObjects()...), "thatchd", result, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, artifact := range scenario.Artifacts {
+				if _, ok := result.Artifacts()[artifact]; !ok {
+					t.Errorf("expected artifact %s", artifact)
+				}
+			}
+
+			resultErr := result.Err()
+			if len(scenario.Failures) == 0 {
+				if resultErr != nil {
+					t.Errorf("unexpected failures: %v", resultErr)
+				}
+				return
+			}
+			if resultErr == nil {
+				t.Fatalf("expected failures %v", scenario.Failures)
+			}
+			for _, failure := range scenario.Failures {
+				if !strings.Contains(resultErr.Error(), failure) {
+					t.Errorf("expected failure %s, got %v", failure, resultErr)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	scenarios := []struct {
+		Value    string
+		Expected path
+		Rendered string
+	}{
+		{Value: "spec.replicas", Expected: path{"spec", "replicas"}, Rendered: "spec.replicas"},
+		{Value: "spec.containers[0].image", Expected: path{"spec", "containers", "[0]", "image"}, Rendered: "spec.containers[0].image"},
+		{Value: "spec.containers[*].*", Expected: path{"spec", "containers", "[*]", "*"}, Rendered: "spec.containers[*].*"},
+		{
+			Value:    "metadata.annotations[example.com/owner]",
+			Expected: path{"metadata", "annotations", "example.com/owner"},
+			Rendered: "metadata.annotations[example.com/owner]",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Value, func(t *testing.T) {
+			p, err := parsePath(scenario.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p, scenario.Expected) {
+				t.Errorf("expected %v, got %v", scenario.Expected, p)
+			}
+			if p.String() != scenario.Rendered {
+				t.Errorf("expected %s, got %s", scenario.Rendered, p.String())
+			}
+		})
+	}
+
+	for _, value := range []string{"", "spec.containers[0", "spec[]"} {
+		if _, err := parsePath(value); err == nil {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package pods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/output"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// podExec runs the command in the container and asserts its exit code and
+// output. The command is a JSON array with the arguments, or a string that
+// is run with sh -c
+func podExec(exec execFunc) checkFactory {
+	return func(configuration map[string]string) (checkFunc, error) {
+		command, err := parseCommand(configuration["command"])
+		if err != nil {
+			return nil, err
+		}
+
+		exitCode, err := config.Int(configuration, "exitCode", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		stdoutMatches, err := optionalRegexp(configuration, "stdoutMatches")
+		if err != nil {
+			return nil, err
+		}
+		stderrMatches, err := optionalRegexp(configuration, "stderrMatches")
+		if err != nil {
+			return nil, err
+		}
+
+		timeout, err := config.Duration(configuration, "timeout", time.Minute)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context, pod *corev1.Pod, container string, result *testcase.Result, logger logr.Logger) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			logger.Info("running command", "command", command)
+			stdout, stderr := output.NewTailWriter(maxOutput), output.NewTailWriter(maxOutput)
+			code, err := exec(ctx, pod, container, command, stdout, stderr)
+			if err != nil {
+				return fmt.Errorf("failed to run command in %s/%s: %w", pod.Name, container, err)
+			}
+
+			for suffix, writer := range map[string]*output.TailWriter{"stdout": stdout, "stderr": stderr} {
+				if writer.Len() > 0 {
+					if err := result.Attach(artifactName(pod, container, suffix), writer.Bytes()); err != nil {
+						return err
+					}
+				}
+			}
+
+			result.Equal(fmt.Sprintf("%s exit code", pod.Name), exitCode, code)
+			if stdoutMatches != nil {
+				result.True(fmt.Sprintf("%s stdout", pod.Name), stdoutMatches.Match(stdout.Bytes()),
+					fmt.Sprintf("stdout doesn't match %s", stdoutMatches))
+			}
+			if stderrMatches != nil {
+				result.True(fmt.Sprintf("%s stderr", pod.Name), stderrMatches.Match(stderr.Bytes()),
+					fmt.Sprintf("stderr doesn't match %s", stderrMatches))
+			}
+
+			return nil
+		}, nil
+	}
+}
+
+// maxOutput is the size of the end of the output of a command that is kept
+const maxOutput = 64 * 1024
+
+func parseCommand(command string) ([]string, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	if !strings.HasPrefix(command, "[") {
+		return []string{"sh", "-c", command}, nil
+	}
+
+	result := []string{}
+	if err := json.Unmarshal([]byte(command), &result); err != nil {
+		return nil, fmt.Errorf("invalid command: %w", err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	return result, nil
+}
+
+func optionalRegexp(configuration map[string]string, key string) (*regexp.Regexp, error) {
+	pattern, ok := configuration[key]
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return result, nil
+}
+
+// newExec returns the execFunc that runs the commands through the API
+// server. Commands that exit with a non-zero code aren't errors
+func newExec(config *rest.Config, clientset kubernetes.Interface) execFunc {
+	return func(ctx context.Context, pod *corev1.Pod, container string, command []string, stdout, stderr io.Writer) (int, error) {
+		request := clientset.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Namespace(pod.Namespace).
+			Name(pod.Name).
+			SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: container,
+				Command:   command,
+				Stdout:    true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+
+		transport, upgrader, err := spdy.RoundTripperFor(config)
+		if err != nil {
+			return 0, err
+		}
+
+		// The stream can't be canceled, so its connection is closed when the
+		// context is done, which ends the stream
+		executor, err := remotecommand.NewSPDYExecutorForTransports(
+			&contextRoundTripper{RoundTripper: transport, ctx: ctx},
+			&contextUpgrader{Upgrader: upgrader, ctx: ctx},
+			"POST", request.URL())
+		if err != nil {
+			return 0, err
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- executor.Stream(remotecommand.StreamOptions{
+				Stdout: stdout,
+				Stderr: stderr,
+			})
+		}()
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case err = <-done:
+		}
+
+		if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+			return exitErr.ExitStatus(), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		return 0, nil
+	}
+}
+
+// contextRoundTripper sends the requests with a context, so the connection
+// attempt of a stream is canceled with it
+type contextRoundTripper struct {
+	http.RoundTripper
+	ctx context.Context
+}
+
+func (t *contextRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return t.RoundTripper.RoundTrip(request.WithContext(t.ctx))
+}
+
+// contextUpgrader closes the connections it upgrades once the context is
+// done
+type contextUpgrader struct {
+	spdy.Upgrader
+	ctx context.Context
+}
+
+func (u *contextUpgrader) NewConnection(response *http.Response) (httpstream.Connection, error) {
+	connection, err := u.Upgrader.NewConnection(response)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-u.ctx.Done():
+			connection.Close()
+		case <-connection.CloseChan():
+		}
+	}()
+
+	return connection, nil
+}
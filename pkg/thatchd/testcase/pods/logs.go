@@ -0,0 +1,138 @@
+package pods
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/output"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxLogLine is the longest log line that is matched
+const maxLogLine = 1024 * 1024
+
+// containerLogs asserts that the logs of the container have a line matching
+// a pattern, and none matching another. The logs since the since duration
+// are read, followed for the window duration if set
+func containerLogs(logs logsFunc) checkFactory {
+	return func(configuration map[string]string) (checkFunc, error) {
+		matches, err := optionalRegexp(configuration, "matches")
+		if err != nil {
+			return nil, err
+		}
+		notMatches, err := optionalRegexp(configuration, "notMatches")
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil && notMatches == nil {
+			return nil, fmt.Errorf("matches or notMatches is required")
+		}
+
+		since, err := config.Duration(configuration, "since", 0)
+		if err != nil {
+			return nil, err
+		}
+		window, err := config.Duration(configuration, "window", 0)
+		if err != nil {
+			return nil, err
+		}
+		previous, err := config.Bool(configuration, "previous")
+		if err != nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context, pod *corev1.Pod, container string, result *testcase.Result, logger logr.Logger) error {
+			options := &corev1.PodLogOptions{
+				Container: container,
+				Previous:  previous,
+				Follow:    window > 0,
+			}
+			if since > 0 {
+				sinceSeconds := int64(since.Seconds())
+				options.SinceSeconds = &sinceSeconds
+			}
+
+			var cancel context.CancelFunc
+			if window > 0 {
+				ctx, cancel = context.WithTimeout(ctx, window)
+			} else {
+				ctx, cancel = context.WithCancel(ctx)
+			}
+			defer cancel()
+
+			logger.Info("reading logs", "window", window)
+			stream, err := logs(ctx, pod, options)
+			if err != nil {
+				return fmt.Errorf("failed to stream logs of %s/%s: %w", pod.Name, container, err)
+			}
+			defer stream.Close()
+
+			// Closing the stream stops the scanner once the window ends
+			go func() {
+				<-ctx.Done()
+				stream.Close()
+			}()
+
+			found, forbidden, content, err := scanLogs(stream, matches, notMatches)
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("failed to read logs of %s/%s: %w", pod.Name, container, err)
+			}
+
+			if err := result.Attach(artifactName(pod, container, "log"), content); err != nil {
+				return err
+			}
+
+			if matches != nil {
+				result.True(fmt.Sprintf("%s logs match", pod.Name), found,
+					fmt.Sprintf("no line matches %s", matches))
+			}
+			if notMatches != nil {
+				result.True(fmt.Sprintf("%s logs don't match", pod.Name), forbidden == "",
+					fmt.Sprintf("line matches %s: %s", notMatches, forbidden))
+			}
+
+			return nil
+		}, nil
+	}
+}
+
+// scanLogs reads the log lines until a line matches notMatches, or one
+// matches matches and there's no notMatches pattern to keep checking. Only
+// the last lines read are returned
+func scanLogs(stream io.Reader, matches, notMatches *regexp.Regexp) (bool, string, []byte, error) {
+	content := output.NewBuffer(0)
+	found := false
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		content.Write(line)
+
+		if notMatches != nil && notMatches.MatchString(line) {
+			return found, line, content.Bytes(), nil
+		}
+		if matches != nil && matches.MatchString(line) {
+			found = true
+			if notMatches == nil {
+				break
+			}
+		}
+	}
+
+	return found, "", content.Bytes(), scanner.Err()
+}
+
+// newLogs returns the logsFunc that streams the logs from the API server
+func newLogs(clientset kubernetes.Interface) logsFunc {
+	return func(ctx context.Context, pod *corev1.Pod, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+		return clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Stream(ctx)
+	}
+}
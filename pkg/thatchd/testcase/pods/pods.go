@@ -0,0 +1,160 @@
+// Package pods provides built-in TestCase strategies that assert the
+// commands run in the containers of a pod, and the logs of the containers,
+// configured through the strategy configuration
+package pods
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the built-in providers
+const (
+	PodExecProvider       = "PodExec"
+	ContainerLogsProvider = "ContainerLogs"
+)
+
+// execFunc runs the command in the container of the pod, writing its output
+// to stdout and stderr, and returns its exit code
+type execFunc func(ctx context.Context, pod *corev1.Pod, container string, command []string, stdout, stderr io.Writer) (int, error)
+
+// logsFunc streams the logs of the container of the pod
+type logsFunc func(ctx context.Context, pod *corev1.Pod, options *corev1.PodLogOptions) (io.ReadCloser, error)
+
+// Register adds the built-in pod providers to the strategy providers. The
+// config and clientset are used to exec commands and stream logs
+func Register(providers map[string]strategy.StrategyProvider, config *rest.Config, clientset kubernetes.Interface) {
+	register(providers, newExec(config, clientset), newLogs(clientset))
+}
+
+func register(providers map[string]strategy.StrategyProvider, exec execFunc, logs logsFunc) {
+	providers[PodExecProvider] = provider(podExec(exec))
+	providers[ContainerLogsProvider] = provider(containerLogs(logs))
+}
+
+// checkFunc asserts a container of a pod
+type checkFunc func(ctx context.Context, pod *corev1.Pod, container string, result *testcase.Result, logger logr.Logger) error
+
+// checkFactory parses the configuration of the container check
+type checkFactory func(configuration map[string]string) (checkFunc, error)
+
+// podCheck is a TestCase that asserts a container of each of the selected
+// pods
+type podCheck struct {
+	dispatch.Configured
+
+	name      string
+	namespace string
+	selector  labels.Selector
+	container string
+	check     checkFunc
+}
+
+var _ testcase.Interface = &podCheck{}
+
+func provider(factory checkFactory) strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		p := &podCheck{
+			Configured: dispatch.Configure(configuration),
+			name:       configuration["pod"],
+			namespace:  configuration["namespace"],
+			container:  configuration["container"],
+			selector:   labels.Everything(),
+		}
+		if p.Err != nil {
+			return p
+		}
+
+		switch {
+		case p.name != "" && configuration["labelSelector"] != "":
+			p.Err = fmt.Errorf("only one of pod and labelSelector can be set")
+			return p
+		case configuration["labelSelector"] != "":
+			if p.selector, p.Err = labels.Parse(configuration["labelSelector"]); p.Err != nil {
+				p.Err = fmt.Errorf("invalid labelSelector: %w", p.Err)
+				return p
+			}
+		case p.name == "":
+			p.Err = fmt.Errorf("pod or labelSelector is required")
+			return p
+		}
+
+		p.check, p.Err = factory(configuration)
+		return p
+	})
+}
+
+func (p *podCheck) Run(c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	if err := p.ConfigurationError(); err != nil {
+		return err
+	}
+
+	ctx := result.Context()
+	if p.namespace != "" {
+		namespace = p.namespace
+	}
+
+	pods, err := p.pods(ctx, c, namespace)
+	if err != nil {
+		return err
+	}
+	if !result.True("exists", len(pods) > 0, "no pods found") {
+		return nil
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+
+		container := p.container
+		if container == "" && len(pod.Spec.Containers) > 0 {
+			container = pod.Spec.Containers[0].Name
+		}
+
+		if err := p.check(ctx, pod, container, result, logger.WithValues("pod", pod.Name, "container", container)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pods returns the pod with the configured name, or the ones matching the
+// label selector
+func (p *podCheck) pods(ctx context.Context, c client.Client, namespace string) ([]corev1.Pod, error) {
+	if p.name != "" {
+		pod := &corev1.Pod{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: p.name}, pod); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to get pod %s: %w", p.name, err)
+		}
+
+		return []corev1.Pod{*pod}, nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: p.selector}); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	return podList.Items, nil
+}
+
+// artifactName returns the name of an artifact of the container of the pod
+func artifactName(pod *corev1.Pod, container, suffix string) string {
+	return fmt.Sprintf("%s.%s.%s", pod.Name, container, suffix)
+}
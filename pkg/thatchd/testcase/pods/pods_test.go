@@ -0,0 +1,211 @@
+package pods
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/resource/resourcetest"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testPods() []runtime.Object {
+	pod := func(name string) *corev1.Pod {
+		pod := resourcetest.Pod(name)
+		pod.Spec.Containers = []corev1.Container{{Name: "app"}, {Name: "sidecar"}}
+		return pod
+	}
+
+	return []runtime.Object{pod("web-0"), pod("web-1")}
+}
+
+// testExec runs the commands by echoing them, exiting with the code of
+// "exit <code>" commands
+func testExec(_ context.Context, pod *corev1.Pod, container string, command []string, stdout, stderr io.Writer) (int, error) {
+	line := strings.Join(command, " ")
+	if strings.HasPrefix(line, "sh -c exit ") {
+		fmt.Fprintf(stderr, "exiting in %s", pod.Name)
+		code := 0
+		fmt.Sscan(strings.TrimPrefix(line, "sh -c exit "), &code)
+		return code, nil
+	}
+
+	fmt.Fprintf(stdout, "%s/%s: %s", pod.Name, container, line)
+	return 0, nil
+}
+
+// testLogs streams the logs of the container, followed by a panic in web-1
+// when following them
+func testLogs(ctx context.Context, pod *corev1.Pod, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+	logs := fmt.Sprintf("starting %s\nready\n", options.Container)
+	if !options.Follow {
+		return ioutil.NopCloser(strings.NewReader(logs)), nil
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		fmt.Fprint(writer, logs)
+		time.Sleep(10 * time.Millisecond)
+		if pod.Name == "web-1" {
+			fmt.Fprintln(writer, "panic: nil pointer")
+		}
+		<-ctx.Done()
+		writer.Close()
+	}()
+
+	return reader, nil
+}
+
+func TestPods(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Provider      string
+		Configuration map[string]string
+		Failures      []string
+		Artifacts     []string
+		Error         string
+	}{
+		{
+			Name:          "Exec a command",
+			Provider:      PodExecProvider,
+			Configuration: map[string]string{"pod": "web-0", "container": "sidecar", "command": "cat /etc/config", "stdoutMatches": "^web-0/sidecar: sh -c cat"},
+			Artifacts:     []string{"web-0.sidecar.stdout"},
+		},
+		{
+			Name:          "Exec an argument list",
+			Provider:      PodExecProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "command": `["ls", "-l"]`, "stdoutMatches": "app: ls -l$"},
+			Artifacts:     []string{"web-0.app.stdout", "web-1.app.stdout"},
+		},
+		{
+			Name:          "Exit code",
+			Provider:      PodExecProvider,
+			Configuration: map[string]string{"pod": "web-1", "command": "exit 3", "stderrMatches": "exiting"},
+			Failures:      []string{"web-1 exit code: expected 0, got 3"},
+			Artifacts:     []string{"web-1.app.stderr"},
+		},
+		{
+			Name:          "Expected exit code",
+			Provider:      PodExecProvider,
+			Configuration: map[string]string{"pod": "web-1", "command": "exit 3", "exitCode": "3"},
+		},
+		{
+			Name:          "Missing pod",
+			Provider:      PodExecProvider,
+			Configuration: map[string]string{"pod": "web-2", "command": "ls"},
+			Failures:      []string{"exists: no pods found"},
+		},
+		{
+			Name:          "Logs match",
+			Provider:      ContainerLogsProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "container": "sidecar", "matches": "starting sidecar", "notMatches": "panic"},
+			Artifacts:     []string{"web-0.sidecar.log", "web-1.sidecar.log"},
+		},
+		{
+			Name:          "Logs don't match",
+			Provider:      ContainerLogsProvider,
+			Configuration: map[string]string{"pod": "web-0", "matches": "stopping"},
+			Failures:      []string{"web-0 logs match: no line matches stopping"},
+		},
+		{
+			Name:          "Logs match within the window",
+			Provider:      ContainerLogsProvider,
+			Configuration: map[string]string{"pod": "web-1", "matches": "panic", "window": "1s"},
+		},
+		{
+			Name:          "Forbidden line within the window",
+			Provider:      ContainerLogsProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "notMatches": "panic", "window": "50ms"},
+			Failures:      []string{"web-1 logs don't match: line matches panic: panic: nil pointer"},
+			Artifacts:     []string{"web-0.app.log", "web-1.app.log"},
+		},
+		{
+			Name:          "Invalid configuration",
+			Provider:      ContainerLogsProvider,
+			Configuration: map[string]string{"pod": "web-0"},
+			Error:         "invalid configuration: matches or notMatches is required",
+		},
+	}
+
+	providers := map[string]strategy.StrategyProvider{}
+	register(providers, testExec, testLogs)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			testCase, err := testcase.FromStrategy(&strategy.Strategy{
+				Provider:      scenario.Provider,
+				Configuration: scenario.Configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testCase.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test case to be dispatched")
+			}
+
+			result := testcase.NewResult()
+			err = testCase.Run(fake.NewFakeClient(testPods()...), "thatchd", result, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			artifacts := result.Artifacts()
+			for _, name := range scenario.Artifacts {
+				if _, ok := artifacts[name]; !ok {
+					t.Errorf("expected artifact %s, got %v", name, artifacts)
+				}
+			}
+
+			resultErr := result.Err()
+			if len(scenario.Failures) == 0 {
+				if resultErr != nil {
+					t.Errorf("unexpected failures: %v", resultErr)
+				}
+				return
+			}
+			if resultErr == nil {
+				t.Fatalf("expected failures %v", scenario.Failures)
+			}
+			for _, failure := range scenario.Failures {
+				if !strings.Contains(resultErr.Error(), failure) {
+					t.Errorf("expected failure %s, got %v", failure, resultErr)
+				}
+			}
+		})
+	}
+}
+
+func TestScanLogsBounded(t *testing.T) {
+	lines := &strings.Builder{}
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(lines, "line %d\n", i)
+	}
+
+	found, _, content, err := scanLogs(strings.NewReader(lines.String()), nil, regexp.MustCompile("panic:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Errorf("expected no line to match")
+	}
+	if !strings.HasPrefix(string(content), "... 1000 lines discarded\nline 1000\n") || !strings.HasSuffix(string(content), "line 1999\n") {
+		t.Errorf("expected the last lines to be kept, got %q...", string(content)[:50])
+	}
+}
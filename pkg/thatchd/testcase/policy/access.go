@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resourceAttributes is a resource to review the access to, in the
+// kubectl notation: resource.group/subresource
+type resourceAttributes struct {
+	resource    string
+	group       string
+	subresource string
+}
+
+func parseResources(value string) ([]resourceAttributes, error) {
+	result := []resourceAttributes{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		attributes := resourceAttributes{}
+		if i := strings.Index(item, "/"); i >= 0 {
+			item, attributes.subresource = item[:i], item[i+1:]
+		}
+		if i := strings.Index(item, "."); i >= 0 {
+			item, attributes.group = item[:i], item[i+1:]
+		}
+		attributes.resource = item
+
+		result = append(result, attributes)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("resources is required")
+	}
+
+	return result, nil
+}
+
+func (a resourceAttributes) String() string {
+	result := a.resource
+	if a.group != "" {
+		result += "." + a.group
+	}
+	if a.subresource != "" {
+		result += "/" + a.subresource
+	}
+
+	return result
+}
+
+// accessReview issues a SubjectAccessReview for each of the verbs on each of
+// the resources, asserting whether the ServiceAccount is allowed to perform
+// them
+func accessReview(configuration map[string]string) (checkFunc, error) {
+	serviceAccountName := configuration["serviceAccount"]
+	if serviceAccountName == "" {
+		return nil, fmt.Errorf("serviceAccount is required")
+	}
+
+	verbs := []string{}
+	for _, verb := range strings.Split(configuration["verbs"], ",") {
+		if verb = strings.TrimSpace(verb); verb != "" {
+			verbs = append(verbs, verb)
+		}
+	}
+	if len(verbs) == 0 {
+		return nil, fmt.Errorf("verbs is required")
+	}
+
+	resources, err := parseResources(configuration["resources"])
+	if err != nil {
+		return nil, err
+	}
+
+	clusterScoped, err := config.Bool(configuration, "clusterScoped")
+	if err != nil {
+		return nil, err
+	}
+
+	expectAllowed, err := allowed(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+		serviceAccountNamespace := configuration["serviceAccountNamespace"]
+		if serviceAccountNamespace == "" {
+			serviceAccountNamespace = namespace
+		}
+		user := fmt.Sprintf("system:serviceaccount:%s:%s", serviceAccountNamespace, serviceAccountName)
+
+		resourceNamespace := configuration["namespace"]
+		if resourceNamespace == "" && !clusterScoped {
+			resourceNamespace = namespace
+		}
+
+		for _, resource := range resources {
+			for _, verb := range verbs {
+				review := &authorizationv1.SubjectAccessReview{
+					Spec: authorizationv1.SubjectAccessReviewSpec{
+						User:   user,
+						Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + serviceAccountNamespace, "system:authenticated"},
+						ResourceAttributes: &authorizationv1.ResourceAttributes{
+							Namespace:   resourceNamespace,
+							Verb:        verb,
+							Group:       resource.group,
+							Resource:    resource.resource,
+							Subresource: resource.subresource,
+							Name:        configuration["name"],
+						},
+					},
+				}
+
+				logger.Info("reviewing access", "user", user, "verb", verb, "resource", resource.String())
+				if err := c.Create(ctx, review); err != nil {
+					return fmt.Errorf("failed to review access of %s: %w", user, err)
+				}
+
+				message := fmt.Sprintf("expected to be %s, was %s", describe(expectAllowed), describe(review.Status.Allowed))
+				if review.Status.Reason != "" {
+					message = fmt.Sprintf("%s: %s", message, review.Status.Reason)
+				}
+				result.True(fmt.Sprintf("%s %s", verb, resource), review.Status.Allowed == expectAllowed, message)
+			}
+		}
+
+		return nil
+	}, nil
+}
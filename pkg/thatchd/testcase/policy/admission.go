@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Operations that are dry run
+const (
+	OperationCreate = "Create"
+	OperationUpdate = "Update"
+)
+
+// admissionDryRun sends the objects of the manifest to the API server with
+// a server-side dry run, so they go through the admission webhooks and the
+// validation without being persisted, and asserts whether they're allowed
+func admissionDryRun(configuration map[string]string) (checkFunc, error) {
+	if configuration["manifest"] == "" {
+		return nil, fmt.Errorf("manifest is required")
+	}
+
+	objects, err := resource.DecodeManifest(configuration["manifest"])
+	if err != nil {
+		return nil, err
+	}
+
+	operation := configuration["operation"]
+	if operation == "" {
+		operation = OperationCreate
+	}
+	if operation != OperationCreate && operation != OperationUpdate {
+		return nil, fmt.Errorf("unsupported operation %s", operation)
+	}
+
+	expectAllowed, err := allowed(configuration)
+	if err != nil {
+		return nil, err
+	}
+	message := configuration["message"]
+
+	return func(ctx context.Context, c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+		for _, object := range objects {
+			object := object.DeepCopy()
+			if object.GetNamespace() == "" {
+				object.SetNamespace(namespace)
+			}
+			name := fmt.Sprintf("%s %s %s", operation, object.GetKind(), object.GetName())
+
+			logger.Info("dry running", "operation", operation, "kind", object.GetKind(), "name", object.GetName())
+			err := dryRun(ctx, c, operation, object)
+
+			if err != nil && !denied(err) {
+				return fmt.Errorf("failed to dry run %s: %w", name, err)
+			}
+
+			if !result.True(name, (err == nil) == expectAllowed, dryRunMessage(expectAllowed, err)) {
+				continue
+			}
+			if err != nil && message != "" {
+				result.True(fmt.Sprintf("%s message", name), strings.Contains(err.Error(), message),
+					fmt.Sprintf("expected message containing %q, got %q", message, err.Error()))
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+func dryRun(ctx context.Context, c client.Client, operation string, object *unstructured.Unstructured) error {
+	if operation == OperationCreate {
+		return c.Create(ctx, object, client.DryRunAll)
+	}
+
+	// Updates are made on the current version of the object
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(object.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKey{Namespace: object.GetNamespace(), Name: object.GetName()}, current); err != nil {
+		return err
+	}
+	object.SetResourceVersion(current.GetResourceVersion())
+
+	return c.Update(ctx, object, client.DryRunAll)
+}
+
+// denied returns whether the error is a rejection by the validation or an
+// admission webhook. Other errors, such as a missing object, an unavailable
+// API server or the manager not being allowed to make the request, aren't
+// denials
+func denied(err error) bool {
+	if errors.IsInvalid(err) {
+		return true
+	}
+
+	// Webhooks can deny with any status code. Other forbidden errors, such as
+	// the RBAC ones, are returned before the request reaches the admission
+	return strings.Contains(err.Error(), "admission webhook") && strings.Contains(err.Error(), "denied the request")
+}
+
+func dryRunMessage(expectAllowed bool, err error) string {
+	if expectAllowed {
+		return fmt.Sprintf("expected to be allowed, was denied: %v", err)
+	}
+
+	return "expected to be denied, was allowed"
+}
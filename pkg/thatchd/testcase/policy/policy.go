@@ -0,0 +1,86 @@
+// Package policy provides built-in TestCase strategies that assert the
+// admission webhooks and the RBAC rules in the cluster, configured through
+// the strategy configuration
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the built-in providers
+const (
+	AdmissionDryRunProvider = "AdmissionDryRun"
+	AccessReviewProvider    = "AccessReview"
+)
+
+// Register adds the built-in policy providers to the strategy providers
+func Register(providers map[string]strategy.StrategyProvider) {
+	providers[AdmissionDryRunProvider] = provider(admissionDryRun)
+	providers[AccessReviewProvider] = provider(accessReview)
+}
+
+// checkFunc asserts the policies in the namespace of the test
+type checkFunc func(ctx context.Context, c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error
+
+// checkFactory parses the configuration of the policy check
+type checkFactory func(configuration map[string]string) (checkFunc, error)
+
+// policyCheck is a TestCase that asserts a policy
+type policyCheck struct {
+	dispatch.Configured
+
+	check checkFunc
+}
+
+var _ testcase.Interface = &policyCheck{}
+
+func provider(factory checkFactory) strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		p := &policyCheck{Configured: dispatch.Configure(configuration)}
+		if p.Err != nil {
+			return p
+		}
+		p.check, p.Err = factory(configuration)
+		return p
+	})
+}
+
+func (p *policyCheck) Run(c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	if err := p.ConfigurationError(); err != nil {
+		return err
+	}
+
+	return p.check(result.Context(), c, namespace, result, logger)
+}
+
+// allowed returns the expected outcome, allowed unless configured otherwise
+func allowed(configuration map[string]string) (bool, error) {
+	value, ok := configuration["allowed"]
+	if !ok {
+		return true, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid allowed: %w", err)
+	}
+
+	return parsed, nil
+}
+
+// describe returns the word for the outcome, for the assertion messages
+func describe(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+
+	return "denied"
+}
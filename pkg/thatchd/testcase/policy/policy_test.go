@@ -0,0 +1,236 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// policyClient simulates the policies of the API server: a webhook denies
+// the ConfigMaps with a forbidden key, only the operator ServiceAccount can
+// get and list Deployments, and authenticated users can get Namespaces
+type policyClient struct {
+	client.Client
+}
+
+func (c *policyClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	switch object := obj.(type) {
+	case *authorizationv1.SubjectAccessReview:
+		attributes := object.Spec.ResourceAttributes
+		authenticated := false
+		for _, group := range object.Spec.Groups {
+			authenticated = authenticated || group == "system:authenticated"
+		}
+		object.Status.Allowed = object.Spec.User == "system:serviceaccount:thatchd:operator" &&
+			attributes.Namespace == "thatchd" &&
+			attributes.Group == "apps" && attributes.Resource == "deployments" &&
+			(attributes.Verb == "get" || attributes.Verb == "list") ||
+			authenticated && attributes.Resource == "namespaces" && attributes.Verb == "get"
+		if !object.Status.Allowed {
+			object.Status.Reason = "no RBAC policy matched"
+		}
+		return nil
+	case *unstructured.Unstructured:
+		createOptions := &client.CreateOptions{}
+		createOptions.ApplyOptions(opts)
+		return admit(object, createOptions.DryRun)
+	}
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *policyClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	updateOptions := &client.UpdateOptions{}
+	updateOptions.ApplyOptions(opts)
+	return admit(obj.(*unstructured.Unstructured), updateOptions.DryRun)
+}
+
+func admit(object *unstructured.Unstructured, dryRun []string) error {
+	if len(dryRun) == 0 {
+		return fmt.Errorf("expected a dry run")
+	}
+
+	if _, found, _ := unstructured.NestedString(object.Object, "data", "forbidden"); found {
+		return errors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, object.GetName(),
+			fmt.Errorf(`admission webhook "validate.example.com" denied the request: forbidden key`))
+	}
+	if _, found, _ := unstructured.NestedString(object.Object, "data", "invalid"); found {
+		return errors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, object.GetName(), field.ErrorList{
+			field.Invalid(field.NewPath("data", "invalid"), "value", "invalid key"),
+		})
+	}
+	if _, found, _ := unstructured.NestedString(object.Object, "data", "unauthorized"); found {
+		return errors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, object.GetName(),
+			fmt.Errorf(`User "system:serviceaccount:thatchd:default" cannot create resource "configmaps" in API group "" in the namespace "thatchd"`))
+	}
+	if _, found, _ := unstructured.NestedString(object.Object, "data", "unavailable"); found {
+		return errors.NewServiceUnavailable("the server is currently unable to handle the request")
+	}
+
+	return nil
+}
+
+func TestPolicy(t *testing.T) {
+	configMap := func(key string) string {
+		return fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  %s: value\n", key)
+	}
+
+	scenarios := []struct {
+		Name          string
+		Provider      string
+		Configuration map[string]string
+		Failures      []string
+		Error         string
+	}{
+		{
+			Name:          "Create allowed",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("allowed")},
+		},
+		{
+			Name:          "Create denied",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("forbidden"), "allowed": "false", "message": "forbidden key"},
+		},
+		{
+			Name:          "Create denied with another message",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("forbidden"), "allowed": "false", "message": "invalid key"},
+			Failures:      []string{`Create ConfigMap settings message: expected message containing "invalid key"`},
+		},
+		{
+			Name:          "Update unexpectedly denied",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("forbidden"), "operation": "Update"},
+			Failures:      []string{"Update ConfigMap settings: expected to be allowed, was denied"},
+		},
+		{
+			Name:          "Create unexpectedly allowed",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("allowed"), "allowed": "false"},
+			Failures:      []string{"Create ConfigMap settings: expected to be denied, was allowed"},
+		},
+		{
+			Name:          "Create invalid",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("invalid"), "allowed": "false", "message": "invalid key"},
+		},
+		{
+			Name:          "Create not authorized",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("unauthorized"), "allowed": "false"},
+			Error:         `failed to dry run Create ConfigMap settings: configmaps "settings" is forbidden: User "system:serviceaccount:thatchd:default" cannot create resource "configmaps" in API group "" in the namespace "thatchd"`,
+		},
+		{
+			Name:          "Unavailable API server",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": configMap("unavailable"), "allowed": "false"},
+			Error:         "failed to dry run Create ConfigMap settings: the server is currently unable to handle the request",
+		},
+		{
+			Name:          "Update of a missing object",
+			Provider:      AdmissionDryRunProvider,
+			Configuration: map[string]string{"manifest": strings.Replace(configMap("allowed"), "settings", "missing", 1), "operation": "Update", "allowed": "false"},
+			Error:         `failed to dry run Update ConfigMap missing: configmaps "missing" not found`,
+		},
+		{
+			Name:          "Access allowed",
+			Provider:      AccessReviewProvider,
+			Configuration: map[string]string{"serviceAccount": "operator", "verbs": "get, list", "resources": "deployments.apps"},
+		},
+		{
+			Name:          "Access denied",
+			Provider:      AccessReviewProvider,
+			Configuration: map[string]string{"serviceAccount": "default", "verbs": "get", "resources": "deployments.apps,pods/log", "allowed": "false"},
+		},
+		{
+			Name:          "Access allowed to authenticated users",
+			Provider:      AccessReviewProvider,
+			Configuration: map[string]string{"serviceAccount": "default", "verbs": "get", "resources": "namespaces", "clusterScoped": "true"},
+		},
+		{
+			Name:          "Access unexpectedly denied",
+			Provider:      AccessReviewProvider,
+			Configuration: map[string]string{"serviceAccount": "operator", "verbs": "list,delete", "resources": "deployments.apps"},
+			Failures:      []string{"delete deployments.apps: expected to be allowed, was denied: no RBAC policy matched"},
+		},
+		{
+			Name:          "Cluster scoped access",
+			Provider:      AccessReviewProvider,
+			Configuration: map[string]string{"serviceAccount": "operator", "verbs": "list", "resources": "deployments.apps", "clusterScoped": "true"},
+			Failures:      []string{"list deployments.apps: expected to be allowed, was denied"},
+		},
+		{
+			Name:          "Invalid configuration",
+			Provider:      AccessReviewProvider,
+			Configuration: map[string]string{"serviceAccount": "operator", "resources": "deployments.apps"},
+			Error:         "invalid configuration: verbs is required",
+		},
+	}
+
+	providers := map[string]strategy.StrategyProvider{}
+	Register(providers)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			testCase, err := testcase.FromStrategy(&strategy.Strategy{
+				Provider:      scenario.Provider,
+				Configuration: scenario.Configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testCase.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test case to be dispatched")
+			}
+
+			c := &policyClient{fake.NewFakeClient(&corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{Name: "settings", Namespace: "thatchd"},
+			})}
+
+			result := testcase.NewResult()
+			err = testCase.Run(c, "thatchd", result, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resultErr := result.Err()
+			if len(scenario.Failures) == 0 {
+				if resultErr != nil {
+					t.Errorf("unexpected failures: %v", resultErr)
+				}
+				return
+			}
+			if resultErr == nil {
+				t.Fatalf("expected failures %v", scenario.Failures)
+			}
+			for _, failure := range scenario.Failures {
+				if !strings.Contains(resultErr.Error(), failure) {
+					t.Errorf("expected failure %s, got %v", failure, resultErr)
+				}
+			}
+		})
+	}
+}
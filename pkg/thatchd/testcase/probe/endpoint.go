@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// endpoint is the Service or Pod port that is probed
+type endpoint struct {
+	kind      string
+	name      string
+	namespace string
+	port      string
+	scheme    string
+	via       string
+}
+
+func newEndpoint(configuration map[string]string) (*endpoint, error) {
+	e := &endpoint{
+		namespace: configuration["namespace"],
+		port:      configuration["port"],
+		scheme:    configuration["scheme"],
+		via:       configuration["via"],
+	}
+
+	switch {
+	case configuration["service"] != "" && configuration["pod"] != "":
+		return nil, fmt.Errorf("only one of service and pod can be set")
+	case configuration["service"] != "":
+		e.kind, e.name = "services", configuration["service"]
+	case configuration["pod"] != "":
+		e.kind, e.name = "pods", configuration["pod"]
+	default:
+		return nil, fmt.Errorf("service or pod is required")
+	}
+
+	if e.port == "" {
+		e.port = "80"
+	}
+	if e.scheme == "" {
+		e.scheme = "http"
+	}
+	if e.scheme != "http" && e.scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %s", e.scheme)
+	}
+	if e.via == "" {
+		e.via = ViaProxy
+	}
+	if e.via != ViaProxy && e.via != ViaDirect {
+		return nil, fmt.Errorf("unsupported via %s", e.via)
+	}
+
+	return e, nil
+}
+
+// url returns the base URL of the endpoint, without the path of the
+// request. Named ports are resolved to reach the endpoint directly
+func (e *endpoint) url(ctx context.Context, c client.Client, namespace string, config *rest.Config) (string, error) {
+	if e.namespace != "" {
+		namespace = e.namespace
+	}
+
+	if e.via == ViaProxy {
+		return fmt.Sprintf("%s/api/v1/namespaces/%s/%s/%s:%s:%s/proxy",
+			strings.TrimSuffix(config.Host, "/"), namespace, e.kind, e.scheme, e.name, e.port), nil
+	}
+
+	if e.kind == "services" {
+		port, err := e.servicePort(ctx, c, namespace)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s://%s.%s.svc:%d", e.scheme, e.name, namespace, port), nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: e.name}, pod); err != nil {
+		return "", fmt.Errorf("failed to get pod %s: %w", e.name, err)
+	}
+	if pod.Status.PodIP == "" {
+		return "", fmt.Errorf("pod %s has no IP", e.name)
+	}
+
+	port, err := e.podPort(pod)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s://%s:%d", e.scheme, pod.Status.PodIP, port), nil
+}
+
+func (e *endpoint) servicePort(ctx context.Context, c client.Client, namespace string) (int32, error) {
+	if port, err := strconv.Atoi(e.port); err == nil {
+		return int32(port), nil
+	}
+
+	service := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: e.name}, service); err != nil {
+		return 0, fmt.Errorf("failed to get service %s: %w", e.name, err)
+	}
+	for _, port := range service.Spec.Ports {
+		if port.Name == e.port {
+			return port.Port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("service %s has no port %s", e.name, e.port)
+}
+
+func (e *endpoint) podPort(pod *corev1.Pod) (int32, error) {
+	if port, err := strconv.Atoi(e.port); err == nil {
+		return int32(port), nil
+	}
+
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == e.port {
+				return port.ContainerPort, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("pod %s has no port %s", e.name, e.port)
+}
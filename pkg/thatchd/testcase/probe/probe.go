@@ -0,0 +1,369 @@
+// Package probe provides a built-in TestCase strategy that probes an HTTP
+// endpoint of a Service or Pod, configured through the strategy configuration
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HTTPProbeProvider is the name of the built-in provider
+const HTTPProbeProvider = "HTTPProbe"
+
+// Ways to reach the endpoint
+const (
+	// ViaProxy sends the requests through the API server proxy, so the
+	// endpoint is reachable from outside the cluster
+	ViaProxy = "proxy"
+	// ViaDirect sends the requests to the in-cluster address of the endpoint
+	ViaDirect = "direct"
+)
+
+// Configuration key prefixes for headers
+const (
+	// RequestHeaderPrefix prefixes the headers sent with the request
+	RequestHeaderPrefix = "requestHeader."
+	// HeaderPrefix prefixes the expected headers of the response
+	HeaderPrefix = "header."
+)
+
+// responseArtifact is the name of the artifact with the last response
+const responseArtifact = "http-response"
+
+// Register adds the HTTP probe provider to the strategy providers. The
+// config is used to reach the API server proxy
+func Register(providers map[string]strategy.StrategyProvider, config *rest.Config) {
+	providers[HTTPProbeProvider] = provider(config)
+}
+
+// httpProbe is a TestCase that sends a request to an endpoint until the
+// response passes its assertions or the timeout expires
+type httpProbe struct {
+	dispatch.Configured
+
+	endpoint  *endpoint
+	request   *requestSpec
+	assertion *responseAssertion
+	timeout   time.Duration
+	interval  time.Duration
+	config    *rest.Config
+}
+
+var _ testcase.Interface = &httpProbe{}
+
+func provider(restConfig *rest.Config) strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		p := &httpProbe{Configured: dispatch.Configure(configuration), config: restConfig}
+		if p.Err != nil {
+			return p
+		}
+		if p.endpoint, p.Err = newEndpoint(configuration); p.Err != nil {
+			return p
+		}
+		if p.request, p.Err = newRequestSpec(configuration); p.Err != nil {
+			return p
+		}
+		if p.assertion, p.Err = newResponseAssertion(configuration); p.Err != nil {
+			return p
+		}
+		if p.timeout, p.Err = config.Duration(configuration, "timeout", time.Minute); p.Err != nil {
+			return p
+		}
+		p.interval, p.Err = config.Duration(configuration, "interval", 2*time.Second)
+		return p
+	})
+}
+
+func (p *httpProbe) Run(c client.Client, namespace string, result *testcase.Result, logger logr.Logger) error {
+	if err := p.ConfigurationError(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(result.Context(), p.timeout)
+	defer cancel()
+
+	httpClient, err := p.httpClient()
+	if err != nil {
+		return err
+	}
+
+	// Each attempt is asserted separately, and only the last one that got a
+	// response is recorded in the result
+	var attempt *testcase.Result
+	var response []byte
+	var lastErr error
+	err = wait.PollImmediateUntil(p.interval, func() (bool, error) {
+		// The endpoint may not exist yet, so failing to resolve it is retried
+		url, err := p.endpoint.url(ctx, c, namespace, p.config)
+		if err != nil {
+			lastErr = err
+			logger.Info("failed to resolve endpoint", "error", err.Error())
+			return false, nil
+		}
+
+		current := testcase.NewResultWithContext(ctx)
+		currentResponse, err := p.probe(ctx, httpClient, url, current, logger)
+		if err != nil {
+			lastErr = err
+			logger.Info("request failed", "url", url, "error", err.Error())
+			return false, nil
+		}
+
+		attempt, response = current, currentResponse
+		return attempt.Err() == nil, nil
+	}, ctx.Done())
+	if err != nil && err != wait.ErrWaitTimeout {
+		return err
+	}
+
+	if attempt == nil {
+		result.True("request", false, fmt.Sprintf("no response after %v: %v", p.timeout, lastErr))
+		return nil
+	}
+
+	for _, assertion := range attempt.Assertions() {
+		result.Record(assertion)
+	}
+
+	return result.Attach(responseArtifact, response)
+}
+
+// maxResponseSize is the size of the largest response body read, so it can
+// be attached to the test case
+const maxResponseSize = testcase.MaxArtifactSize
+
+// probe sends the request and asserts the response, returning it as text
+func (p *httpProbe) probe(ctx context.Context, httpClient *http.Client, url string, result *testcase.Result, logger logr.Logger) ([]byte, error) {
+	request, err := p.request.build(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("sending request", "method", request.Method, "url", url)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > maxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+	}
+
+	if err := p.assertion.assert(response, body, result); err != nil {
+		return nil, err
+	}
+
+	return dumpResponse(response, body), nil
+}
+
+// httpClient returns the client that reaches the endpoint, authenticated
+// against the API server when going through its proxy
+func (p *httpProbe) httpClient() (*http.Client, error) {
+	if p.endpoint.via == ViaDirect {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: p.request.insecure}
+		return &http.Client{Transport: transport, Timeout: p.request.timeout}, nil
+	}
+
+	if p.config == nil {
+		return nil, fmt.Errorf("no API server configuration to reach the proxy")
+	}
+
+	transport, err := rest.TransportFor(p.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API server transport: %w", err)
+	}
+
+	return &http.Client{Transport: transport, Timeout: p.request.timeout}, nil
+}
+
+// requestSpec is the request sent to the endpoint
+type requestSpec struct {
+	method   string
+	path     string
+	body     string
+	headers  map[string]string
+	insecure bool
+	timeout  time.Duration
+}
+
+func newRequestSpec(configuration map[string]string) (*requestSpec, error) {
+	spec := &requestSpec{
+		method:  strings.ToUpper(configuration["method"]),
+		path:    configuration["path"],
+		body:    configuration["body"],
+		headers: prefixed(configuration, RequestHeaderPrefix),
+	}
+	if spec.method == "" {
+		spec.method = http.MethodGet
+	}
+	if !strings.HasPrefix(spec.path, "/") {
+		spec.path = "/" + spec.path
+	}
+
+	var err error
+	if configuration["insecureSkipVerify"] != "" {
+		if spec.insecure, err = strconv.ParseBool(configuration["insecureSkipVerify"]); err != nil {
+			return nil, fmt.Errorf("invalid insecureSkipVerify: %w", err)
+		}
+	}
+	if spec.timeout, err = config.Duration(configuration, "requestTimeout", 10*time.Second); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+func (s *requestSpec) build(ctx context.Context, url string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, s.method, url+s.path, strings.NewReader(s.body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	for name, value := range s.headers {
+		request.Header.Set(name, value)
+	}
+
+	return request, nil
+}
+
+// responseAssertion asserts the response of the endpoint
+type responseAssertion struct {
+	status       int
+	headers      map[string]string
+	bodyContains string
+	bodyMatches  *regexp.Regexp
+	jsonPath     *jsonpath.JSONPath
+	jsonPathExpr string
+	value        *string
+}
+
+func newResponseAssertion(configuration map[string]string) (*responseAssertion, error) {
+	assertion := &responseAssertion{
+		headers:      prefixed(configuration, HeaderPrefix),
+		bodyContains: configuration["bodyContains"],
+		jsonPathExpr: configuration["jsonPath"],
+	}
+
+	if status, ok := configuration["status"]; ok {
+		var err error
+		if assertion.status, err = strconv.Atoi(status); err != nil {
+			return nil, fmt.Errorf("invalid status: %w", err)
+		}
+	}
+
+	if pattern, ok := configuration["bodyMatches"]; ok {
+		var err error
+		if assertion.bodyMatches, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid bodyMatches: %w", err)
+		}
+	}
+
+	if assertion.jsonPathExpr != "" {
+		assertion.jsonPath = jsonpath.New("").AllowMissingKeys(true)
+		if err := assertion.jsonPath.Parse(assertion.jsonPathExpr); err != nil {
+			return nil, fmt.Errorf("invalid jsonPath: %w", err)
+		}
+		if value, ok := configuration["value"]; ok {
+			assertion.value = &value
+		}
+	}
+
+	return assertion, nil
+}
+
+// assert records the assertions of the response. The status is expected to
+// be successful unless configured otherwise
+func (a *responseAssertion) assert(response *http.Response, body []byte, result *testcase.Result) error {
+	if a.status != 0 {
+		result.Equal("status", a.status, response.StatusCode)
+	} else {
+		result.True("status", response.StatusCode >= 200 && response.StatusCode < 300,
+			fmt.Sprintf("expected a successful status, got %d", response.StatusCode))
+	}
+
+	for name, value := range a.headers {
+		result.Equal(fmt.Sprintf("header %s", name), value, response.Header.Get(name))
+	}
+
+	if a.bodyContains != "" {
+		result.True("body contains", bytes.Contains(body, []byte(a.bodyContains)),
+			fmt.Sprintf("body doesn't contain %q", a.bodyContains))
+	}
+
+	if a.bodyMatches != nil {
+		result.True("body matches", a.bodyMatches.Match(body),
+			fmt.Sprintf("body doesn't match %s", a.bodyMatches))
+	}
+
+	if a.jsonPath != nil {
+		name := fmt.Sprintf("jsonPath %s", a.jsonPathExpr)
+
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			result.True(name, false, fmt.Sprintf("body isn't JSON: %v", err))
+			return nil
+		}
+
+		buffer := &bytes.Buffer{}
+		if err := a.jsonPath.Execute(buffer, document); err != nil {
+			return fmt.Errorf("failed to evaluate %s: %w", name, err)
+		}
+
+		if a.value != nil {
+			result.Equal(name, *a.value, buffer.String())
+		} else {
+			result.True(name, buffer.Len() > 0, "no value found")
+		}
+	}
+
+	return nil
+}
+
+// dumpResponse renders the status, headers and body of the response
+func dumpResponse(response *http.Response, body []byte) []byte {
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "%s %s\n", response.Proto, response.Status)
+	response.Header.Write(buffer)
+	buffer.WriteString("\n")
+	buffer.Write(body)
+
+	return buffer.Bytes()
+}
+
+// prefixed returns the configuration entries with the prefix, keyed by the
+// rest of the key
+func prefixed(configuration map[string]string, prefix string) map[string]string {
+	result := map[string]string{}
+	for key, value := range configuration {
+		if strings.HasPrefix(key, prefix) {
+			result[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,225 @@
+package probe
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testcase"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHTTPProbe(t *testing.T) {
+	// The server answers both as the API server proxy and as the pod
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := atomic.AddInt32(&requests, 1)
+		path := strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/thatchd/services/http:web:8080/proxy")
+
+		switch path {
+		case "/health":
+			w.Header().Set("X-Version", "1")
+			fmt.Fprint(w, `{"status":"ok","checks":[{"name":"db","ready":true}]}`)
+		case "/flaky":
+			if attempt < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "recovered")
+		case "/large":
+			fmt.Fprint(w, strings.Repeat("x", maxResponseSize+1))
+		case "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Authorization"), body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{Name: "web-0", Namespace: "thatchd"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: int32(atoi(t, serverURL.Port()))}},
+			}},
+		},
+		Status: corev1.PodStatus{PodIP: serverURL.Hostname()},
+	}
+
+	scenarios := []struct {
+		Name          string
+		Configuration map[string]string
+		Failures      []string
+		Error         string
+	}{
+		{
+			Name: "Service through the proxy",
+			Configuration: map[string]string{
+				"service":          "web",
+				"port":             "8080",
+				"path":             "/health",
+				"header.X-Version": "1",
+				"bodyContains":     `"status":"ok"`,
+				"jsonPath":         "{.checks[?(@.name==\"db\")].ready}",
+				"value":            "true",
+			},
+		},
+		{
+			Name: "Pod directly",
+			Configuration: map[string]string{
+				"pod":         "web-0",
+				"port":        "http",
+				"via":         "direct",
+				"path":        "health",
+				"bodyMatches": `"status":"(ok|degraded)"`,
+				"jsonPath":    "{.status}",
+			},
+		},
+		{
+			Name: "Request method, headers and body",
+			Configuration: map[string]string{
+				"pod":                         "web-0",
+				"port":                        "http",
+				"via":                         "direct",
+				"path":                        "/echo",
+				"method":                      "post",
+				"body":                        "payload",
+				"requestHeader.Authorization": "Bearer token",
+				"bodyContains":                "POST Bearer token payload",
+			},
+		},
+		{
+			Name: "Retries until the response passes",
+			Configuration: map[string]string{
+				"service":  "web",
+				"port":     "8080",
+				"path":     "/flaky",
+				"interval": "10ms",
+			},
+		},
+		{
+			Name: "Failed assertions of the last attempt",
+			Configuration: map[string]string{
+				"service":          "web",
+				"port":             "8080",
+				"path":             "/health",
+				"status":           "201",
+				"header.X-Version": "2",
+				"bodyMatches":      "degraded",
+				"jsonPath":         "{.status}",
+				"value":            "failed",
+				"timeout":          "50ms",
+				"interval":         "10ms",
+			},
+			Failures: []string{
+				"status: expected 201, got 200",
+				"header X-Version: expected 2, got 1",
+				"body matches: body doesn't match degraded",
+				"jsonPath {.status}: expected failed, got ok",
+			},
+		},
+		{
+			Name: "Response too large",
+			Configuration: map[string]string{
+				"service":  "web",
+				"port":     "8080",
+				"path":     "/large",
+				"timeout":  "50ms",
+				"interval": "10ms",
+			},
+			Failures: []string{`request: no response after 50ms: response exceeds 262144 bytes`},
+		},
+		{
+			Name: "Missing endpoint",
+			Configuration: map[string]string{
+				"pod":      "web-1",
+				"via":      "direct",
+				"timeout":  "50ms",
+				"interval": "10ms",
+			},
+			Failures: []string{`request: no response after 50ms: failed to get pod web-1`},
+		},
+		{
+			Name:          "Invalid configuration",
+			Configuration: map[string]string{"path": "/health"},
+			Error:         "invalid configuration: service or pod is required",
+		},
+	}
+
+	providers := map[string]strategy.StrategyProvider{}
+	Register(providers, &rest.Config{Host: server.URL})
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			testCase, err := testcase.FromStrategy(&strategy.Strategy{
+				Provider:      HTTPProbeProvider,
+				Configuration: scenario.Configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testCase.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test case to be dispatched")
+			}
+
+			result := testcase.NewResult()
+			err = testCase.Run(fake.NewFakeClient(pod.DeepCopy()), "thatchd", result, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resultErr := result.Err()
+			if len(scenario.Failures) == 0 {
+				if resultErr != nil {
+					t.Errorf("unexpected failures: %v", resultErr)
+				}
+				if _, ok := result.Artifacts()[responseArtifact]; !ok {
+					t.Errorf("expected the response to be attached")
+				}
+				return
+			}
+			if resultErr == nil {
+				t.Fatalf("expected failures %v", scenario.Failures)
+			}
+			for _, failure := range scenario.Failures {
+				if !strings.Contains(resultErr.Error(), failure) {
+					t.Errorf("expected failure %s, got %v", failure, resultErr)
+				}
+			}
+		})
+	}
+}
+
+func atoi(t *testing.T, value string) int {
+	var result int
+	if _, err := fmt.Sscan(value, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
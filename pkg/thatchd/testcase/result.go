@@ -0,0 +1,347 @@
+package testcase
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/storage"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Size limits of the artifacts, so they fit in a ConfigMap or a Secret,
+// limited to 1MiB
+const (
+	MaxArtifactSize  = 256 * 1024
+	MaxArtifactsSize = 768 * 1024
+)
+
+// Result collects the assertions performed by a test case while it runs,
+// and the artifacts it attaches. Failed assertions are soft failures: they're
+// recorded and the test keeps running, but the test case is marked as failed
+// once it finishes. It's safe to use from multiple goroutines
+type Result struct {
+	ctx          context.Context
+	mu           sync.Mutex
+	assertions   []thatchdv1alpha1.AssertionResult
+	artifacts    map[string][]byte
+	measurements []thatchdv1alpha1.Measurement
+}
+
+func NewResult() *Result {
+	return NewResultWithContext(context.Background())
+}
+
+// NewResultWithContext creates a result for a run that's canceled with the
+// context, such as when it times out
+func NewResultWithContext(ctx context.Context) *Result {
+	return &Result{
+		ctx: ctx,
+	}
+}
+
+// Context is canceled when the run is no longer awaited, so the requests of
+// the test case should use it
+func (r *Result) Context() context.Context {
+	return r.ctx
+}
+
+// Equal records an assertion that passes when expected and actual are deeply
+// equal. Returns whether the assertion passed
+func (r *Result) Equal(name string, expected, actual interface{}) bool {
+	ok := reflect.DeepEqual(expected, actual)
+
+	outcome := thatchdv1alpha1.AssertionPassed
+	message := ""
+	if !ok {
+		outcome = thatchdv1alpha1.AssertionFailed
+		message = fmt.Sprintf("expected %v, got %v", expected, actual)
+	}
+
+	r.Record(thatchdv1alpha1.AssertionResult{
+		Name:     name,
+		Outcome:  outcome,
+		Expected: fmt.Sprintf("%v", expected),
+		Actual:   fmt.Sprintf("%v", actual),
+		Message:  message,
+	})
+
+	return ok
+}
+
+// True records an assertion that passes when ok is true. The message
+// describes the failure, and is only stored if the assertion didn't pass
+func (r *Result) True(name string, ok bool, message string) bool {
+	outcome := thatchdv1alpha1.AssertionPassed
+	if ok {
+		message = ""
+	} else {
+		outcome = thatchdv1alpha1.AssertionFailed
+	}
+
+	r.Record(thatchdv1alpha1.AssertionResult{
+		Name:    name,
+		Outcome: outcome,
+		Message: message,
+	})
+
+	return ok
+}
+
+// Warn records an assertion that is reported as a warning instead of a
+// failure when ok is false
+func (r *Result) Warn(name string, ok bool, message string) bool {
+	outcome := thatchdv1alpha1.AssertionPassed
+	if ok {
+		message = ""
+	} else {
+		outcome = thatchdv1alpha1.AssertionWarning
+	}
+
+	r.Record(thatchdv1alpha1.AssertionResult{
+		Name:    name,
+		Outcome: outcome,
+		Message: message,
+	})
+
+	return ok
+}
+
+// Skip records an assertion that wasn't evaluated
+func (r *Result) Skip(name string, message string) {
+	r.Record(thatchdv1alpha1.AssertionResult{
+		Name:    name,
+		Outcome: thatchdv1alpha1.AssertionSkipped,
+		Message: message,
+	})
+}
+
+// Record appends an assertion result as is
+func (r *Result) Record(assertion thatchdv1alpha1.AssertionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.assertions = append(r.assertions, assertion)
+}
+
+// Assertions returns a copy of the assertions recorded so far
+func (r *Result) Assertions() []thatchdv1alpha1.AssertionResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.assertions) == 0 {
+		return nil
+	}
+
+	result := make([]thatchdv1alpha1.AssertionResult, len(r.assertions))
+	copy(result, r.assertions)
+	return result
+}
+
+// Attach adds a named artifact to the result, such as a generated manifest
+// or an HTTP response, that is persisted once the test finishes. The name
+// must be a valid ConfigMap key. Attaching an artifact with the same name
+// replaces it. Artifacts are truncated to MaxArtifactSize, and the ones that
+// would make the artifacts exceed MaxArtifactsSize are left out, recording a
+// warning
+func (r *Result) Attach(name string, content []byte) error {
+	if errs := validation.IsConfigMapKey(name); len(errs) > 0 {
+		return fmt.Errorf("invalid artifact name %s: %s", name, strings.Join(errs, ", "))
+	}
+	content = storage.Truncate(name, content, MaxArtifactSize)
+
+	r.mu.Lock()
+	size := len(content)
+	for attached, attachedContent := range r.artifacts {
+		if attached != name {
+			size += len(attachedContent)
+		}
+	}
+	if size <= MaxArtifactsSize {
+		if r.artifacts == nil {
+			r.artifacts = map[string][]byte{}
+		}
+		r.artifacts[name] = content
+	}
+	r.mu.Unlock()
+
+	if size > MaxArtifactsSize {
+		r.Warn(fmt.Sprintf("artifact %s", name), false, fmt.Sprintf("left out, the artifacts exceed %d bytes", MaxArtifactsSize))
+	}
+	return nil
+}
+
+// AttachFile attaches the content of a local file, named after its base name
+func (r *Result) AttachFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return r.Attach(filepath.Base(path), content)
+}
+
+// Artifacts returns a copy of the artifacts attached so far
+func (r *Result) Artifacts() map[string][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[string][]byte, len(r.artifacts))
+	for name, content := range r.artifacts {
+		result[name] = content
+	}
+	return result
+}
+
+// Measure records a named numeric value, such as the time a resource took
+// to be ready, with its unit. Measuring the same name again replaces the value
+func (r *Result) Measure(name string, value float64, unit string) {
+	measurement := thatchdv1alpha1.Measurement{
+		Name:  name,
+		Value: strconv.FormatFloat(value, 'g', -1, 64),
+		Unit:  unit,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.measurements {
+		if r.measurements[i].Name == name {
+			r.measurements[i] = measurement
+			return
+		}
+	}
+	r.measurements = append(r.measurements, measurement)
+}
+
+// MeasureDuration records a duration in seconds
+func (r *Result) MeasureDuration(name string, duration time.Duration) {
+	r.Measure(name, duration.Seconds(), "s")
+}
+
+// Measurements returns a copy of the measurements recorded so far
+func (r *Result) Measurements() []thatchdv1alpha1.Measurement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.measurements) == 0 {
+		return nil
+	}
+
+	result := make([]thatchdv1alpha1.Measurement, len(r.measurements))
+	copy(result, r.measurements)
+	return result
+}
+
+// CheckThresholds records an assertion for each threshold, that fails when
+// the measurement is missing, in another unit or out of its bounds
+func (r *Result) CheckThresholds(thresholds []thatchdv1alpha1.Threshold) {
+	measurements := map[string]thatchdv1alpha1.Measurement{}
+	for _, measurement := range r.Measurements() {
+		measurements[measurement.Name] = measurement
+	}
+
+	for _, threshold := range thresholds {
+		name := fmt.Sprintf("threshold %s", threshold.Measurement)
+
+		bounds := []string{}
+		if threshold.Min != nil {
+			bounds = append(bounds, ">= "+*threshold.Min)
+		}
+		if threshold.Max != nil {
+			bounds = append(bounds, "<= "+*threshold.Max)
+		}
+		expected := strings.Join(bounds, ", ")
+		if threshold.Unit != "" {
+			expected = fmt.Sprintf("%s %s", expected, threshold.Unit)
+		}
+
+		measurement, ok := measurements[threshold.Measurement]
+		if !ok {
+			r.Record(thatchdv1alpha1.AssertionResult{
+				Name:     name,
+				Outcome:  thatchdv1alpha1.AssertionFailed,
+				Expected: expected,
+				Message:  "measurement not reported",
+			})
+			continue
+		}
+
+		actual := strings.TrimSpace(measurement.Value + " " + measurement.Unit)
+		outcome := thatchdv1alpha1.AssertionPassed
+		message := ""
+		if threshold.Unit != "" && measurement.Unit != threshold.Unit {
+			outcome = thatchdv1alpha1.AssertionFailed
+			message = fmt.Sprintf("measured in %q instead of %q", measurement.Unit, threshold.Unit)
+		} else if err := checkBounds(measurement.Value, threshold.Min, threshold.Max); err != nil {
+			outcome = thatchdv1alpha1.AssertionFailed
+			message = err.Error()
+		}
+
+		r.Record(thatchdv1alpha1.AssertionResult{
+			Name:     name,
+			Outcome:  outcome,
+			Expected: expected,
+			Actual:   actual,
+			Message:  message,
+		})
+	}
+}
+
+func checkBounds(value string, min, max *string) error {
+	actual, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid measurement %s: %w", value, err)
+	}
+
+	if min != nil {
+		bound, err := strconv.ParseFloat(*min, 64)
+		if err != nil {
+			return fmt.Errorf("invalid minimum %s: %w", *min, err)
+		}
+		if !(actual >= bound) {
+			return fmt.Errorf("%s is below the minimum %s", value, *min)
+		}
+	}
+	if max != nil {
+		bound, err := strconv.ParseFloat(*max, 64)
+		if err != nil {
+			return fmt.Errorf("invalid maximum %s: %w", *max, err)
+		}
+		if !(actual <= bound) {
+			return fmt.Errorf("%s is above the maximum %s", value, *max)
+		}
+	}
+
+	return nil
+}
+
+// Err returns an error listing the failed assertions, or nil if none of
+// them failed
+func (r *Result) Err() error {
+	failed := []string{}
+	for _, assertion := range r.Assertions() {
+		if assertion.Outcome != thatchdv1alpha1.AssertionFailed {
+			continue
+		}
+
+		if assertion.Message == "" {
+			failed = append(failed, assertion.Name)
+		} else {
+			failed = append(failed, fmt.Sprintf("%s: %s", assertion.Name, assertion.Message))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d assertion(s) failed: %s", len(failed), strings.Join(failed, "; "))
+}
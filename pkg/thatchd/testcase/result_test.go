@@ -0,0 +1,165 @@
+package testcase
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+)
+
+func TestAttach(t *testing.T) {
+	result := NewResult()
+
+	if err := result.Attach("response/body", []byte("{}")); err == nil || !strings.HasPrefix(err.Error(), "invalid artifact name response/body") {
+		t.Errorf("expected the artifact name to be rejected, got %v", err)
+	}
+
+	for _, name := range []string{"first.txt", "second.log", "second.log", "third.txt", "fourth.txt"} {
+		if err := result.Attach(name, []byte(strings.Repeat("x", MaxArtifactSize+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	artifacts := result.Artifacts()
+	if len(artifacts) != 3 {
+		t.Errorf("expected 3 artifacts to fit, got %d", len(artifacts))
+	}
+	for name, content := range artifacts {
+		if len(content) > MaxArtifactSize {
+			t.Errorf("expected %s to be truncated to %d bytes, got %d", name, MaxArtifactSize, len(content))
+		}
+	}
+	if content := string(artifacts["first.txt"]); !strings.HasSuffix(content, "... truncated 1 bytes ...\n") {
+		t.Errorf("expected the end of first.txt to be truncated, got %q", content[len(content)-30:])
+	}
+	if content := string(artifacts["second.log"]); !strings.HasPrefix(content, "... truncated 1 bytes ...\n") {
+		t.Errorf("expected the beginning of second.log to be truncated, got %q", content[:30])
+	}
+
+	assertions := result.Assertions()
+	if len(assertions) != 1 || assertions[0].Name != "artifact fourth.txt" || assertions[0].Outcome != thatchdv1alpha1.AssertionWarning {
+		t.Errorf("expected a warning for the artifact left out, got %v", assertions)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	result := NewResult()
+	result.Measure("restarts", 2, "")
+	result.MeasureDuration("time-to-ready", 1500*time.Millisecond)
+	result.Measure("restarts", 3, "")
+
+	expected := []thatchdv1alpha1.Measurement{
+		{Name: "restarts", Value: "3"},
+		{Name: "time-to-ready", Value: "1.5", Unit: "s"},
+	}
+	if measurements := result.Measurements(); !reflect.DeepEqual(measurements, expected) {
+		t.Errorf("expected measurements %v, got %v", expected, measurements)
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	scenarios := []struct {
+		Name      string
+		Threshold thatchdv1alpha1.Threshold
+		Expected  string
+		Actual    string
+		Message   string
+	}{
+		{
+			Name:      "Within the bounds",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "time-to-ready", Unit: "s", Min: addr("1"), Max: addr("30")},
+			Expected:  ">= 1, <= 30 s",
+			Actual:    "12.5 s",
+		},
+		{
+			Name:      "Bound on the value",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "restarts", Max: addr("0")},
+			Expected:  "<= 0",
+			Actual:    "0",
+		},
+		{
+			Name:      "Above the maximum",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "time-to-ready", Max: addr("10")},
+			Expected:  "<= 10",
+			Actual:    "12.5 s",
+			Message:   "12.5 is above the maximum 10",
+		},
+		{
+			Name:      "Below the minimum",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "time-to-ready", Min: addr("1e2")},
+			Expected:  ">= 1e2",
+			Actual:    "12.5 s",
+			Message:   "12.5 is below the minimum 1e2",
+		},
+		{
+			Name:      "Another unit",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "time-to-ready", Unit: "ms", Max: addr("30000")},
+			Expected:  "<= 30000 ms",
+			Actual:    "12.5 s",
+			Message:   `measured in "s" instead of "ms"`,
+		},
+		{
+			Name:      "Missing measurement",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "latency", Max: addr("1")},
+			Expected:  "<= 1",
+			Message:   "measurement not reported",
+		},
+		{
+			Name:      "Invalid bound",
+			Threshold: thatchdv1alpha1.Threshold{Measurement: "restarts", Max: addr("none")},
+			Expected:  "<= none",
+			Actual:    "0",
+			Message:   `invalid maximum none: strconv.ParseFloat: parsing "none": invalid syntax`,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			result := NewResult()
+			result.Measure("time-to-ready", 12.5, "s")
+			result.Measure("restarts", 0, "")
+			result.CheckThresholds([]thatchdv1alpha1.Threshold{scenario.Threshold})
+
+			outcome := thatchdv1alpha1.AssertionPassed
+			if scenario.Message != "" {
+				outcome = thatchdv1alpha1.AssertionFailed
+			}
+			expected := []thatchdv1alpha1.AssertionResult{{
+				Name:     "threshold " + scenario.Threshold.Measurement,
+				Outcome:  outcome,
+				Expected: scenario.Expected,
+				Actual:   scenario.Actual,
+				Message:  scenario.Message,
+			}}
+			if assertions := result.Assertions(); !reflect.DeepEqual(assertions, expected) {
+				t.Errorf("expected assertions %v, got %v", expected, assertions)
+			}
+		})
+	}
+}
+
+func TestErr(t *testing.T) {
+	result := NewResult()
+	if err := result.Err(); err != nil {
+		t.Errorf("unexpected error without assertions: %v", err)
+	}
+
+	result.Equal("replicas", 3, 3)
+	result.Warn("restarts", false, "containers restarted")
+	result.Skip("image", "no image")
+	if err := result.Err(); err != nil {
+		t.Errorf("unexpected error without failed assertions: %v", err)
+	}
+
+	result.Equal("ready", true, false)
+	result.True("image", false, "")
+	if err := result.Err(); err == nil || err.Error() != "2 assertion(s) failed: ready: expected true, got false; image" {
+		t.Errorf("expected the failed assertions to be listed, got %v", err)
+	}
+}
+
+func addr(value string) *string {
+	return &value
+}
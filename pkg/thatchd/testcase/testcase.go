@@ -1,17 +1,33 @@
 package testcase
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/go-logr/logr"
 	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ErrSkip is the error returned by Run when a test case is skipped. Use Skip
+// to add the reason why
+var ErrSkip = errors.New("test case skipped")
+
+// Skip returns an error that marks the test case as skipped for the given
+// reason
+func Skip(reason string) error {
+	return fmt.Errorf("%w: %s", ErrSkip, reason)
+}
+
 type Interface interface {
 	dispatch.Dispatchable
 
-	Run(client client.Client, namespace string) error
+	// Run executes the test logic. Assertions can be recorded in the result
+	// to report several checks at once. The test case fails if Run returns
+	// an error or any of the recorded assertions failed. The lines logged
+	// with the logger are captured in the test case output
+	Run(client client.Client, namespace string, result *Result, logger logr.Logger) error
 }
 
 func FromStrategy(s *strategy.Strategy, providers map[string]strategy.StrategyProvider) (Interface, error) {
@@ -0,0 +1,63 @@
+package testsuite
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MigrateStateFn migrates the JSON decoded state, made of maps, slices and
+// json.Number values, to the next version
+type MigrateStateFn func(state interface{}) (interface{}, error)
+
+// Migrator is implemented by reconcilers whose state type changes between
+// versions. Each migration upgrades the state from the version of its index
+// to the next one, so the version of the strategy is the number of
+// migrations. Migrations must only be appended
+type Migrator interface {
+	StateMigrations() []MigrateStateFn
+}
+
+// StateVersion returns the version of the state of the reconciler
+func StateVersion(reconciler Reconciler) int {
+	if migrator, ok := reconciler.(Migrator); ok {
+		return len(migrator.StateMigrations())
+	}
+
+	return 0
+}
+
+// MigrateState upgrades the JSON encoded state from its version to the
+// version of the reconciler, one migration at a time
+func MigrateState(reconciler Reconciler, state string, version int) (string, error) {
+	currentVersion := StateVersion(reconciler)
+	if version == currentVersion {
+		return state, nil
+	}
+	if version > currentVersion {
+		return "", fmt.Errorf("state version %d is newer than the strategy version %d", version, currentVersion)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(state))
+	decoder.UseNumber()
+
+	var migrated interface{}
+	if err := decoder.Decode(&migrated); err != nil {
+		return "", fmt.Errorf("invalid state: %w", err)
+	}
+
+	migrations := reconciler.(Migrator).StateMigrations()
+	for i := version; i < currentVersion; i++ {
+		var err error
+		if migrated, err = migrations[i](migrated); err != nil {
+			return "", fmt.Errorf("failed to migrate state from version %d to %d: %w", i, i+1, err)
+		}
+	}
+
+	result, err := json.Marshal(migrated)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal migrated state: %w", err)
+	}
+
+	return string(result), nil
+}
@@ -0,0 +1,126 @@
+package testsuite
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type componentState struct {
+	Components map[string]componentStatus `json:"components"`
+}
+
+type componentStatus struct {
+	Ready    bool `json:"ready"`
+	Replicas int  `json:"replicas"`
+}
+
+type migratingReconciler struct {
+	migrations []MigrateStateFn
+}
+
+func (r *migratingReconciler) ParseState(state string) (interface{}, error) {
+	result := componentState{}
+	err := json.Unmarshal([]byte(state), &result)
+	return result, err
+}
+
+func (r *migratingReconciler) Reconcile(_ client.Client, _ string, currentState interface{}, _ logr.Logger) (interface{}, error) {
+	return currentState, nil
+}
+
+func (r *migratingReconciler) StateMigrations() []MigrateStateFn {
+	return r.migrations
+}
+
+var migrations = []MigrateStateFn{
+	// Version 0 maps component names to whether they're ready
+	func(state interface{}) (interface{}, error) {
+		components := map[string]interface{}{}
+		for name, ready := range state.(map[string]interface{}) {
+			components[name] = map[string]interface{}{"ready": ready}
+		}
+		return map[string]interface{}{"components": components}, nil
+	},
+	// Version 1 adds the replicas of the components
+	func(state interface{}) (interface{}, error) {
+		components := state.(map[string]interface{})["components"].(map[string]interface{})
+		for _, component := range components {
+			component.(map[string]interface{})["replicas"] = json.Number("1")
+		}
+		return state, nil
+	},
+}
+
+func TestMigrateState(t *testing.T) {
+	reconciler := &migratingReconciler{migrations: migrations}
+
+	if version := StateVersion(reconciler); version != 2 {
+		t.Errorf("expected version 2, got %d", version)
+	}
+
+	scenarios := []struct {
+		Name     string
+		State    string
+		Version  int
+		Expected string
+		Error    string
+	}{
+		{
+			Name:     "Migrate from the first version",
+			State:    `{"a":true}`,
+			Version:  0,
+			Expected: `{"components":{"a":{"ready":true,"replicas":1}}}`,
+		},
+		{
+			Name:     "Migrate from an intermediate version",
+			State:    `{"components":{"a":{"ready":false}}}`,
+			Version:  1,
+			Expected: `{"components":{"a":{"ready":false,"replicas":1}}}`,
+		},
+		{
+			Name:     "Current version",
+			State:    `{"components":{}}`,
+			Version:  2,
+			Expected: `{"components":{}}`,
+		},
+		{
+			Name:    "Newer version",
+			State:   `{}`,
+			Version: 3,
+			Error:   "newer than the strategy version",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			migrated, err := MigrateState(reconciler, scenario.State, scenario.Version)
+			if scenario.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), scenario.Error) {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if migrated != scenario.Expected {
+				t.Errorf("expected state %s, got %s", scenario.Expected, migrated)
+			}
+			if err := ValidateState(reconciler, migrated); err != nil {
+				t.Errorf("expected migrated state to be valid: %v", err)
+			}
+		})
+	}
+
+	failing := &migratingReconciler{migrations: []MigrateStateFn{
+		func(interface{}) (interface{}, error) { return nil, errors.New("unexpected state") },
+	}}
+	if _, err := MigrateState(failing, `{}`, 0); err == nil || !strings.Contains(err.Error(), "from version 0 to 1") {
+		t.Errorf("expected migration error, got %v", err)
+	}
+}
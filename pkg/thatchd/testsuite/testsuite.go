@@ -3,14 +3,23 @@ package testsuite
 import (
 	"fmt"
 
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/schema"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Reconciler interface {
 	ParseState(state string) (interface{}, error)
 
-	Reconcile(client client.Client, namespace string, currentState interface{}) (interface{}, error)
+	Reconcile(client client.Client, namespace string, currentState interface{}, logger logr.Logger) (interface{}, error)
+}
+
+// SchemaProvider is implemented by reconcilers that declare the schema of
+// their state. schema.FromType derives it from the Go type of the state
+type SchemaProvider interface {
+	StateSchema() *apiextensionsv1.JSONSchemaProps
 }
 
 func FromStrategy(s *strategy.Strategy, providers map[string]strategy.StrategyProvider) (Reconciler, error) {
@@ -26,3 +35,20 @@ func FromStrategy(s *strategy.Strategy, providers map[string]strategy.StrategyPr
 
 	return typedResult, nil
 }
+
+// ValidateState validates the JSON encoded state against the schema of the
+// reconciler, if it declares one, and checks that the reconciler can parse
+// it
+func ValidateState(reconciler Reconciler, state string) error {
+	if schemaProvider, ok := reconciler.(SchemaProvider); ok {
+		if err := schema.Validate(schemaProvider.StateSchema(), state); err != nil {
+			return err
+		}
+	}
+
+	if _, err := reconciler.ParseState(state); err != nil {
+		return fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	return nil
+}
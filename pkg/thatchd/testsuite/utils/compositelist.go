@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListReconciler reconciles a list state whose items are structs keyed by
+// name, like the list of TestCaseState, by discovering the names and
+// delegating the reconciliation of each item into an EntryReconcilerFunc.
+// The items are sorted by name
+type ListReconciler struct {
+	stateType reflect.Type
+	nameField int
+	discover  DiscoverFunc
+	entry     EntryReconcilerFunc
+	options   collectionOptions
+}
+
+var _ TypedReconciler = &ListReconciler{}
+
+// NewListReconciler creates a ListReconciler for the stateType, which must
+// be a slice of structs with a string field named "name" in JSON. New items
+// are created with their name set
+func NewListReconciler(stateType reflect.Type, discover DiscoverFunc, entry EntryReconcilerFunc, opts ...CollectionOption) (*ListReconciler, error) {
+	if stateType.Kind() != reflect.Slice || stateType.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("stateType must be a slice of structs")
+	}
+
+	nameField := -1
+	itemType := stateType.Elem()
+	for i := 0; i < itemType.NumField(); i++ {
+		field := itemType.Field(i)
+		if jsonName(field) == "name" && field.Type.Kind() == reflect.String && field.PkgPath == "" {
+			nameField = i
+			break
+		}
+	}
+	if nameField == -1 {
+		return nil, fmt.Errorf("no name field in %s", itemType)
+	}
+
+	options := collectionOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &ListReconciler{
+		stateType: stateType,
+		nameField: nameField,
+		discover:  discover,
+		entry:     entry,
+		options:   options,
+	}, nil
+}
+
+func (r *ListReconciler) ParseState(stringState string) (interface{}, error) {
+	stateValue := reflect.New(r.stateType)
+	if err := json.Unmarshal([]byte(stringState), stateValue.Interface()); err != nil {
+		return nil, err
+	}
+
+	if stateValue.Elem().IsNil() {
+		return reflect.MakeSlice(r.stateType, 0, 0).Interface(), nil
+	}
+
+	return stateValue.Elem().Interface(), nil
+}
+
+func (r *ListReconciler) StateType() reflect.Type {
+	return r.stateType
+}
+
+// Reconcile reconciles the item of each discovered name. The current state
+// isn't modified
+func (r *ListReconciler) Reconcile(client client.Client, namespace string, currentStateInterface interface{}, logger logr.Logger) (interface{}, error) {
+	currentState := reflect.ValueOf(currentStateInterface)
+	if !currentState.IsValid() {
+		currentState = reflect.Zero(r.stateType)
+	}
+	if currentState.Type() != r.stateType {
+		return nil, fmt.Errorf("expected state of type %s, got %s", r.stateType, currentState.Type())
+	}
+
+	objects, err := r.discover(client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the current items by name
+	items := map[string]reflect.Value{}
+	for i := 0; i < currentState.Len(); i++ {
+		item := currentState.Index(i)
+		items[item.Field(r.nameField).String()] = item
+	}
+
+	reconciledItems := map[string]reflect.Value{}
+	if r.options.retain {
+		for name, item := range items {
+			reconciledItems[name] = item
+		}
+	}
+
+	for name, object := range objects {
+		current, ok := items[name]
+		if !ok {
+			current = reflect.New(r.stateType.Elem()).Elem()
+			current.Field(r.nameField).SetString(name)
+		}
+
+		reconciled, err := reconcileEntry(r.entry, name, object, current, r.stateType.Elem(), logger)
+		if err != nil {
+			return nil, err
+		}
+		reconciledItems[name] = reconciled
+	}
+
+	names := make([]string, 0, len(reconciledItems))
+	for name := range reconciledItems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := reflect.MakeSlice(r.stateType, 0, len(names))
+	for _, name := range names {
+		result = reflect.Append(result, reconciledItems[name])
+	}
+
+	return result.Interface(), nil
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type podItem struct {
+	Name  string          `json:"name"`
+	Phase corev1.PodPhase `json:"phase,omitempty"`
+}
+
+func TestListReconciler(t *testing.T) {
+	reconciler, err := NewListReconciler(reflect.TypeOf([]podItem{}), DiscoverObjects(&corev1.PodList{}), Sticky(
+		func(_ string, object interface{}, current interface{}, _ logr.Logger) (interface{}, error) {
+			item := current.(podItem)
+			item.Phase = object.(*corev1.Pod).Status.Phase
+			return item, nil
+		},
+		func(current interface{}) bool {
+			return current.(podItem).Phase == corev1.PodSucceeded
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentState, err := reconciler.ParseState(`[{"name":"deleted","phase":"Failed"},{"name":"a","phase":"Succeeded"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := reconciler.Reconcile(fake.NewFakeClient(testPods()...), "thatchd", currentState, ctrl.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []podItem{
+		{Name: "a", Phase: corev1.PodSucceeded},
+		{Name: "b", Phase: corev1.PodPending},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unmatching resulting value. Expected %v, got %v", expected, result)
+	}
+
+	if _, err := NewListReconciler(reflect.TypeOf([]foo{}), nil, nil); err == nil {
+		t.Errorf("expected error for items without name")
+	}
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DiscoverFunc discovers the entries of a composite state, returning the
+// object that each key was discovered from
+type DiscoverFunc func(client client.Client, namespace string) (map[string]interface{}, error)
+
+// EntryReconcilerFunc reconciles the entry of a composite state for a key.
+// The current value is the zero value of the entries for new keys
+type EntryReconcilerFunc func(key string, object interface{}, current interface{}, logger logr.Logger) (interface{}, error)
+
+// CollectionOption configures a MapReconciler or ListReconciler
+type CollectionOption func(*collectionOptions)
+
+type collectionOptions struct {
+	retain bool
+}
+
+// WithRetainedEntries keeps the entries whose keys are no longer discovered,
+// instead of removing them
+func WithRetainedEntries() CollectionOption {
+	return func(o *collectionOptions) {
+		o.retain = true
+	}
+}
+
+// DiscoverObjects discovers the objects of the list type in the namespace,
+// keyed by name
+func DiscoverObjects(list runtime.Object, opts ...client.ListOption) DiscoverFunc {
+	return func(c client.Client, namespace string) (map[string]interface{}, error) {
+		objectList := list.DeepCopyObject()
+		if err := c.List(context.TODO(), objectList, append([]client.ListOption{client.InNamespace(namespace)}, opts...)...); err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		items, err := meta.ExtractList(objectList)
+		if err != nil {
+			return nil, err
+		}
+
+		objects := make(map[string]interface{}, len(items))
+		for _, item := range items {
+			accessor, err := meta.Accessor(item)
+			if err != nil {
+				return nil, err
+			}
+			objects[accessor.GetName()] = item
+		}
+
+		return objects, nil
+	}
+}
+
+// MapReconciler reconciles a map state with string keys by discovering its
+// keys, for example one for each Pod in the namespace, and delegating the
+// reconciliation of each entry into an EntryReconcilerFunc
+type MapReconciler struct {
+	stateType reflect.Type
+	discover  DiscoverFunc
+	entry     EntryReconcilerFunc
+	options   collectionOptions
+}
+
+var _ TypedReconciler = &MapReconciler{}
+
+// NewMapReconciler creates a MapReconciler for the stateType, which must be
+// a map with string keys
+func NewMapReconciler(stateType reflect.Type, discover DiscoverFunc, entry EntryReconcilerFunc, opts ...CollectionOption) (*MapReconciler, error) {
+	if stateType.Kind() != reflect.Map || stateType.Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("stateType must be a map with string keys")
+	}
+
+	options := collectionOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &MapReconciler{
+		stateType: stateType,
+		discover:  discover,
+		entry:     entry,
+		options:   options,
+	}, nil
+}
+
+func (r *MapReconciler) ParseState(stringState string) (interface{}, error) {
+	stateValue := reflect.New(r.stateType)
+	if err := json.Unmarshal([]byte(stringState), stateValue.Interface()); err != nil {
+		return nil, err
+	}
+
+	if stateValue.Elem().IsNil() {
+		return reflect.MakeMap(r.stateType).Interface(), nil
+	}
+
+	return stateValue.Elem().Interface(), nil
+}
+
+func (r *MapReconciler) StateType() reflect.Type {
+	return r.stateType
+}
+
+// Reconcile reconciles the entry of each discovered key. The current state
+// isn't modified
+func (r *MapReconciler) Reconcile(client client.Client, namespace string, currentStateInterface interface{}, logger logr.Logger) (interface{}, error) {
+	currentState := reflect.ValueOf(currentStateInterface)
+	if !currentState.IsValid() {
+		currentState = reflect.Zero(r.stateType)
+	}
+	if currentState.Type() != r.stateType {
+		return nil, fmt.Errorf("expected state of type %s, got %s", r.stateType, currentState.Type())
+	}
+
+	objects, err := r.discover(client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result := reflect.MakeMap(r.stateType)
+	if r.options.retain {
+		iter := currentState.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyValue := reflect.ValueOf(key).Convert(r.stateType.Key())
+
+		current := currentState.MapIndex(keyValue)
+		if !current.IsValid() {
+			current = reflect.Zero(r.stateType.Elem())
+		}
+
+		reconciled, err := reconcileEntry(r.entry, key, objects[key], current, r.stateType.Elem(), logger)
+		if err != nil {
+			return nil, err
+		}
+		result.SetMapIndex(keyValue, reconciled)
+	}
+
+	return result.Interface(), nil
+}
+
+// reconcileEntry reconciles the current value of the entry for the key,
+// verifying that the result is of the entry type
+func reconcileEntry(entry EntryReconcilerFunc, key string, object interface{}, current reflect.Value, entryType reflect.Type, logger logr.Logger) (reflect.Value, error) {
+	reconciled, err := entry(key, object, current.Interface(), logger.WithValues("key", key))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to reconcile entry %s: %w", key, err)
+	}
+
+	reconciledValue := reflect.ValueOf(reconciled)
+	if !reconciledValue.IsValid() {
+		return reflect.Zero(entryType), nil
+	}
+	if !reconciledValue.Type().AssignableTo(entryType) {
+		return reflect.Value{}, fmt.Errorf("reconciled value of type %s can't be assigned to entry %s of type %s",
+			reconciledValue.Type(), key, entryType)
+	}
+
+	return reconciledValue, nil
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type podPhases map[string]corev1.PodPhase
+
+func reconcilePhase(_ string, object interface{}, _ interface{}, _ logr.Logger) (interface{}, error) {
+	return object.(*corev1.Pod).Status.Phase, nil
+}
+
+func testPods() []runtime.Object {
+	return []runtime.Object{
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "a", Namespace: "thatchd"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "b", Namespace: "thatchd"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "other"},
+		},
+	}
+}
+
+func TestMapReconciler(t *testing.T) {
+	scenarios := []struct {
+		Name     string
+		Entry    EntryReconcilerFunc
+		Options  []CollectionOption
+		Expected podPhases
+	}{
+		{
+			Name:     "Entries for discovered keys",
+			Entry:    reconcilePhase,
+			Expected: podPhases{"a": corev1.PodRunning, "b": corev1.PodPending},
+		},
+		{
+			Name:     "Sticky entries",
+			Entry:    Sticky(reconcilePhase, StickyValues(corev1.PodSucceeded)),
+			Expected: podPhases{"a": corev1.PodSucceeded, "b": corev1.PodPending},
+		},
+		{
+			Name:     "Retained entries",
+			Entry:    reconcilePhase,
+			Options:  []CollectionOption{WithRetainedEntries()},
+			Expected: podPhases{"a": corev1.PodRunning, "b": corev1.PodPending, "deleted": corev1.PodFailed},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			reconciler, err := NewMapReconciler(reflect.TypeOf(podPhases{}), DiscoverObjects(&corev1.PodList{}), scenario.Entry, scenario.Options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			currentState, err := reconciler.ParseState(`{"a":"Succeeded","deleted":"Failed"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := reconciler.Reconcile(fake.NewFakeClient(testPods()...), "thatchd", currentState, ctrl.Log)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(result, scenario.Expected) {
+				t.Errorf("unmatching resulting value. Expected %v, got %v", scenario.Expected, result)
+			}
+		})
+	}
+
+	if _, err := NewMapReconciler(reflect.TypeOf(map[int]string{}), nil, nil); err == nil {
+		t.Errorf("expected error for map without string keys")
+	}
+}
@@ -4,24 +4,84 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// TypedReconciler is implemented by reconcilers that parse their state into
+// a known Go type. Composite reconcilers pass the values of that type
+// directly, instead of marshalling and parsing them
+type TypedReconciler interface {
+	testsuite.Reconciler
+
+	StateType() reflect.Type
+}
+
+// CompositeOption configures a CompositeStructReconciler
+type CompositeOption func(*compositeOptions)
+
+type compositeOptions struct {
+	concurrent      bool
+	fieldTimeout    time.Duration
+	passThrough     bool
+	aggregateErrors bool
+}
+
+// WithConcurrency reconciles the fields concurrently
+func WithConcurrency() CompositeOption {
+	return func(o *compositeOptions) {
+		o.concurrent = true
+	}
+}
+
+// WithFieldTimeout fails the reconciliation of a field that doesn't finish
+// within the timeout. The field reconciler can't be interrupted, it keeps
+// running in the background and its result is discarded. As it may still be
+// running during the next reconciliation, field reconcilers must not share
+// mutable state, neither between them nor across reconciliations
+func WithFieldTimeout(timeout time.Duration) CompositeOption {
+	return func(o *compositeOptions) {
+		o.fieldTimeout = timeout
+	}
+}
+
+// WithPassThrough allows fields without a reconciler, which keep their
+// current value
+func WithPassThrough() CompositeOption {
+	return func(o *compositeOptions) {
+		o.passThrough = true
+	}
+}
+
+// WithAggregatedErrors reconciles every field even if some of them fail,
+// and returns all the errors
+func WithAggregatedErrors() CompositeOption {
+	return func(o *compositeOptions) {
+		o.aggregateErrors = true
+	}
+}
+
 // CompositeStructReconciler reconciles a struct or struct pointer by delegating
 // each field reconciliation into separate reconcilers
 type CompositeStructReconciler struct {
 	stateType reflect.Type
 	fields    map[string]testsuite.Reconciler
+	options   compositeOptions
 }
 
-var _ testsuite.Reconciler = &CompositeStructReconciler{}
+var _ TypedReconciler = &CompositeStructReconciler{}
 
 // NewCompositeStructReconciler creates a CompositeStructReconciler for the
-// stateType. Verifies that the fieldReconciler map contains entries for
-// all the fields in the stateType struct
-func NewCompositeStructReconciler(stateType reflect.Type, fieldReconcilers map[string]testsuite.Reconciler) (*CompositeStructReconciler, error) {
+// stateType. The fieldReconcilers map is keyed by the Go name or the json
+// name of the fields. Verifies that it contains entries for all the fields
+// in the stateType struct, unless configured to pass them through
+func NewCompositeStructReconciler(stateType reflect.Type, fieldReconcilers map[string]testsuite.Reconciler, opts ...CompositeOption) (*CompositeStructReconciler, error) {
 	// Validate that the type is a struct or a pointer to a struct
 	if stateType.Kind() != reflect.Struct {
 		if stateType.Kind() != reflect.Ptr {
@@ -33,21 +93,51 @@ func NewCompositeStructReconciler(stateType reflect.Type, fieldReconcilers map[s
 		}
 	}
 
+	options := compositeOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// Get the struct type
 	targetType := getTargetType(stateType)
 
-	// Validate that there's reconcilers for every field in the struct
+	// Match the reconcilers with the fields in the struct
+	fields := map[string]testsuite.Reconciler{}
+	matched := map[string]bool{}
 	numField := targetType.NumField()
 	for i := 0; i < numField; i++ {
-		fieldName := targetType.Field(i).Name
-		if _, ok := fieldReconcilers[fieldName]; !ok {
-			return nil, fmt.Errorf("no field reconciler for field %s", fieldName)
+		field := targetType.Field(i)
+
+		for _, name := range []string{field.Name, jsonName(field)} {
+			if fieldReconciler, ok := fieldReconcilers[name]; ok {
+				fields[field.Name] = fieldReconciler
+				matched[name] = true
+				break
+			}
+		}
+
+		if _, ok := fields[field.Name]; ok {
+			if field.PkgPath != "" {
+				return nil, fmt.Errorf("field %s is not exported", field.Name)
+			}
+			continue
+		}
+
+		if !options.passThrough {
+			return nil, fmt.Errorf("no field reconciler for field %s", field.Name)
+		}
+	}
+
+	for name := range fieldReconcilers {
+		if !matched[name] {
+			return nil, fmt.Errorf("field %s not found in state type", name)
 		}
 	}
 
 	return &CompositeStructReconciler{
 		stateType: stateType,
-		fields:    fieldReconcilers,
+		fields:    fields,
+		options:   options,
 	}, nil
 }
 
@@ -61,54 +151,84 @@ func (r *CompositeStructReconciler) ParseState(stringState string) (interface{},
 	return stateValue.Elem().Interface(), nil
 }
 
+// StateType returns the type of the state, so composite reconcilers can be
+// nested without marshalling the state of each level
+func (r *CompositeStructReconciler) StateType() reflect.Type {
+	return r.stateType
+}
+
 // Reconcile reconciles the current state by delegating the reconciliation of
 // each field into the reconcilers for each field in the stateType
-func (r *CompositeStructReconciler) Reconcile(client client.Client, namespace string, currentStateInterface interface{}) (interface{}, error) {
+func (r *CompositeStructReconciler) Reconcile(client client.Client, namespace string, currentStateInterface interface{}, logger logr.Logger) (interface{}, error) {
 	targetType := r.getTargetType()
 	result := reflect.New(targetType)
-	currentState := reflect.ValueOf(currentStateInterface)
 
-	// Reconcile each field
+	// Accept the struct or a pointer to it, treating nil as the zero value
+	currentState := reflect.Indirect(reflect.ValueOf(currentStateInterface))
+	if !currentState.IsValid() {
+		currentState = reflect.Zero(targetType)
+	}
+	if currentState.Type() != targetType {
+		return nil, fmt.Errorf("expected state of type %s, got %s", targetType, currentState.Type())
+	}
+
+	// Unmanaged fields keep their current value
+	if r.options.passThrough {
+		result.Elem().Set(currentState)
+	}
+
+	// Reconcile the managed fields, in the order of the struct
+	fieldNames := []string{}
 	numField := targetType.NumField()
 	for i := 0; i < numField; i++ {
-		// Get the field and the field reconciler
-		field := targetType.Field(i)
-		fieldName := field.Name
-		fieldReconciler, ok := r.fields[fieldName]
-		if !ok {
-			return nil, fmt.Errorf("no field reconciler for field %s", fieldName)
+		if _, ok := r.fields[targetType.Field(i).Name]; ok {
+			fieldNames = append(fieldNames, targetType.Field(i).Name)
 		}
+	}
 
-		// Get the field current value
-		currentFieldValue := currentState.FieldByName(fieldName)
-		resultFieldValue := result.Elem().FieldByName(fieldName)
-		if currentFieldValue == (reflect.Value{}) {
-			return nil, fmt.Errorf("field %s not found in state type", fieldName)
-		}
+	values := make([]reflect.Value, len(fieldNames))
+	errs := make([]error, len(fieldNames))
+	reconcileField := func(i int) {
+		values[i], errs[i] = r.reconcileField(client, namespace, fieldNames[i], currentState.FieldByName(fieldNames[i]), logger)
+	}
 
-		// Marshal the value to be passed to the field reconciler
-		currentFieldJSON, err := json.Marshal(currentFieldValue.Interface())
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal field %s value", fieldName)
+	if r.options.concurrent {
+		wg := sync.WaitGroup{}
+		for i := range fieldNames {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				reconcileField(i)
+			}(i)
 		}
-
-		currentField, err := fieldReconciler.ParseState(string(currentFieldJSON))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse field %s: %w", fieldName, err)
+		wg.Wait()
+	} else {
+		for i := range fieldNames {
+			reconcileField(i)
+			if errs[i] != nil && !r.options.aggregateErrors {
+				break
+			}
 		}
+	}
 
-		// Reconcile the field
-		reconciledFieldValue, err := fieldReconciler.Reconcile(
-			client,
-			namespace,
-			currentField,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to reconciled field %s: %w", fieldName, err)
+	failed := []error{}
+	for i, fieldName := range fieldNames {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
 		}
 
 		// Set the field value to the reconciled one
-		resultFieldValue.Set(reflect.ValueOf(reconciledFieldValue))
+		if values[i].IsValid() {
+			result.Elem().FieldByName(fieldName).Set(values[i])
+		}
+	}
+
+	if len(failed) > 0 {
+		if r.options.aggregateErrors {
+			return nil, utilerrors.NewAggregate(failed)
+		}
+		return nil, failed[0]
 	}
 
 	resultValue := result
@@ -119,6 +239,94 @@ func (r *CompositeStructReconciler) Reconcile(client client.Client, namespace st
 	return resultValue.Interface(), nil
 }
 
+// reconcileField reconciles the current value of a field, returning the
+// reconciled value
+func (r *CompositeStructReconciler) reconcileField(client client.Client, namespace, fieldName string, currentFieldValue reflect.Value, logger logr.Logger) (reflect.Value, error) {
+	fieldReconciler := r.fields[fieldName]
+
+	currentField, err := parseField(fieldReconciler, currentFieldValue)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to parse field %s: %w", fieldName, err)
+	}
+
+	reconciledField, err := r.reconcileWithTimeout(func() (interface{}, error) {
+		return fieldReconciler.Reconcile(
+			client,
+			namespace,
+			currentField,
+			logger.WithValues("field", fieldName),
+		)
+	})
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to reconcile field %s: %w", fieldName, err)
+	}
+
+	// Nil values result in the zero value of the field
+	reconciledFieldValue := reflect.ValueOf(reconciledField)
+	if !reconciledFieldValue.IsValid() {
+		return reflect.Zero(currentFieldValue.Type()), nil
+	}
+	if !reconciledFieldValue.Type().AssignableTo(currentFieldValue.Type()) {
+		return reflect.Value{}, fmt.Errorf("reconciled value of type %s can't be assigned to field %s of type %s",
+			reconciledFieldValue.Type(), fieldName, currentFieldValue.Type())
+	}
+
+	return reconciledFieldValue, nil
+}
+
+func (r *CompositeStructReconciler) reconcileWithTimeout(reconcile func() (interface{}, error)) (interface{}, error) {
+	if r.options.fieldTimeout == 0 {
+		return reconcile()
+	}
+
+	type reconcileResult struct {
+		value interface{}
+		err   error
+	}
+
+	// The channel is buffered so the goroutine finishes after a timeout,
+	// whenever the field reconciler returns
+	done := make(chan reconcileResult, 1)
+	go func() {
+		value, err := reconcile()
+		done <- reconcileResult{value, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-time.After(r.options.fieldTimeout):
+		return nil, fmt.Errorf("timed out after %v", r.options.fieldTimeout)
+	}
+}
+
+// parseField converts the field value into the state of the field
+// reconciler. Values are passed directly to typed reconcilers, and through
+// JSON to the rest
+func parseField(fieldReconciler testsuite.Reconciler, value reflect.Value) (interface{}, error) {
+	if typedReconciler, ok := fieldReconciler.(TypedReconciler); ok && value.Type().AssignableTo(typedReconciler.StateType()) {
+		return value.Interface(), nil
+	}
+
+	// Marshal the value to be passed to the field reconciler
+	currentFieldJSON, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return fieldReconciler.ParseState(string(currentFieldJSON))
+}
+
+// jsonName returns the name of the field in its JSON encoding
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
+
 func (r *CompositeStructReconciler) getTargetType() reflect.Type {
 	return getTargetType(r.stateType)
 }
@@ -2,11 +2,17 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -42,7 +48,7 @@ func TestCompositeStructReconciler(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	result, err := compositeReconciler.Reconcile(client, "", currentState)
+	result, err := compositeReconciler.Reconcile(client, "", currentState, ctrl.Log)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,6 +74,185 @@ func TestCompositeStructReconciler(t *testing.T) {
 	}
 }
 
+func TestCompositeStructReconcilerOptions(t *testing.T) {
+	failing := &funcReconciler{reconcile: func(interface{}) (interface{}, error) {
+		return nil, errors.New("unavailable")
+	}}
+	slow := &funcReconciler{reconcile: func(state interface{}) (interface{}, error) {
+		time.Sleep(time.Second)
+		return state, nil
+	}}
+
+	scenarios := []struct {
+		Name        string
+		Reconcilers map[string]testsuite.Reconciler
+		Options     []CompositeOption
+		State       interface{}
+		Expected    interface{}
+		Errors      []string
+	}{
+		{
+			Name: "Fields matched by json name",
+			Reconcilers: map[string]testsuite.Reconciler{
+				"foo": &FooReconciler{},
+				"Bar": &BarReconciler{},
+			},
+			State:    testStruct{Foo: &foo{A: "a", B: "b"}, Bar: &bar{A: 1}},
+			Expected: testStruct{Foo: &foo{A: "a foo", B: "foo b"}, Bar: &bar{A: 2, B: 1}},
+		},
+		{
+			Name:        "Unmanaged fields are passed through",
+			Reconcilers: map[string]testsuite.Reconciler{"bar": &BarReconciler{}},
+			Options:     []CompositeOption{WithPassThrough()},
+			State:       testStruct{Foo: &foo{A: "a"}, Bar: &bar{A: 1}},
+			Expected:    testStruct{Foo: &foo{A: "a"}, Bar: &bar{A: 2, B: 1}},
+		},
+		{
+			Name: "Concurrent fields with timeout",
+			Reconcilers: map[string]testsuite.Reconciler{
+				"foo": &FooReconciler{},
+				"bar": slow,
+			},
+			Options: []CompositeOption{WithConcurrency(), WithFieldTimeout(100 * time.Millisecond)},
+			State:   testStruct{Foo: &foo{}, Bar: &bar{}},
+			Errors:  []string{"failed to reconcile field Bar: timed out after 100ms"},
+		},
+		{
+			Name: "First error aborts",
+			Reconcilers: map[string]testsuite.Reconciler{
+				"foo": failing,
+				"bar": failing,
+			},
+			State:  testStruct{},
+			Errors: []string{"field Foo: unavailable"},
+		},
+		{
+			Name: "Aggregated errors",
+			Reconcilers: map[string]testsuite.Reconciler{
+				"foo": failing,
+				"bar": failing,
+			},
+			Options: []CompositeOption{WithAggregatedErrors()},
+			State:   testStruct{},
+			Errors:  []string{"field Foo: unavailable", "field Bar: unavailable"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			compositeReconciler, err := NewCompositeStructReconciler(reflect.TypeOf(testStruct{}), scenario.Reconcilers, scenario.Options...)
+			if err != nil {
+				t.Fatalf("unexpected error instantiating reconciler: %v", err)
+			}
+
+			result, err := compositeReconciler.Reconcile(fake.NewFakeClient(), "", scenario.State, ctrl.Log)
+			if len(scenario.Errors) > 0 {
+				if err == nil {
+					t.Fatalf("expected errors %v", scenario.Errors)
+				}
+				for _, message := range scenario.Errors {
+					if !strings.Contains(err.Error(), message) {
+						t.Errorf("expected error to contain %s, got %v", message, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(result, scenario.Expected) {
+				t.Errorf("unmatching resulting value. Expected %v, got %v", scenario.Expected, result)
+			}
+		})
+	}
+}
+
+func TestCompositeStructReconcilerValidation(t *testing.T) {
+	if _, err := NewCompositeStructReconciler(reflect.TypeOf(testStruct{}), map[string]testsuite.Reconciler{
+		"foo": &FooReconciler{},
+	}); err == nil {
+		t.Errorf("expected error for missing field reconciler")
+	}
+
+	if _, err := NewCompositeStructReconciler(reflect.TypeOf(testStruct{}), map[string]testsuite.Reconciler{
+		"baz": &FooReconciler{},
+	}, WithPassThrough()); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func TestNestedCompositeStructReconciler(t *testing.T) {
+	parsed := int32(0)
+	counting := &funcReconciler{
+		parseState: func(state string) (interface{}, error) {
+			atomic.AddInt32(&parsed, 1)
+			return (&FooReconciler{}).ParseState(state)
+		},
+		reconcile: func(state interface{}) (interface{}, error) {
+			return (&FooReconciler{}).Reconcile(nil, "", state, ctrl.Log)
+		},
+	}
+
+	inner, err := NewCompositeStructReconciler(reflect.TypeOf(&innerStruct{}), map[string]testsuite.Reconciler{
+		"foo": counting,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outer, err := NewCompositeStructReconciler(reflect.TypeOf(outerStruct{}), map[string]testsuite.Reconciler{
+		"inner": inner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentState, err := outer.ParseState(`{"inner":{"foo":{"A":"a","B":"b"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := outer.Reconcile(fake.NewFakeClient(), "", currentState, ctrl.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedResult := outerStruct{Inner: &innerStruct{Foo: &foo{A: "a foo", B: "foo b"}}}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Errorf("unmatching resulting value. Expected %v, got %v", expectedResult, result)
+	}
+
+	// Only the leaf reconciler parses its state
+	if parsed != 1 {
+		t.Errorf("expected the state to be parsed once, got %d", parsed)
+	}
+}
+
+type innerStruct struct {
+	Foo *foo `json:"foo"`
+}
+
+type outerStruct struct {
+	Inner *innerStruct `json:"inner"`
+}
+
+type funcReconciler struct {
+	parseState func(string) (interface{}, error)
+	reconcile  func(interface{}) (interface{}, error)
+}
+
+func (r *funcReconciler) ParseState(state string) (interface{}, error) {
+	if r.parseState == nil {
+		return nil, nil
+	}
+	return r.parseState(state)
+}
+
+func (r *funcReconciler) Reconcile(_ client.Client, _ string, currentState interface{}, _ logr.Logger) (interface{}, error) {
+	return r.reconcile(currentState)
+}
+
 type foo struct {
 	A string
 	B string
@@ -97,7 +282,7 @@ func (r *FooReconciler) ParseState(state string) (interface{}, error) {
 
 	return currentState, nil
 }
-func (r *FooReconciler) Reconcile(_ client.Client, _ string, currentState interface{}) (interface{}, error) {
+func (r *FooReconciler) Reconcile(_ client.Client, _ string, currentState interface{}, _ logr.Logger) (interface{}, error) {
 	return &foo{
 		A: fmt.Sprintf("%s foo", currentState.(*foo).A),
 		B: fmt.Sprintf("foo %s", currentState.(*foo).B),
@@ -111,7 +296,7 @@ func (r *BarReconciler) ParseState(state string) (interface{}, error) {
 
 	return currentState, nil
 }
-func (r *BarReconciler) Reconcile(_ client.Client, _ string, currentState interface{}) (interface{}, error) {
+func (r *BarReconciler) Reconcile(_ client.Client, _ string, currentState interface{}, _ logr.Logger) (interface{}, error) {
 	return &bar{
 		A: currentState.(*bar).A + 1,
 		B: currentState.(*bar).B + 1,
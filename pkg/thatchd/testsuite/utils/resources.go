@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResourceState is the state reconciled by the ResourceReconciler, mapping
+// the name of each resource to the fields extracted from it
+type ResourceState map[string]map[string]interface{}
+
+// Configuration keys of the ResourceReconciler
+const (
+	ResourceAPIVersionKey    = "apiVersion"
+	ResourceKindKey          = "kind"
+	ResourceLabelSelectorKey = "labelSelector"
+	ResourceFieldSelectorKey = "fieldSelector"
+	// ResourceJSONPathPrefix prefixes the fields extracted with a JSONPath
+	// expression, such as "jsonPath.replicas": "{.status.readyReplicas}"
+	ResourceJSONPathPrefix = "jsonPath."
+	// ResourceConditionPrefix prefixes the fields set to whether a condition
+	// of the resource is true, such as "condition.available": "Available"
+	ResourceConditionPrefix = "condition."
+)
+
+// ResourceReconciler reconciles the state of the resources of any kind in
+// the namespace, including custom resources that aren't in the manager
+// scheme, extracting fields from each of them with JSONPath expressions or
+// from their conditions
+type ResourceReconciler struct {
+	gvk        schema.GroupVersionKind
+	listOpts   []client.ListOption
+	jsonPaths  map[string]*jsonpath.JSONPath
+	conditions map[string]string
+}
+
+var _ testsuite.Reconciler = &ResourceReconciler{}
+var _ testsuite.SchemaProvider = &ResourceReconciler{}
+
+// NewResourceReconciler creates a ResourceReconciler from the strategy
+// configuration
+func NewResourceReconciler(configuration map[string]string) (*ResourceReconciler, error) {
+	if configuration[ResourceAPIVersionKey] == "" || configuration[ResourceKindKey] == "" {
+		return nil, fmt.Errorf("%s and %s are required", ResourceAPIVersionKey, ResourceKindKey)
+	}
+
+	groupVersion, err := schema.ParseGroupVersion(configuration[ResourceAPIVersionKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ResourceAPIVersionKey, err)
+	}
+
+	r := &ResourceReconciler{
+		gvk:        groupVersion.WithKind(configuration[ResourceKindKey]),
+		jsonPaths:  map[string]*jsonpath.JSONPath{},
+		conditions: map[string]string{},
+	}
+
+	if selector := configuration[ResourceLabelSelectorKey]; selector != "" {
+		labelSelector, err := labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ResourceLabelSelectorKey, err)
+		}
+		r.listOpts = append(r.listOpts, client.MatchingLabelsSelector{Selector: labelSelector})
+	}
+	if selector := configuration[ResourceFieldSelectorKey]; selector != "" {
+		fieldSelector, err := fields.ParseSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ResourceFieldSelectorKey, err)
+		}
+		r.listOpts = append(r.listOpts, client.MatchingFieldsSelector{Selector: fieldSelector})
+	}
+
+	for key, value := range configuration {
+		switch {
+		case strings.HasPrefix(key, ResourceJSONPathPrefix):
+			name := strings.TrimPrefix(key, ResourceJSONPathPrefix)
+			path := jsonpath.New(name).AllowMissingKeys(true)
+			if err := path.Parse(value); err != nil {
+				return nil, fmt.Errorf("invalid JSONPath for %s: %w", name, err)
+			}
+			r.jsonPaths[name] = path
+		case strings.HasPrefix(key, ResourceConditionPrefix):
+			r.conditions[strings.TrimPrefix(key, ResourceConditionPrefix)] = value
+		}
+	}
+
+	return r, nil
+}
+
+// ResourcesProvider is the name the ResourceReconciler provider is registered
+// with by the manager
+const ResourcesProvider = "Resources"
+
+// NewResourceReconcilerProvider returns the provider of ResourceReconcilers,
+// to be registered with the strategy providers of the manager. Invalid
+// configurations are reported when reconciling
+func NewResourceReconcilerProvider() strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		r, err := NewResourceReconciler(configuration)
+		if err != nil {
+			return &invalidReconciler{err: err}
+		}
+
+		return r
+	})
+}
+
+func (r *ResourceReconciler) ParseState(state string) (interface{}, error) {
+	return r.mapReconciler().ParseState(state)
+}
+
+// StateSchema restricts the state to the configured fields, conditions
+// being booleans
+func (r *ResourceReconciler) StateSchema() *apiextensionsv1.JSONSchemaProps {
+	properties := map[string]apiextensionsv1.JSONSchemaProps{}
+	for name := range r.jsonPaths {
+		properties[name] = apiextensionsv1.JSONSchemaProps{}
+	}
+	for name := range r.conditions {
+		properties[name] = apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	}
+
+	return &apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+			Allows: true,
+			Schema: &apiextensionsv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: properties,
+				AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+					Allows: false,
+				},
+			},
+		},
+	}
+}
+
+// Reconcile lists the resources and extracts the fields of each of them
+func (r *ResourceReconciler) Reconcile(c client.Client, namespace string, currentState interface{}, logger logr.Logger) (interface{}, error) {
+	return r.mapReconciler().Reconcile(c, namespace, currentState, logger)
+}
+
+func (r *ResourceReconciler) mapReconciler() *MapReconciler {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(r.gvk.GroupVersion().WithKind(r.gvk.Kind + "List"))
+
+	return &MapReconciler{
+		stateType: reflect.TypeOf(ResourceState{}),
+		discover:  DiscoverObjects(list, r.listOpts...),
+		entry:     r.extractFields,
+	}
+}
+
+func (r *ResourceReconciler) extractFields(name string, object interface{}, _ interface{}, _ logr.Logger) (interface{}, error) {
+	content := object.(*unstructured.Unstructured).UnstructuredContent()
+	result := map[string]interface{}{}
+
+	names := make([]string, 0, len(r.jsonPaths))
+	for name := range r.jsonPaths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, field := range names {
+		value, err := extractJSONPath(r.jsonPaths[field], content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", field, err)
+		}
+		if value != nil {
+			result[field] = value
+		}
+	}
+
+	for field, conditionType := range r.conditions {
+		result[field] = resource.ConditionStatus(object.(*unstructured.Unstructured), conditionType) == "True"
+	}
+
+	return result, nil
+}
+
+// extractJSONPath returns the value matched by the JSONPath expression, a
+// list of values if it matches several, or nil if it doesn't match
+func extractJSONPath(path *jsonpath.JSONPath, content map[string]interface{}) (interface{}, error) {
+	results, err := path.FindResults(content)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{}
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() {
+				values = append(values, value.Interface())
+			}
+		}
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0], nil
+	}
+
+	return values, nil
+}
+
+// invalidReconciler reports an invalid strategy configuration
+type invalidReconciler struct {
+	err error
+}
+
+func (r *invalidReconciler) ParseState(string) (interface{}, error) {
+	return nil, r.err
+}
+
+func (r *invalidReconciler) Reconcile(client.Client, string, interface{}, logr.Logger) (interface{}, error) {
+	return nil, r.err
+}
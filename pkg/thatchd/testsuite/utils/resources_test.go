@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thatchd/thatchd/pkg/thatchd/schema"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestResourceReconciler(t *testing.T) {
+	provider := NewResourceReconcilerProvider()
+	reconciler, err := testsuite.FromStrategy(&strategy.Strategy{
+		Provider: "Resources",
+		Configuration: map[string]string{
+			"apiVersion":         "apps/v1",
+			"kind":               "Deployment",
+			"labelSelector":      "app=test",
+			"jsonPath.replicas":  "{.status.readyReplicas}",
+			"jsonPath.images":    "{.spec.template.spec.containers[*].image}",
+			"condition.progress": "Progressing",
+			"condition.ready":    "Available",
+		},
+	}, map[string]strategy.StrategyProvider{"Resources": provider})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := fake.NewFakeClient(
+		&appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "a", Namespace: "thatchd", Labels: map[string]string{"app": "test"}},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Image: "app:v1"}, {Image: "proxy:v1"}},
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				ReadyReplicas: 2,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+					{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse},
+				},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "b", Namespace: "thatchd", Labels: map[string]string{"app": "test"}},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "app:v1"}}},
+				},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "unlabeled", Namespace: "thatchd"},
+		},
+	)
+
+	currentState, err := reconciler.ParseState(`{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := reconciler.Reconcile(c, "thatchd", currentState, ctrl.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ResourceState{
+		"a": {
+			"replicas": int64(2),
+			"images":   []interface{}{"app:v1", "proxy:v1"},
+			"ready":    true,
+			"progress": false,
+		},
+		"b": {
+			"images":   "app:v1",
+			"ready":    false,
+			"progress": false,
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unmatching resulting value. Expected %v, got %v", expected, result)
+	}
+
+	// The state is valid for the schema of the reconciler
+	if err := schema.Validate(reconciler.(testsuite.SchemaProvider).StateSchema(), `{"a":{"ready":true,"replicas":2}}`); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if err := schema.Validate(reconciler.(testsuite.SchemaProvider).StateSchema(), `{"a":{"ready":"yes"}}`); err == nil {
+		t.Errorf("expected validation error for non boolean condition")
+	}
+}
+
+func TestResourceReconcilerConfiguration(t *testing.T) {
+	for _, configuration := range []map[string]string{
+		{"kind": "Deployment"},
+		{"apiVersion": "apps/v1/v2", "kind": "Deployment"},
+		{"apiVersion": "apps/v1", "kind": "Deployment", "labelSelector": "app in"},
+		{"apiVersion": "apps/v1", "kind": "Deployment", "jsonPath.replicas": "{.status"},
+	} {
+		if _, err := NewResourceReconciler(configuration); err == nil {
+			t.Errorf("expected error for configuration %v", configuration)
+		}
+
+		reconciler := NewResourceReconcilerProvider().New(configuration).(testsuite.Reconciler)
+		if _, err := reconciler.ParseState(`{}`); err == nil {
+			t.Errorf("expected invalid configuration to be reported")
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"reflect"
+
+	"github.com/go-logr/logr"
+)
+
+// Sticky wraps an EntryReconcilerFunc so entries in a terminal state, such
+// as a Pod that has already been tested, keep their value instead of being
+// reconciled
+func Sticky(entry EntryReconcilerFunc, isTerminal func(current interface{}) bool) EntryReconcilerFunc {
+	return func(key string, object interface{}, current interface{}, logger logr.Logger) (interface{}, error) {
+		if isTerminal(current) {
+			return current, nil
+		}
+
+		return entry(key, object, current, logger)
+	}
+}
+
+// StickyValues returns a function for Sticky that considers terminal the
+// values equal to any of the given ones
+func StickyValues(values ...interface{}) func(current interface{}) bool {
+	return func(current interface{}) bool {
+		for _, value := range values {
+			if reflect.DeepEqual(current, value) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/go-logr/logr"
 	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
 	"github.com/thatchd/thatchd/pkg/thatchd/testsuite"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,7 +37,7 @@ func (r *TestCaseReconciler) ParseState(state string) (interface{}, error) {
 	return result, nil
 }
 
-func (r *TestCaseReconciler) Reconcile(client k8sclient.Client, namespace string, currentState interface{}) (interface{}, error) {
+func (r *TestCaseReconciler) Reconcile(client k8sclient.Client, namespace string, currentState interface{}, _ logr.Logger) (interface{}, error) {
 	testCaseList := &thatchdv1alpha1.TestCaseList{}
 	if err := client.List(context.TODO(), testCaseList, k8sclient.InNamespace(namespace)); err != nil {
 		return nil, fmt.Errorf("failed to list test cases: %w", err)
@@ -7,6 +7,7 @@ import (
 	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -53,7 +54,7 @@ func TestTestCaseReconciler(t *testing.T) {
 			client := fake.NewFakeClientWithScheme(scheme, scenario.TestCases...)
 
 			reconciler := NewTestCaseReconciler()
-			state, err := reconciler.Reconcile(client, "thatchd", scenario.CurrentState)
+			state, err := reconciler.Reconcile(client, "thatchd", scenario.CurrentState, ctrl.Log)
 			if err != nil {
 				t.Fatal(err)
 			}
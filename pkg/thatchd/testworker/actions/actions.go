@@ -0,0 +1,262 @@
+// Package actions provides built-in TestWorker strategies that act on the
+// resources of any kind, configured through the strategy configuration
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the built-in providers
+const (
+	ApplyManifestProvider    = "ApplyManifest"
+	PatchResourceProvider    = "PatchResource"
+	ScaleProvider            = "Scale"
+	DeleteResourceProvider   = "DeleteResource"
+	WaitForConditionProvider = "WaitForCondition"
+)
+
+// Register adds the built-in action providers to the strategy providers
+func Register(providers map[string]strategy.StrategyProvider) {
+	providers[ApplyManifestProvider] = provider(false, applyManifest)
+	providers[PatchResourceProvider] = provider(true, patchResource)
+	providers[ScaleProvider] = provider(true, scale)
+	providers[DeleteResourceProvider] = provider(true, deleteResource)
+	providers[WaitForConditionProvider] = provider(true, waitForCondition)
+}
+
+// actionFunc acts on the resources selected by the target, if any
+type actionFunc func(ctx context.Context, c client.Client, namespace string, target *resource.Target, logger logr.Logger) error
+
+// actionFactory creates the actionFunc from the strategy configuration
+type actionFactory func(configuration map[string]string) (actionFunc, error)
+
+// action is a TestWorker that performs an action and mutates the state with
+// the configured patch when it succeeds
+type action struct {
+	dispatch.Configured
+
+	target     *resource.Target
+	statePatch *thatchdv1alpha1.StatePatch
+	run        actionFunc
+}
+
+var _ testworker.PatchInterface = &action{}
+
+func provider(targeted bool, factory actionFactory) strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		a := &action{Configured: dispatch.Configure(configuration)}
+		if a.Err != nil {
+			return a
+		}
+		if targeted {
+			if a.target, a.Err = resource.NewTarget(configuration); a.Err != nil {
+				return a
+			}
+		}
+		if a.statePatch, a.Err = testworker.ConfiguredStatePatch(configuration); a.Err != nil {
+			return a
+		}
+		a.run, a.Err = factory(configuration)
+		return a
+	})
+}
+
+func (a *action) RunPatch(ctx context.Context, namespace string, c client.Client, logger logr.Logger) (*thatchdv1alpha1.StatePatch, error) {
+	if err := a.ConfigurationError(); err != nil {
+		return nil, err
+	}
+
+	if err := a.run(ctx, c, namespace, a.target, logger); err != nil {
+		return nil, err
+	}
+
+	return a.statePatch, nil
+}
+
+// forEach runs the action on each of the resources selected by the target,
+// failing if there are none
+func forEach(run func(ctx context.Context, c client.Client, object *unstructured.Unstructured, logger logr.Logger) error) actionFunc {
+	return func(ctx context.Context, c client.Client, namespace string, target *resource.Target, logger logr.Logger) error {
+		objects, err := target.List(ctx, c, namespace)
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return fmt.Errorf("no %s found", target)
+		}
+
+		for i := range objects {
+			if err := run(ctx, c, &objects[i], logger.WithValues("name", objects[i].GetName())); err != nil {
+				return fmt.Errorf("failed on %s: %w", objects[i].GetName(), err)
+			}
+		}
+
+		return nil
+	}
+}
+
+func applyManifest(configuration map[string]string) (actionFunc, error) {
+	manifest := configuration["manifest"]
+	if manifest == "" {
+		return nil, fmt.Errorf("manifest is required")
+	}
+
+	objects, err := resource.DecodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, c client.Client, namespace string, _ *resource.Target, logger logr.Logger) error {
+		for _, object := range objects {
+			object := object.DeepCopy()
+			if object.GetNamespace() == "" {
+				object.SetNamespace(namespace)
+			}
+
+			logger.Info("applying resource", "kind", object.GetKind(), "name", object.GetName())
+			if err := apply(ctx, c, object); err != nil {
+				return fmt.Errorf("failed to apply %s %s: %w", object.GetKind(), object.GetName(), err)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+// apply creates the object, or updates it if it already exists
+func apply(ctx context.Context, c client.Client, object *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(object.GroupVersionKind())
+
+	err := c.Get(ctx, client.ObjectKey{Namespace: object.GetNamespace(), Name: object.GetName()}, existing)
+	if errors.IsNotFound(err) {
+		return c.Create(ctx, object)
+	}
+	if err != nil {
+		return err
+	}
+
+	object.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, object)
+}
+
+func patchResource(configuration map[string]string) (actionFunc, error) {
+	patch := configuration["patch"]
+	if patch == "" {
+		return nil, fmt.Errorf("patch is required")
+	}
+
+	patchType := types.MergePatchType
+	switch configuration["patchType"] {
+	case "", "MergePatch":
+	case "JSONPatch":
+		patchType = types.JSONPatchType
+	default:
+		return nil, fmt.Errorf("unsupported patchType %s", configuration["patchType"])
+	}
+
+	return forEach(func(ctx context.Context, c client.Client, object *unstructured.Unstructured, logger logr.Logger) error {
+		logger.Info("patching resource", "kind", object.GetKind())
+		return c.Patch(ctx, object, client.RawPatch(patchType, []byte(patch)))
+	}), nil
+}
+
+func scale(configuration map[string]string) (actionFunc, error) {
+	replicas, err := strconv.Atoi(configuration["replicas"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid replicas: %w", err)
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+
+	return forEach(func(ctx context.Context, c client.Client, object *unstructured.Unstructured, logger logr.Logger) error {
+		logger.Info("scaling resource", "kind", object.GetKind(), "replicas", replicas)
+		return c.Patch(ctx, object, client.RawPatch(types.MergePatchType, patch))
+	}), nil
+}
+
+func deleteResource(_ map[string]string) (actionFunc, error) {
+	return func(ctx context.Context, c client.Client, namespace string, target *resource.Target, logger logr.Logger) error {
+		objects, err := target.List(ctx, c, namespace)
+		if err != nil {
+			return err
+		}
+
+		for i := range objects {
+			logger.Info("deleting resource", "kind", objects[i].GetKind(), "name", objects[i].GetName())
+			if err := c.Delete(ctx, &objects[i]); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s: %w", objects[i].GetName(), err)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+func waitForCondition(configuration map[string]string) (actionFunc, error) {
+	conditionType := configuration["conditionType"]
+	if conditionType == "" {
+		return nil, fmt.Errorf("conditionType is required")
+	}
+
+	status := configuration["status"]
+	if status == "" {
+		status = "True"
+	}
+
+	timeout, err := config.Duration(configuration, "timeout", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := config.Duration(configuration, "interval", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, c client.Client, namespace string, target *resource.Target, logger logr.Logger) error {
+		logger.Info("waiting for condition", "target", target.String(), "condition", conditionType, "status", status)
+
+		// The wait stops at the timeout, or once the worker is canceled
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := wait.PollImmediateUntil(interval, func() (bool, error) {
+			objects, err := target.List(ctx, c, namespace)
+			if err != nil || len(objects) == 0 {
+				return false, err
+			}
+
+			for i := range objects {
+				if resource.ConditionStatus(&objects[i], conditionType) != status {
+					return false, nil
+				}
+			}
+
+			return true, nil
+		}, waitCtx.Done())
+		if err == wait.ErrWaitTimeout {
+			if ctx.Err() != nil {
+				return fmt.Errorf("stopped waiting for condition %s of %s: %w", conditionType, target, ctx.Err())
+			}
+			return fmt.Errorf("condition %s of %s wasn't %s after %v", conditionType, target, status, timeout)
+		}
+
+		return err
+	}, nil
+}
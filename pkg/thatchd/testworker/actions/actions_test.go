@@ -0,0 +1,253 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource/resourcetest"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestActions(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Provider      string
+		Configuration map[string]string
+		// Canceled runs the worker with a canceled context
+		Canceled   bool
+		Error      string
+		StatePatch *thatchdv1alpha1.StatePatch
+		Verify     func(c client.Client) error
+	}{
+		{
+			Name:     "Apply manifest",
+			Provider: ApplyManifestProvider,
+			Configuration: map[string]string{
+				"manifest": `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: created
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ready
+  labels:
+    app: updated
+`,
+				testworker.StatePatchKey: `{"applied":true}`,
+			},
+			StatePatch: testworker.MergePatch(`{"applied":true}`),
+			Verify: func(c client.Client) error {
+				configMap := &corev1.ConfigMap{}
+				if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "thatchd", Name: "created"}, configMap); err != nil {
+					return err
+				}
+				if configMap.Data["key"] != "value" {
+					return fmt.Errorf("expected data key=value, got %v", configMap.Data)
+				}
+
+				return expectDeployment(c, "ready", func(deployment *appsv1.Deployment) error {
+					if deployment.Labels["app"] != "updated" {
+						return fmt.Errorf("expected label app=updated, got %v", deployment.Labels)
+					}
+					return nil
+				})
+			},
+		},
+		{
+			Name:     "Patch resource",
+			Provider: PatchResourceProvider,
+			Configuration: map[string]string{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       "ready",
+				"patchType":  "JSONPatch",
+				"patch":      `[{"op":"add","path":"/metadata/annotations","value":{"patched":"true"}}]`,
+			},
+			Verify: func(c client.Client) error {
+				return expectDeployment(c, "ready", func(deployment *appsv1.Deployment) error {
+					if deployment.Annotations["patched"] != "true" {
+						return fmt.Errorf("expected annotation patched=true, got %v", deployment.Annotations)
+					}
+					return nil
+				})
+			},
+		},
+		{
+			Name:     "Scale",
+			Provider: ScaleProvider,
+			Configuration: map[string]string{
+				"apiVersion":                 "apps/v1",
+				"kind":                       "Deployment",
+				"labelSelector":              "app=test",
+				"replicas":                   "0",
+				testworker.StatePatchKey:     `[{"op":"add","path":"/scaled","value":true}]`,
+				testworker.StatePatchTypeKey: "JSONPatch",
+			},
+			StatePatch: testworker.JSONPatch(`[{"op":"add","path":"/scaled","value":true}]`),
+			Verify: func(c client.Client) error {
+				for _, name := range []string{"ready", "unavailable"} {
+					if err := expectDeployment(c, name, func(deployment *appsv1.Deployment) error {
+						if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 0 {
+							return fmt.Errorf("expected %s to be scaled to 0", name)
+						}
+						return nil
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:     "Scale missing resource",
+			Provider: ScaleProvider,
+			Configuration: map[string]string{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       "deleted",
+				"replicas":   "1",
+			},
+			Error: "no Deployment deleted found",
+		},
+		{
+			Name:     "Delete resource",
+			Provider: DeleteResourceProvider,
+			Configuration: map[string]string{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       "unavailable",
+			},
+			Verify: func(c client.Client) error {
+				list := &appsv1.DeploymentList{}
+				if err := c.List(context.TODO(), list); err != nil {
+					return err
+				}
+				if len(list.Items) != 1 || list.Items[0].Name != "ready" {
+					return fmt.Errorf("expected only the ready deployment, got %v", list.Items)
+				}
+				return nil
+			},
+		},
+		{
+			Name:     "Wait for condition",
+			Provider: WaitForConditionProvider,
+			Configuration: map[string]string{
+				"apiVersion":             "apps/v1",
+				"kind":                   "Deployment",
+				"name":                   "ready",
+				"conditionType":          "Available",
+				testworker.StatePatchKey: `{"available":true}`,
+			},
+			StatePatch: testworker.MergePatch(`{"available":true}`),
+		},
+		{
+			Name:     "Wait for condition timeout",
+			Provider: WaitForConditionProvider,
+			Configuration: map[string]string{
+				"apiVersion":    "apps/v1",
+				"kind":          "Deployment",
+				"labelSelector": "app=test",
+				"conditionType": "Available",
+				"timeout":       "50ms",
+				"interval":      "10ms",
+			},
+			Error: "condition Available of Deployment app=test wasn't True after 50ms",
+		},
+		{
+			Name:     "Wait for condition canceled",
+			Provider: WaitForConditionProvider,
+			Configuration: map[string]string{
+				"apiVersion":    "apps/v1",
+				"kind":          "Deployment",
+				"labelSelector": "app=test",
+				"conditionType": "Available",
+			},
+			Canceled: true,
+			Error:    "stopped waiting for condition Available of Deployment app=test: context canceled",
+		},
+		{
+			Name:     "Invalid configuration",
+			Provider: ScaleProvider,
+			Configuration: map[string]string{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       "ready",
+				"replicas":   "many",
+			},
+			Error: `invalid configuration: invalid replicas: strconv.Atoi: parsing "many": invalid syntax`,
+		},
+	}
+
+	providers := map[string]strategy.StrategyProvider{}
+	Register(providers)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			testWorker, err := testworker.FromStrategy(&strategy.Strategy{
+				Provider:      scenario.Provider,
+				Configuration: scenario.Configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testWorker.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test worker to be dispatched")
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			if scenario.Canceled {
+				cancel()
+			}
+
+			c := fake.NewFakeClient(resourcetest.Deployments()...)
+			statePatch, err := testWorker.(testworker.PatchInterface).RunPatch(ctx, "thatchd", c, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if scenario.StatePatch == nil && statePatch != nil {
+				t.Errorf("unexpected state patch %v", statePatch)
+			}
+			if scenario.StatePatch != nil && (statePatch == nil || *statePatch != *scenario.StatePatch) {
+				t.Errorf("expected state patch %v, got %v", scenario.StatePatch, statePatch)
+			}
+
+			if scenario.Verify != nil {
+				if err := scenario.Verify(c); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func expectDeployment(c client.Client, name string, verify func(*appsv1.Deployment) error) error {
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "thatchd", Name: name}, deployment); err != nil {
+		return err
+	}
+
+	return verify(deployment)
+}
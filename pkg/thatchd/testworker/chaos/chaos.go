@@ -0,0 +1,176 @@
+// Package chaos provides built-in TestWorker strategies that inject failures
+// into the cluster. They record what they do in the TestWorker status, so
+// their changes are reverted if the worker is canceled
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the built-in providers
+const (
+	KillPodsProvider       = "KillPods"
+	EvictPodsProvider      = "EvictPods"
+	DrainNodeProvider      = "DrainNode"
+	IsolateNetworkProvider = "IsolateNetwork"
+	ScaleToZeroProvider    = "ScaleToZero"
+)
+
+// Types of the recorded actions
+const (
+	PodKilledAction            = "PodKilled"
+	PodEvictedAction           = "PodEvicted"
+	NodeCordonedAction         = "NodeCordoned"
+	NetworkPolicyCreatedAction = "NetworkPolicyCreated"
+	ScaledToZeroAction         = "ScaledToZero"
+)
+
+// DurationKey is the time the changes are kept before the worker reverts
+// them. Changes are kept after the worker finishes when it's not set
+const DurationKey = "duration"
+
+// Register adds the built-in chaos providers to the strategy providers. The
+// clientset is used for the requests the controller-runtime client doesn't
+// support, such as evictions
+func Register(providers map[string]strategy.StrategyProvider, clientset kubernetes.Interface) {
+	providers[KillPodsProvider] = provider(killPods)
+	providers[EvictPodsProvider] = provider(evictPods(clientset))
+	providers[DrainNodeProvider] = provider(drainNode(clientset))
+	providers[IsolateNetworkProvider] = provider(isolateNetwork)
+	providers[ScaleToZeroProvider] = provider(scaleToZero)
+}
+
+// chaosFunc injects the failure, recording the actions it makes
+type chaosFunc func(ctx context.Context, c client.Client, namespace string, recorder testworker.Recorder, logger logr.Logger) error
+
+// chaosFactory creates the chaosFunc from the strategy configuration
+type chaosFactory func(configuration map[string]string) (chaosFunc, error)
+
+// chaos is a TestWorker that injects a failure, optionally reverting it
+// after a duration, and mutates the state with the configured patch when it
+// succeeds
+type chaos struct {
+	dispatch.Configured
+
+	statePatch *thatchdv1alpha1.StatePatch
+	duration   time.Duration
+	run        chaosFunc
+}
+
+var _ testworker.RecordingInterface = &chaos{}
+
+func provider(factory chaosFactory) strategy.StrategyProvider {
+	return strategy.NewProviderFunction(func(configuration map[string]string) interface{} {
+		w := &chaos{Configured: dispatch.Configure(configuration)}
+		if w.Err != nil {
+			return w
+		}
+		if w.statePatch, w.Err = testworker.ConfiguredStatePatch(configuration); w.Err != nil {
+			return w
+		}
+		if w.duration, w.Err = config.Duration(configuration, DurationKey, 0); w.Err != nil {
+			return w
+		}
+		w.run, w.Err = factory(configuration)
+		return w
+	})
+}
+
+func (w *chaos) RunRecorded(ctx context.Context, namespace string, c client.Client, recorder testworker.Recorder, logger logr.Logger) (*thatchdv1alpha1.StatePatch, error) {
+	if err := w.ConfigurationError(); err != nil {
+		return nil, err
+	}
+
+	if err := w.run(ctx, c, namespace, recorder, logger); err != nil {
+		return nil, err
+	}
+
+	if w.duration > 0 {
+		logger.Info("keeping changes", "duration", w.duration)
+		recorder.RevertAfter(w.duration)
+	}
+
+	return w.statePatch, nil
+}
+
+// perform makes the action and records it. Actions that can be reverted are
+// recorded before being made, so they're reverted even if the manager stops
+// meanwhile, and forgotten if they fail, so nothing they don't own is
+// reverted
+func perform(ctx context.Context, recorder testworker.Recorder, action thatchdv1alpha1.WorkerAction, do func() error) error {
+	if action.Revert != nil {
+		if err := recorder.Record(ctx, action); err != nil {
+			return fmt.Errorf("failed to record action: %w", err)
+		}
+
+		if err := do(); err != nil {
+			if forgetErr := recorder.Forget(ctx, action); forgetErr != nil {
+				return fmt.Errorf("%w; failed to forget action: %v", err, forgetErr)
+			}
+			return err
+		}
+
+		return nil
+	}
+
+	if err := do(); err != nil {
+		return err
+	}
+
+	if err := recorder.Record(ctx, action); err != nil {
+		return fmt.Errorf("failed to record action: %w", err)
+	}
+
+	return nil
+}
+
+// random returns the random source of the configured seed, or of a new seed
+// that is logged so the selection can be reproduced
+func random(configuration map[string]string) (func(logger logr.Logger) *rand.Rand, error) {
+	seedValue, ok := configuration["seed"]
+	if !ok {
+		return func(logger logr.Logger) *rand.Rand {
+			seed := time.Now().UnixNano()
+			logger.Info("generated seed", "seed", seed)
+			return rand.New(rand.NewSource(seed))
+		}, nil
+	}
+
+	seed, err := strconv.ParseInt(seedValue, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed: %w", err)
+	}
+
+	return func(logr.Logger) *rand.Rand {
+		return rand.New(rand.NewSource(seed))
+	}, nil
+}
+
+// pick returns up to count of the names, shuffled by the random source.
+// Names are sorted first, so the same seed picks the same names
+func pick(names []string, count int, random *rand.Rand) []string {
+	sort.Strings(names)
+	random.Shuffle(len(names), func(i, j int) {
+		names[i], names[j] = names[j], names[i]
+	})
+
+	if count < len(names) {
+		return names[:count]
+	}
+
+	return names
+}
@@ -0,0 +1,311 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource/resourcetest"
+	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testRecorder records the actions in memory
+type testRecorder struct {
+	client      client.Client
+	actions     []thatchdv1alpha1.WorkerAction
+	revertAfter time.Duration
+}
+
+func (r *testRecorder) Record(ctx context.Context, action thatchdv1alpha1.WorkerAction) error {
+	r.actions = append(r.actions, action)
+	return nil
+}
+
+func (r *testRecorder) Forget(ctx context.Context, action thatchdv1alpha1.WorkerAction) error {
+	for i := range r.actions {
+		if r.actions[i].Type == action.Type && r.actions[i].Name == action.Name {
+			r.actions = append(r.actions[:i], r.actions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("action %s %s wasn't recorded", action.Type, action.Name)
+}
+
+func (r *testRecorder) RevertAfter(duration time.Duration) {
+	r.revertAfter = duration
+}
+
+func (r *testRecorder) Revert(ctx context.Context) error {
+	for i := range r.actions {
+		if err := testworker.RevertAction(ctx, r.client, &r.actions[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func testObjects() []runtime.Object {
+	controller := true
+
+	pod := func(name, node string, owners ...v1.OwnerReference) *corev1.Pod {
+		pod := resourcetest.Pod(name)
+		pod.OwnerReferences = owners
+		pod.Spec.NodeName = node
+		return pod
+	}
+
+	database := resourcetest.Deployment("database", 3)
+	database.Labels = nil
+
+	return []runtime.Object{
+		pod("pod-a", "node-a"),
+		pod("pod-b", "node-a", v1.OwnerReference{Kind: "DaemonSet", Name: "agent", Controller: &controller}),
+		pod("pod-c", "node-b"),
+		&corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node-b"}},
+		database,
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: v1.ObjectMeta{Name: "existing", Namespace: resourcetest.Namespace},
+		},
+	}
+}
+
+func TestChaos(t *testing.T) {
+	scenarios := []struct {
+		Name          string
+		Provider      string
+		Configuration map[string]string
+		Error         string
+		Actions       []string
+		RevertAfter   time.Duration
+		Verify        func(c client.Client, clientset *k8sfake.Clientset) error
+		Reverted      func(c client.Client) error
+	}{
+		{
+			Name:          "Kill pods",
+			Provider:      KillPodsProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "count": "2", "seed": "1"},
+			Actions:       []string{"PodKilled pod-a", "PodKilled pod-c"},
+			Verify: func(c client.Client, _ *k8sfake.Clientset) error {
+				podList := &corev1.PodList{}
+				if err := c.List(context.TODO(), podList); err != nil {
+					return err
+				}
+				if len(podList.Items) != 1 || podList.Items[0].Name != "pod-b" {
+					return fmt.Errorf("expected only pod-b, got %v", podList.Items)
+				}
+				return nil
+			},
+		},
+		{
+			Name:          "Kill missing pods",
+			Provider:      KillPodsProvider,
+			Configuration: map[string]string{"labelSelector": "app=missing"},
+			Error:         "no pods matching app=missing found",
+		},
+		{
+			Name:          "Evict pods",
+			Provider:      EvictPodsProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "seed": "1"},
+			Actions:       []string{"PodEvicted pod-a"},
+			Verify: func(_ client.Client, clientset *k8sfake.Clientset) error {
+				return expectEvictions(clientset, "pod-a")
+			},
+		},
+		{
+			Name:          "Drain node",
+			Provider:      DrainNodeProvider,
+			Configuration: map[string]string{"node": "node-a", "drain": "true"},
+			Actions:       []string{"NodeCordoned node-a", "PodEvicted pod-a"},
+			Verify: func(c client.Client, clientset *k8sfake.Clientset) error {
+				if err := expectUnschedulable(c, true); err != nil {
+					return err
+				}
+				return expectEvictions(clientset, "pod-a")
+			},
+			Reverted: func(c client.Client) error {
+				return expectUnschedulable(c, false)
+			},
+		},
+		{
+			Name:          "Isolate network",
+			Provider:      IsolateNetworkProvider,
+			Configuration: map[string]string{"labelSelector": "app=test", "duration": "10m"},
+			Actions:       []string{"NetworkPolicyCreated thatchd-deny-all"},
+			RevertAfter:   10 * time.Minute,
+			Verify: func(c client.Client, _ *k8sfake.Clientset) error {
+				return expectNetworkPolicy(c, true)
+			},
+			Reverted: func(c client.Client) error {
+				return expectNetworkPolicy(c, false)
+			},
+		},
+		{
+			Name:          "Isolate network with an existing policy",
+			Provider:      IsolateNetworkProvider,
+			Configuration: map[string]string{"networkPolicyName": "existing", "duration": "1h"},
+			Error:         "failed to create network policy existing: it already exists",
+			Reverted: func(c client.Client) error {
+				return c.Get(context.TODO(), types.NamespacedName{Namespace: "thatchd", Name: "existing"}, &networkingv1.NetworkPolicy{})
+			},
+		},
+		{
+			Name:          "Isolate network without duration",
+			Provider:      IsolateNetworkProvider,
+			Configuration: map[string]string{},
+			Error:         "invalid configuration: duration is required",
+		},
+		{
+			Name:          "Scale to zero",
+			Provider:      ScaleToZeroProvider,
+			Configuration: map[string]string{"apiVersion": "apps/v1", "kind": "Deployment", "name": "database"},
+			Actions:       []string{"ScaledToZero database"},
+			Verify: func(c client.Client, _ *k8sfake.Clientset) error {
+				return expectReplicas(c, 0)
+			},
+			Reverted: func(c client.Client) error {
+				return expectReplicas(c, 3)
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			clientset := k8sfake.NewSimpleClientset(testObjects()...)
+			providers := map[string]strategy.StrategyProvider{}
+			Register(providers, clientset)
+
+			testWorker, err := testworker.FromStrategy(&strategy.Strategy{
+				Provider:      scenario.Provider,
+				Configuration: scenario.Configuration,
+			}, providers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !testWorker.ShouldRun(map[string]interface{}{}, ctrl.Log) {
+				t.Errorf("expected test worker to be dispatched")
+			}
+
+			c := fake.NewFakeClient(testObjects()...)
+			recorder := &testRecorder{client: c}
+			_, err = testWorker.(testworker.RecordingInterface).RunRecorded(context.TODO(), "thatchd", c, recorder, ctrl.Log)
+			if scenario.Error != "" {
+				if err == nil || err.Error() != scenario.Error {
+					t.Errorf("expected error %s, got %v", scenario.Error, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			actions := []string{}
+			for _, action := range recorder.actions {
+				description := fmt.Sprintf("%s %s", action.Type, action.Name)
+				if action.RevertedAt != nil {
+					description += " (reverted)"
+				}
+				actions = append(actions, description)
+			}
+			if fmt.Sprint(actions) != fmt.Sprint(scenario.Actions) {
+				t.Errorf("expected actions %v, got %v", scenario.Actions, actions)
+			}
+			if recorder.revertAfter != scenario.RevertAfter {
+				t.Errorf("expected the actions to be reverted after %v, got %v", scenario.RevertAfter, recorder.revertAfter)
+			}
+
+			if scenario.Verify != nil {
+				if err := scenario.Verify(c, clientset); err != nil {
+					t.Error(err)
+				}
+			}
+
+			if scenario.Reverted != nil {
+				if err := recorder.Revert(context.TODO()); err != nil {
+					t.Fatal(err)
+				}
+				if err := scenario.Reverted(c); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func expectEvictions(clientset *k8sfake.Clientset, names ...string) error {
+	evicted := []string{}
+	for _, action := range clientset.Actions() {
+		if action.GetSubresource() == "eviction" {
+			evicted = append(evicted, action.(interface{ GetObject() runtime.Object }).GetObject().(v1.Object).GetName())
+		}
+	}
+
+	if fmt.Sprint(evicted) != fmt.Sprint(names) {
+		return fmt.Errorf("expected evictions of %v, got %v", names, evicted)
+	}
+
+	return nil
+}
+
+func expectUnschedulable(c client.Client, unschedulable bool) error {
+	node := &corev1.Node{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "node-a"}, node); err != nil {
+		return err
+	}
+
+	if node.Spec.Unschedulable != unschedulable {
+		return fmt.Errorf("expected node-a unschedulable to be %v", unschedulable)
+	}
+
+	return nil
+}
+
+func expectNetworkPolicy(c client.Client, exists bool) error {
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "thatchd", Name: defaultNetworkPolicyName}, networkPolicy)
+	if errors.IsNotFound(err) {
+		if exists {
+			return fmt.Errorf("expected network policy to exist")
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("expected network policy to be removed")
+	}
+	if len(networkPolicy.Spec.PolicyTypes) != 2 || len(networkPolicy.Spec.Ingress)+len(networkPolicy.Spec.Egress) > 0 {
+		return fmt.Errorf("expected network policy to deny all traffic, got %v", networkPolicy.Spec)
+	}
+
+	return nil
+}
+
+func expectReplicas(c client.Client, replicas int32) error {
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "thatchd", Name: "database"}, deployment); err != nil {
+		return err
+	}
+
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != replicas {
+		return fmt.Errorf("expected %d replicas, got %v", replicas, deployment.Spec.Replicas)
+	}
+
+	return nil
+}
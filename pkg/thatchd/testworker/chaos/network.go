@@ -0,0 +1,79 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultNetworkPolicyName is the name of the deny-all NetworkPolicy unless
+// configured otherwise
+const defaultNetworkPolicyName = "thatchd-deny-all"
+
+// isolateNetwork creates a NetworkPolicy that denies all the traffic from and
+// to the pods matching the label selector, or all the pods in the namespace,
+// and removes it after the duration
+func isolateNetwork(configuration map[string]string) (chaosFunc, error) {
+	if configuration[DurationKey] == "" {
+		return nil, fmt.Errorf("%s is required", DurationKey)
+	}
+
+	podSelector := &v1.LabelSelector{}
+	if configuration["labelSelector"] != "" {
+		var err error
+		if podSelector, err = v1.ParseToLabelSelector(configuration["labelSelector"]); err != nil {
+			return nil, fmt.Errorf("invalid labelSelector: %w", err)
+		}
+	}
+
+	name := configuration["networkPolicyName"]
+	if name == "" {
+		name = defaultNetworkPolicyName
+	}
+
+	return func(ctx context.Context, c client.Client, namespace string, recorder testworker.Recorder, logger logr.Logger) error {
+		if configuration["namespace"] != "" {
+			namespace = configuration["namespace"]
+		}
+
+		// No rules deny all the traffic of the policy types
+		networkPolicy := &networkingv1.NetworkPolicy{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: *podSelector,
+				PolicyTypes: []networkingv1.PolicyType{
+					networkingv1.PolicyTypeIngress,
+					networkingv1.PolicyTypeEgress,
+				},
+			},
+		}
+
+		logger.Info("isolating pods", "networkPolicy", name, "selector", v1.FormatLabelSelector(podSelector))
+		action := testworker.NewAction(NetworkPolicyCreatedAction, networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy"), networkPolicy, &thatchdv1alpha1.ActionRevert{
+			Type: thatchdv1alpha1.RevertDelete,
+		})
+		if err := perform(ctx, recorder, action, func() error {
+			// An existing policy belongs to someone else, so it's not reverted
+			if err := c.Create(ctx, networkPolicy); errors.IsAlreadyExists(err) {
+				return fmt.Errorf("it already exists")
+			} else if err != nil {
+				return err
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to create network policy %s: %w", name, err)
+		}
+
+		return nil
+	}, nil
+}
@@ -0,0 +1,166 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// mirrorPodAnnotation is set on the pods managed by the kubelet, which can't
+// be evicted
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// drainNode cordons the configured node, or a random one matching the label
+// selector, and evicts its pods if drain is set
+func drainNode(clientset kubernetes.Interface) chaosFactory {
+	return func(configuration map[string]string) (chaosFunc, error) {
+		nodeName := configuration["node"]
+
+		selector := labels.Everything()
+		if configuration["labelSelector"] != "" {
+			var err error
+			if selector, err = labels.Parse(configuration["labelSelector"]); err != nil {
+				return nil, fmt.Errorf("invalid labelSelector: %w", err)
+			}
+		}
+
+		random, err := random(configuration)
+		if err != nil {
+			return nil, err
+		}
+
+		drain := false
+		if configuration["drain"] != "" {
+			if drain, err = strconv.ParseBool(configuration["drain"]); err != nil {
+				return nil, fmt.Errorf("invalid drain: %w", err)
+			}
+		}
+
+		return func(ctx context.Context, c client.Client, _ string, recorder testworker.Recorder, logger logr.Logger) error {
+			node, err := pickNode(ctx, c, nodeName, selector, random(logger))
+			if err != nil {
+				return err
+			}
+			logger = logger.WithValues("node", node.Name)
+
+			if err := cordon(ctx, c, node, recorder, logger); err != nil {
+				return err
+			}
+
+			if !drain {
+				return nil
+			}
+
+			pods, err := drainablePods(ctx, c, node.Name)
+			if err != nil {
+				return err
+			}
+			for i := range pods {
+				if err := evict(ctx, clientset, &pods[i], recorder, logger); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, nil
+	}
+}
+
+func pickNode(ctx context.Context, c client.Client, nodeName string, selector labels.Selector, random *rand.Rand) (*corev1.Node, error) {
+	if nodeName != "" {
+		node := &corev1.Node{}
+		if err := c.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := c.List(ctx, nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]*corev1.Node{}
+	names := []string{}
+	for i := range nodeList.Items {
+		nodes[nodeList.Items[i].Name] = &nodeList.Items[i]
+		names = append(names, nodeList.Items[i].Name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no nodes matching %s found", selector)
+	}
+
+	return nodes[pick(names, 1, random)[0]], nil
+}
+
+// cordon marks the node as unschedulable. Nodes that are already
+// unschedulable are left as they are, so they're not uncordoned on revert
+func cordon(ctx context.Context, c client.Client, node *corev1.Node, recorder testworker.Recorder, logger logr.Logger) error {
+	if node.Spec.Unschedulable {
+		logger.Info("node is already cordoned")
+		return nil
+	}
+
+	logger.Info("cordoning node")
+	action := testworker.NewAction(NodeCordonedAction, corev1.SchemeGroupVersion.WithKind("Node"), node, &thatchdv1alpha1.ActionRevert{
+		Type:  thatchdv1alpha1.RevertMergePatch,
+		Patch: `{"spec":{"unschedulable":null}}`,
+	})
+	if err := perform(ctx, recorder, action, func() error {
+		return c.Patch(ctx, node, client.RawPatch(types.MergePatchType, []byte(`{"spec":{"unschedulable":true}}`)))
+	}); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %w", node.Name, err)
+	}
+
+	return nil
+}
+
+// drainablePods returns the pods running in the node, except the ones
+// managed by the kubelet or a DaemonSet, which would be recreated in it
+func drainablePods(ctx context.Context, c client.Client, nodeName string) ([]corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList); err != nil {
+		return nil, err
+	}
+
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != nodeName || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if ownedByDaemonSet(&pod) {
+			continue
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+func ownedByDaemonSet(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
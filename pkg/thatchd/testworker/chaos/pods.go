@@ -0,0 +1,163 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/go-logr/logr"
+	"github.com/thatchd/thatchd/pkg/thatchd/config"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var podKind = corev1.SchemeGroupVersion.WithKind("Pod")
+
+// podSelection picks random pods matching a label selector
+type podSelection struct {
+	namespace string
+	selector  labels.Selector
+	count     int
+	random    func(logger logr.Logger) *rand.Rand
+}
+
+func newPodSelection(configuration map[string]string) (*podSelection, error) {
+	if configuration["labelSelector"] == "" {
+		return nil, fmt.Errorf("labelSelector is required")
+	}
+
+	selector, err := labels.Parse(configuration["labelSelector"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+
+	count, err := config.Int(configuration, "count", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	random, err := random(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &podSelection{
+		namespace: configuration["namespace"],
+		selector:  selector,
+		count:     count,
+		random:    random,
+	}, nil
+}
+
+// pods returns the picked pods, failing if there are none. Pods that are
+// being deleted aren't picked
+func (s *podSelection) pods(ctx context.Context, c client.Client, namespace string, logger logr.Logger) ([]corev1.Pod, error) {
+	if s.namespace != "" {
+		namespace = s.namespace
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: s.selector}); err != nil {
+		return nil, err
+	}
+
+	pods := map[string]corev1.Pod{}
+	names := []string{}
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp == nil {
+			pods[pod.Name] = pod
+			names = append(names, pod.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no pods matching %s found", s.selector)
+	}
+
+	result := []corev1.Pod{}
+	for _, name := range pick(names, s.count, s.random(logger)) {
+		result = append(result, pods[name])
+	}
+
+	return result, nil
+}
+
+func killPods(configuration map[string]string) (chaosFunc, error) {
+	selection, err := newPodSelection(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod, err := config.Int(configuration, "gracePeriodSeconds", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, c client.Client, namespace string, recorder testworker.Recorder, logger logr.Logger) error {
+		pods, err := selection.pods(ctx, c, namespace, logger)
+		if err != nil {
+			return err
+		}
+
+		for i := range pods {
+			pod := &pods[i]
+			logger.Info("killing pod", "pod", pod.Name)
+
+			action := testworker.NewAction(PodKilledAction, podKind, pod, nil)
+			if err := perform(ctx, recorder, action, func() error {
+				return c.Delete(ctx, pod, client.GracePeriodSeconds(gracePeriod))
+			}); err != nil {
+				return fmt.Errorf("failed to kill pod %s: %w", pod.Name, err)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+func evictPods(clientset kubernetes.Interface) chaosFactory {
+	return func(configuration map[string]string) (chaosFunc, error) {
+		selection, err := newPodSelection(configuration)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context, c client.Client, namespace string, recorder testworker.Recorder, logger logr.Logger) error {
+			pods, err := selection.pods(ctx, c, namespace, logger)
+			if err != nil {
+				return err
+			}
+
+			for i := range pods {
+				if err := evict(ctx, clientset, &pods[i], recorder, logger); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, nil
+	}
+}
+
+// evict evicts the pod, respecting its disruption budget
+func evict(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod, recorder testworker.Recorder, logger logr.Logger) error {
+	logger.Info("evicting pod", "pod", pod.Name, "namespace", pod.Namespace)
+
+	action := testworker.NewAction(PodEvictedAction, podKind, pod, nil)
+	if err := perform(ctx, recorder, action, func() error {
+		return clientset.PolicyV1beta1().Evictions(pod.Namespace).Evict(ctx, &policyv1beta1.Eviction{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to evict pod %s: %w", pod.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/resource"
+	"github.com/thatchd/thatchd/pkg/thatchd/testworker"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// scaleToZero scales the target resources, such as the Deployments or
+// StatefulSets of the dependencies of the operator, to zero replicas. The
+// revert restores their previous replicas
+func scaleToZero(configuration map[string]string) (chaosFunc, error) {
+	target, err := resource.NewTarget(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, c client.Client, namespace string, recorder testworker.Recorder, logger logr.Logger) error {
+		objects, err := target.List(ctx, c, namespace)
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return fmt.Errorf("no %s found", target)
+		}
+
+		for i := range objects {
+			if err := scaleObjectToZero(ctx, c, &objects[i], recorder, logger); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+func scaleObjectToZero(ctx context.Context, c client.Client, object *unstructured.Unstructured, recorder testworker.Recorder, logger logr.Logger) error {
+	// The replicas default to one when not set
+	replicas, found, err := unstructured.NestedInt64(object.Object, "spec", "replicas")
+	if err != nil {
+		return fmt.Errorf("invalid replicas of %s: %w", object.GetName(), err)
+	}
+	if !found {
+		replicas = 1
+	}
+	if replicas == 0 {
+		logger.Info("resource is already scaled to zero", "name", object.GetName())
+		return nil
+	}
+
+	logger.Info("scaling resource to zero", "kind", object.GetKind(), "name", object.GetName(), "replicas", replicas)
+	action := testworker.NewAction(ScaledToZeroAction, object.GroupVersionKind(), object, &thatchdv1alpha1.ActionRevert{
+		Type:  thatchdv1alpha1.RevertMergePatch,
+		Patch: fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas),
+	})
+	if err := perform(ctx, recorder, action, func() error {
+		return c.Patch(ctx, object, client.RawPatch(types.MergePatchType, []byte(`{"spec":{"replicas":0}}`)))
+	}); err != nil {
+		return fmt.Errorf("failed to scale %s to zero: %w", object.GetName(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package testworker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
+	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Recorder records the actions of a test worker in its status
+type Recorder interface {
+	// Record records an action once it's made
+	Record(ctx context.Context, action thatchdv1alpha1.WorkerAction) error
+	// Forget removes a recorded action that couldn't be made, so it's not
+	// reverted
+	Forget(ctx context.Context, action thatchdv1alpha1.WorkerAction) error
+	// Revert reverts the recorded actions that haven't been reverted yet
+	Revert(ctx context.Context) error
+	// RevertAfter reverts the recorded actions once the duration elapses
+	// after the worker returns, so it doesn't have to wait for it
+	RevertAfter(duration time.Duration)
+}
+
+// RecordingInterface is implemented by test workers that make changes to the
+// cluster that are reverted if the worker is canceled. The context is
+// canceled when the TestWorker is deleted while running
+type RecordingInterface interface {
+	dispatch.Dispatchable
+
+	RunRecorded(ctx context.Context, namespace string, client client.Client, recorder Recorder, logger logr.Logger) (*thatchdv1alpha1.StatePatch, error)
+}
+
+// NewAction returns the action of type actionType made on the object of
+// kind gvk, that is reverted by revert, if not nil
+func NewAction(actionType string, gvk schema.GroupVersionKind, object metav1.Object, revert *thatchdv1alpha1.ActionRevert) thatchdv1alpha1.WorkerAction {
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+
+	return thatchdv1alpha1.WorkerAction{
+		Type:        actionType,
+		APIVersion:  apiVersion,
+		Kind:        kind,
+		Namespace:   object.GetNamespace(),
+		Name:        object.GetName(),
+		Revert:      revert,
+		PerformedAt: *thatchdv1alpha1.TimeString(time.Now()),
+	}
+}
+
+// RevertAction reverts the action, setting its RevertedAt field. Actions that
+// can't be reverted, or whose resource no longer exists, are marked as
+// reverted
+func RevertAction(ctx context.Context, c client.Client, action *thatchdv1alpha1.WorkerAction) error {
+	if action.RevertedAt != nil {
+		return nil
+	}
+
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(action.APIVersion)
+	object.SetKind(action.Kind)
+	object.SetNamespace(action.Namespace)
+	object.SetName(action.Name)
+
+	var err error
+	if action.Revert != nil {
+		switch action.Revert.Type {
+		case thatchdv1alpha1.RevertDelete:
+			err = c.Delete(ctx, object)
+		case thatchdv1alpha1.RevertMergePatch:
+			err = c.Patch(ctx, object, client.RawPatch(types.MergePatchType, []byte(action.Revert.Patch)))
+		default:
+			err = fmt.Errorf("unsupported revert type %s", action.Revert.Type)
+		}
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to revert %s of %s %s: %w", action.Type, action.Kind, action.Name, err)
+	}
+
+	action.RevertedAt = thatchdv1alpha1.TimeString(time.Now())
+	return nil
+}
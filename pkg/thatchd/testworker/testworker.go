@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/go-logr/logr"
+	thatchdv1alpha1 "github.com/thatchd/thatchd/api/v1alpha1"
 	"github.com/thatchd/thatchd/pkg/thatchd/dispatch"
 	"github.com/thatchd/thatchd/pkg/thatchd/strategy"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -14,21 +16,105 @@ type MutateStateFn func(interface{}) (interface{}, error)
 type Interface interface {
 	dispatch.Dispatchable
 
-	Run(ctx context.Context, namespace string, client client.Client) (MutateStateFn, error)
+	Run(ctx context.Context, namespace string, client client.Client, logger logr.Logger) (MutateStateFn, error)
 }
 
+// PatchInterface is implemented by test workers that mutate the state with
+// a patch instead of a MutateStateFn. Unlike a function, the patch can be
+// recorded in the TestWorker status and replayed. A nil patch leaves the
+// state unchanged
+type PatchInterface interface {
+	dispatch.Dispatchable
+
+	RunPatch(ctx context.Context, namespace string, client client.Client, logger logr.Logger) (*thatchdv1alpha1.StatePatch, error)
+}
+
+// FromStrategy returns the test worker for the strategy. Providers may
+// return an Interface, a PatchInterface or a RecordingInterface
 func FromStrategy(s *strategy.Strategy, providers map[string]strategy.StrategyProvider) (Interface, error) {
 	result := strategy.FromStrategy(s, providers)
 	if result == nil {
 		return nil, fmt.Errorf("no provider found for strategy %s", s)
 	}
 
-	typedResult, ok := result.(Interface)
+	if typedResult, ok := result.(Interface); ok {
+		return typedResult, nil
+	}
+
+	if patchResult, ok := result.(PatchInterface); ok {
+		return &patchWorker{patchResult}, nil
+	}
+
+	if recordingResult, ok := result.(RecordingInterface); ok {
+		return &recordingWorker{recordingResult}, nil
+	}
+
+	return nil, fmt.Errorf("provider for strategy %s doesn't return testworker interface", s)
+}
+
+// patchWorker adapts a PatchInterface into an Interface, so it can be
+// dispatched. Callers are expected to check for PatchInterface and call
+// RunPatch instead of Run
+type patchWorker struct {
+	PatchInterface
+}
+
+func (w *patchWorker) Run(ctx context.Context, namespace string, client client.Client, logger logr.Logger) (MutateStateFn, error) {
+	return nil, fmt.Errorf("test worker mutates the state with a patch, use RunPatch")
+}
+
+// recordingWorker adapts a RecordingInterface into an Interface, so it can be
+// dispatched. Callers are expected to check for RecordingInterface and call
+// RunRecorded instead of Run
+type recordingWorker struct {
+	RecordingInterface
+}
+
+func (w *recordingWorker) Run(ctx context.Context, namespace string, client client.Client, logger logr.Logger) (MutateStateFn, error) {
+	return nil, fmt.Errorf("test worker records its actions, use RunRecorded")
+}
+
+// JSONPatch returns a RFC 6902 JSON Patch to return from RunPatch
+func JSONPatch(patch string) *thatchdv1alpha1.StatePatch {
+	return &thatchdv1alpha1.StatePatch{
+		Type:  thatchdv1alpha1.StateJSONPatch,
+		Patch: patch,
+	}
+}
+
+// MergePatch returns a RFC 7386 JSON Merge Patch to return from RunPatch
+func MergePatch(patch string) *thatchdv1alpha1.StatePatch {
+	return &thatchdv1alpha1.StatePatch{
+		Type:  thatchdv1alpha1.StateMergePatch,
+		Patch: patch,
+	}
+}
+
+// Configuration keys of the state patch of built-in test workers
+const (
+	// StatePatchKey is the patch applied to the suite state when the worker
+	// succeeds
+	StatePatchKey = "statePatch"
+	// StatePatchTypeKey is the type of the state patch, MergePatch by default
+	StatePatchTypeKey = "statePatchType"
+)
+
+// ConfiguredStatePatch returns the state patch set in the configuration of
+// a built-in test worker, or nil if there's none
+func ConfiguredStatePatch(configuration map[string]string) (*thatchdv1alpha1.StatePatch, error) {
+	patch, ok := configuration[StatePatchKey]
 	if !ok {
-		return nil, fmt.Errorf("provider for strategy %s doesn't return testworker interface", s)
+		return nil, nil
+	}
+
+	switch thatchdv1alpha1.StatePatchType(configuration[StatePatchTypeKey]) {
+	case "", thatchdv1alpha1.StateMergePatch:
+		return MergePatch(patch), nil
+	case thatchdv1alpha1.StateJSONPatch:
+		return JSONPatch(patch), nil
 	}
 
-	return typedResult, nil
+	return nil, fmt.Errorf("unsupported %s %s", StatePatchTypeKey, configuration[StatePatchTypeKey])
 }
 
 // NoMutate is used when the test worker doesn't mutate the